@@ -0,0 +1,24 @@
+package shortenerv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative shortener/v1/shortener.proto
+
+import "context"
+
+// ApiKey authenticates every call of a client with an API key:
+//
+//	conn, err := grpc.NewClient(address,
+//		grpc.WithTransportCredentials(insecure.NewCredentials()),
+//		grpc.WithPerRPCCredentials(shortenerv1.ApiKey(key)))
+//	client := shortenerv1.NewShortenerServiceClient(conn)
+//
+// The key is sent over plaintext connections as well, the API is meant for
+// services inside the same network.
+type ApiKey string
+
+func (k ApiKey) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(k)}, nil
+}
+
+func (k ApiKey) RequireTransportSecurity() bool {
+	return false
+}
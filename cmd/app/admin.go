@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/admin"
+	"github.com/Komilov31/url-shortener/internal/config"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/rs/zerolog"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// IsAdminCommand reports whether name is an admin command handled by Admin.
+func IsAdminCommand(name string) bool {
+	return admin.IsCommand(name)
+}
+
+// Admin runs an admin command against the configured storage and cache.
+// Logs go to stderr so that stdout only carries the command output.
+func Admin(cfg *config.Config, args []string) error {
+	zlog.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+	ctx := context.Background()
+
+	storage, err := newStorage(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+	defer closeResource("storage", storage)
+
+	cache, err := newCache(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeResource("cache", cache)
+
+	metadataWorker := newMetadataWorker(cfg.Metadata, storage)
+	metadataWorker.Start(ctx)
+	// Links created by the command get their metadata before the process
+	// exits.
+	defer metadataWorker.Wait()
+	defer metadataWorker.Close()
+
+	timeout := time.Duration(cfg.HttpServer.Timeout) * time.Second
+	service := service.New(storage, cache, metadataWorker, timeout)
+
+	err = admin.New(service, os.Stdin, os.Stdout, os.Stderr).Run(ctx, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
@@ -1,63 +1,556 @@
 package app
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	memorycache "github.com/Komilov31/url-shortener/internal/cache/memory"
 	"github.com/Komilov31/url-shortener/internal/cache/redis"
 	"github.com/Komilov31/url-shortener/internal/config"
+	"github.com/Komilov31/url-shortener/internal/grpcapi"
 	"github.com/Komilov31/url-shortener/internal/handler"
+	"github.com/Komilov31/url-shortener/internal/healthcheck"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/metadata"
+	"github.com/Komilov31/url-shortener/internal/metrics"
+	"github.com/Komilov31/url-shortener/internal/migrate"
+	"github.com/Komilov31/url-shortener/internal/probe"
 	"github.com/Komilov31/url-shortener/internal/repository"
+	memoryrepo "github.com/Komilov31/url-shortener/internal/repository/memory"
+	"github.com/Komilov31/url-shortener/internal/repository/sqlite"
+	"github.com/Komilov31/url-shortener/internal/server"
 	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/Komilov31/url-shortener/internal/tracing"
+	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
 	"github.com/wb-go/wbf/dbpg"
 	"github.com/wb-go/wbf/ginext"
 	"github.com/wb-go/wbf/zlog"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"google.golang.org/grpc"
 )
 
-func Run() error {
+// Options are the command line options of the server.
+type Options struct {
+	// AutoMigrate applies pending migrations on start instead of refusing
+	// to start with an outdated schema.
+	AutoMigrate bool
+}
+
+// Run serves HTTP until SIGINT or SIGTERM. On a signal it stops accepting
+// connections, lets in-flight requests finish so that their clicks are
+// written, stops the background workers and closes the storage and the
+// cache. Requests and then workers get up to http_server.shutdown_timeout
+// each, a second signal kills the process right away.
+func Run(cfg *config.Config, opts Options) error {
 	zlog.Init()
+	zlog.Logger = zlog.Logger.Hook(tracing.LogHook{})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		Stdout:      os.Stdout,
+	})
+	if err != nil {
+		return err
+	}
+	defer flushTraces(shutdownTracing)
+
+	storage, err := newStorage(ctx, cfg, opts.AutoMigrate)
+	if err != nil {
+		return err
+	}
+	defer closeResource("storage", storage)
+
+	cache, err := newCache(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeResource("cache", cache)
+
+	// The metadata workers outlive ctx: they are stopped only after the
+	// server has drained, because requests still being served may enqueue
+	// jobs. Health checks simply stop on the signal.
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// The probe and Close talk to the backends directly, everything else
+	// goes through the instrumented ones.
+	var instrumentedStorage metrics.Storage = storage
+	var instrumentedCache service.Cache = cache
+	var appMetrics *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		appMetrics = metrics.New()
+		instrumentedStorage = appMetrics.Storage(storage)
+		instrumentedCache = appMetrics.Cache(cache)
+	}
+
+	metadataWorker := newMetadataWorker(cfg.Metadata, instrumentedStorage)
+	if appMetrics != nil {
+		metadataWorker.SetMetrics(appMetrics)
+	}
+	metadataWorker.Start(workersCtx)
+
+	checkerDone := make(chan struct{})
+	if cfg.HealthCheck.Enabled {
+		checker := healthcheck.New(instrumentedStorage, healthcheck.Options{
+			Interval:         time.Duration(cfg.HealthCheck.Interval) * time.Second,
+			Timeout:          time.Duration(cfg.HealthCheck.Timeout) * time.Second,
+			Concurrency:      cfg.HealthCheck.Concurrency,
+			PerHostDelay:     time.Duration(cfg.HealthCheck.PerHostDelay) * time.Second,
+			MaxRedirects:     cfg.HealthCheck.MaxRedirects,
+			FailureThreshold: cfg.HealthCheck.FailureThreshold,
+		})
+		go func() {
+			defer close(checkerDone)
+			checker.Run(ctx)
+		}()
+	} else {
+		close(checkerDone)
+	}
+
+	timeout := time.Duration(cfg.HttpServer.Timeout) * time.Second
+	service := service.New(instrumentedStorage, tracing.Cache(instrumentedCache), metadataWorker, timeout)
+	if appMetrics != nil {
+		service.SetMetrics(appMetrics)
+	}
+	service.SetIdempotencyTTL(time.Duration(cfg.Idempotency.TTL) * time.Second)
+	service.SetIdempotencyLease(time.Duration(cfg.Idempotency.Lease) * time.Second)
+	purgeDone := make(chan struct{})
+	go func() {
+		defer close(purgeDone)
+		purgeIdempotencyKeys(ctx, service, time.Duration(cfg.Idempotency.PurgeInterval)*time.Second)
+	}()
+	tracedService := tracing.Service(service)
+	handler := handler.New(tracedService)
+	if cfg.HttpServer.PublicUrl != "" {
+		publicUrl, err := url.Parse(cfg.HttpServer.PublicUrl)
+		if err != nil {
+			return fmt.Errorf("invalid http_server.public_url: %w", err)
+		}
+		handler.SetPublicUrl(publicUrl)
+	}
+
+	// Readiness turns negative as soon as the signal arrives, the server
+	// keeps serving for shutdown_delay so that load balancers notice.
+	probe := newProbe(cfg, storage, cache)
+	context.AfterFunc(ctx, probe.Shutdown)
+
+	router := ginext.New()
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(tracedRequest)))
+	router.Use(logging.Middleware("/healthz", "/readyz", "/metrics"))
+	metricsErr := make(chan error, 1)
+	if appMetrics != nil {
+		router.Use(appMetrics.Middleware())
+		if cfg.Metrics.Address == "" {
+			router.GET("/metrics", gin.WrapH(appMetrics.Handler()))
+			metricsErr <- nil
+		} else {
+			go func() {
+				metricsErr <- serveMetrics(ctx, cfg.Metrics.Address, appMetrics, stop)
+			}()
+		}
+	} else {
+		metricsErr <- nil
+	}
+	registerRoutes(router, handler, probe)
+
+	shutdownTimeout := time.Duration(cfg.HttpServer.ShutdownTimeout) * time.Second
+	grpcErr := make(chan error, 1)
+	if cfg.Grpc.Enabled {
+		go func() {
+			grpcErr <- serveGrpc(ctx, cfg.Grpc.Address, grpcapi.NewServer(tracedService), shutdownTimeout, stop)
+		}()
+	} else {
+		grpcErr <- nil
+	}
+	srv := server.New(router, server.Options{
+		Address:           cfg.HttpServer.Address,
+		ReadTimeout:       time.Duration(cfg.HttpServer.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(cfg.HttpServer.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(cfg.HttpServer.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(cfg.HttpServer.IdleTimeout) * time.Second,
+		ShutdownTimeout:   shutdownTimeout,
+		ShutdownDelay:     time.Duration(cfg.HttpServer.ShutdownDelay) * time.Second,
+	})
+
+	zlog.Logger.Info().Msg("succesfully started server on " + cfg.HttpServer.Address)
+	err = srv.Run(ctx)
+	stop()
+	err = errors.Join(err, <-metricsErr, <-grpcErr)
+	zlog.Logger.Info().Msg("shutting down")
+
+	// Queued metadata jobs may finish unless that takes longer than the
+	// shutdown timeout.
+	metadataWorker.Close()
+	drained := make(chan struct{})
+	go func() {
+		metadataWorker.Wait()
+		<-checkerDone
+		<-purgeDone
+		close(drained)
+	}()
+	var deadline <-chan time.Time
+	if shutdownTimeout > 0 {
+		deadline = time.After(shutdownTimeout)
+	}
+	select {
+	case <-drained:
+	case <-deadline:
+		zlog.Logger.Warn().Msg("background workers did not stop in time, cancelling them")
+	}
+	stopWorkers()
+	<-drained
 
+	if err != nil {
+		return err
+	}
+	zlog.Logger.Info().Msg("server stopped")
+	return nil
+}
+
+// serveMetrics serves /metrics on its own listener until ctx is done. When
+// the listener fails the whole server is stopped through stop, otherwise
+// the missing metrics would go unnoticed.
+func serveMetrics(ctx context.Context, address string, appMetrics *metrics.Metrics, stop context.CancelFunc) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", appMetrics.Handler())
+	srv := server.New(mux, server.Options{
+		Address:           address,
+		ReadHeaderTimeout: 5 * time.Second,
+		ShutdownTimeout:   5 * time.Second,
+	})
+
+	zlog.Logger.Info().Msg("serving metrics on " + address)
+	if err := srv.Run(ctx); err != nil {
+		stop()
+		return fmt.Errorf("metrics server: %w", err)
+	}
+	return nil
+}
+
+// serveGrpc serves the gRPC API until ctx is done, a failing listener
+// stops the whole server like in serveMetrics.
+func serveGrpc(ctx context.Context, address string, srv *grpc.Server, shutdownTimeout time.Duration, stop context.CancelFunc) error {
+	zlog.Logger.Info().Msg("serving grpc on " + address)
+	if err := grpcapi.Run(ctx, srv, address, shutdownTimeout); err != nil {
+		stop()
+		return fmt.Errorf("grpc server: %w", err)
+	}
+	return nil
+}
+
+// purgeIdempotencyKeys deletes expired idempotency keys every interval
+// until ctx is done.
+func purgeIdempotencyKeys(ctx context.Context, service *service.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := service.PurgeIdempotencyKeys(ctx)
+		if err != nil {
+			zlog.Logger.Error().Err(err).Msg("could not purge idempotency keys")
+			continue
+		}
+		if deleted > 0 {
+			zlog.Logger.Info().Int("deleted", deleted).Msg("purged expired idempotency keys")
+		}
+	}
+}
+
+// tracedRequest leaves probes and scrapes out of the traces, they run every
+// few seconds and would drown the requests worth looking at.
+func tracedRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/metrics":
+		return false
+	}
+	return true
+}
+
+// flushTraces exports the spans that are still buffered.
+func flushTraces(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		zlog.Logger.Error().Msg("could not flush traces: " + err.Error())
+	}
+}
+
+// closeResource closes a storage or cache on shutdown, an error is only
+// logged because there is nothing left to do about it.
+func closeResource(name string, resource io.Closer) {
+	if err := resource.Close(); err != nil {
+		zlog.Logger.Error().Msg("could not close " + name + ": " + err.Error())
+	}
+}
+
+// linkStorage is everything the application needs from a storage backend.
+type linkStorage interface {
+	service.Storage
+	metadata.Storage
+	healthcheck.Storage
+	io.Closer
+	Ping(context.Context) error
+}
+
+// linkCache is everything the application needs from a cache backend.
+type linkCache interface {
+	service.Cache
+	io.Closer
+	Ping(context.Context) error
+}
+
+// replicated is implemented by storages with read replicas, each replica
+// is reported by the readiness probe on its own.
+type replicated interface {
+	Replicas() int
+	PingReplica(ctx context.Context, i int) error
+}
+
+// newProbe makes the storage a critical dependency: without it nothing
+// works. Replicas serve the reads and are critical as well. Without the
+// cache links are still resolved from the storage, so a cache failure only
+// degrades the service.
+func newProbe(cfg *config.Config, storage linkStorage, cache linkCache) *probe.Probe {
+	storageName, cacheName := cfg.Storage.Backend, cfg.Cache.Backend
+	switch storageName {
+	case "":
+		storageName = "postgres"
+	case "memory":
+		storageName = "memory_storage"
+	}
+	switch cacheName {
+	case "":
+		cacheName = "redis"
+	case "memory":
+		cacheName = "memory_cache"
+	}
+
+	dependencies := []probe.Dependency{{Name: storageName, Critical: true, Ping: storage.Ping}}
+	if replicas, ok := storage.(replicated); ok {
+		for i := 0; i < replicas.Replicas(); i++ {
+			dependencies = append(dependencies, probe.Dependency{
+				Name:     storageName + "_replica_" + strconv.Itoa(i+1),
+				Critical: true,
+				Ping: func(ctx context.Context) error {
+					return replicas.PingReplica(ctx, i)
+				},
+			})
+		}
+	}
+	dependencies = append(dependencies, probe.Dependency{Name: cacheName, Ping: cache.Ping})
+
+	timeout := time.Duration(cfg.HttpServer.ReadinessTimeout) * time.Second
+	return probe.New(timeout, dependencies...)
+}
+
+func newStorage(ctx context.Context, cfg *config.Config, autoMigrate bool) (linkStorage, error) {
+	switch cfg.Storage.Backend {
+	case "", "postgres":
+		db, err := openPostgres(cfg.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		storage := repository.New(db)
+		if err := prepareSchema(ctx, db.Master, migrate.Postgres, autoMigrate); err != nil {
+			storage.Close()
+			return nil, err
+		}
+		return storage, nil
+	case "sqlite":
+		db, err := sqlite.Open(cfg.Sqlite.Path)
+		if err != nil {
+			return nil, fmt.Errorf("could not init db: %w", err)
+		}
+		if err := prepareSchema(ctx, db, migrate.Sqlite, autoMigrate); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return sqlite.New(db), nil
+	case "memory":
+		zlog.Logger.Warn().Msg("using in-memory storage, data will be lost on restart")
+		return memoryrepo.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q, expected postgres, sqlite or memory", cfg.Storage.Backend)
+	}
+}
+
+func openPostgres(cfg config.PostgresConfig) (*dbpg.DB, error) {
 	dbString := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		config.Cfg.Postgres.Host,
-		config.Cfg.Postgres.Port,
-		config.Cfg.Postgres.User,
-		config.Cfg.Postgres.Password,
-		config.Cfg.Postgres.Name,
+		cfg.Host,
+		cfg.Port,
+		cfg.User,
+		cfg.Password,
+		cfg.Name,
 	)
-	opts := &dbpg.Options{MaxOpenConns: 10, MaxIdleConns: 5}
-	db, err := dbpg.New(dbString, []string{}, opts)
+	master, err := tracing.OpenDB("postgres", dbString)
 	if err != nil {
-		log.Fatal("could not init db: " + err.Error())
+		return nil, fmt.Errorf("could not init db: %w", err)
 	}
+	master.SetMaxOpenConns(10)
+	master.SetMaxIdleConns(5)
 
-	repository := repository.New(db)
-	cache := redis.New()
-	service := service.New(repository, cache)
-	handler := handler.New(service)
+	db := &dbpg.DB{Master: master}
+	for i, dsn := range cfg.Replicas {
+		replica, err := tracing.OpenDB("postgres", dsn)
+		if err != nil {
+			for _, opened := range append([]*sql.DB{master}, db.Slaves...) {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("could not init replica %d: %w", i+1, err)
+		}
+		replica.SetMaxOpenConns(10)
+		replica.SetMaxIdleConns(5)
+		db.Slaves = append(db.Slaves, replica)
+	}
+	return db, nil
+}
 
-	router := ginext.New()
-	registerRoutes(router, handler)
+// prepareSchema applies pending migrations when autoMigrate is set,
+// otherwise it refuses to work with a schema older than the code.
+func prepareSchema(ctx context.Context, db *sql.DB, newMigrator func(*sql.DB) (*migrate.Migrator, error), autoMigrate bool) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	if !autoMigrate {
+		if err := migrator.Check(ctx); err != nil {
+			return fmt.Errorf("%w, run \"app migrate up\" or start with -auto-migrate", err)
+		}
+		return nil
+	}
+
+	results, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		zlog.Logger.Info().Msg("applied migration " + result.Source.Path)
+	}
+	return nil
+}
+
+func newMetadataWorker(cfg config.MetadataConfig, storage metadata.Storage) *metadata.Worker {
+	opts := metadata.Options{
+		Workers:      cfg.Workers,
+		QueueSize:    cfg.QueueSize,
+		Timeout:      time.Duration(cfg.Timeout) * time.Second,
+		MaxBodyBytes: cfg.MaxBodyBytes,
+		MaxRedirects: cfg.MaxRedirects,
+		MaxAttempts:  cfg.MaxAttempts,
+		RetryDelay:   time.Duration(cfg.RetryDelay) * time.Second,
+	}
+	return metadata.NewWorker(metadata.NewFetcher(opts), storage, opts)
+}
 
-	zlog.Logger.Info().Msg("succesfully started server on " + config.Cfg.HttpServer.Address)
-	return router.Run(config.Cfg.HttpServer.Address)
+func newCache(cfg *config.Config) (linkCache, error) {
+	switch cfg.Cache.Backend {
+	case "", "redis":
+		return redis.New(cfg.Redis.Host+":"+cfg.Redis.Port, cfg.Redis.Password), nil
+	case "memory":
+		return memorycache.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q, expected redis or memory", cfg.Cache.Backend)
+	}
 }
 
-func registerRoutes(engine *ginext.Engine, handler *handler.Handler) {
+func registerRoutes(engine *ginext.Engine, handler *handler.Handler, probe *probe.Probe) {
 	// Register static files
 	engine.LoadHTMLFiles("/app/static/index.html")
 	engine.Static("/static", "/app/static")
 
-	// POST requests
-	engine.POST("/shorten", handler.CreateShortUrl)
-
-	// GET requests
 	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	engine.GET("/healthz", probe.Healthz)
+	engine.GET("/readyz", probe.Readyz)
 	engine.GET("/", handler.GetMainPage)
 	engine.GET("/s/:short_url", handler.RedirectByShortUrl)
-	engine.GET("analytics/:short_url", handler.GetAnalytics)
-	engine.GET("analytics/user_agent", handler.AggregateByUserAgent)
-	engine.GET("analytics/date", handler.AggregateByDate)
-	engine.GET("analytics/month", handler.AggregateByMonth)
+
+	registerAPI(engine, handler)
+	registerLegacyRoutes(engine, handler)
+}
+
+// registerAPI lets anyone create and read links, changing or deleting an
+// existing link takes an API key.
+func registerAPI(engine *ginext.Engine, h *handler.Handler) {
+	api := engine.Group(handler.APIPrefix)
+	api.POST("/links", h.IdentifyApiKey, h.CreateLink)
+	api.GET("/links", h.ListLinks)
+	api.GET("/health/links", h.GetUnhealthyUrls)
+	api.GET("/links/:short_url", h.GetLink)
+	api.DELETE("/links/:short_url", h.RequireApiKey, h.DeleteLink)
+	api.PUT("/links/:short_url/tags", h.RequireApiKey, h.SetTags)
+	api.PUT("/links/:short_url/folder", h.RequireApiKey, h.SetFolder)
+	api.PUT("/links/:short_url/disabled", h.RequireApiKey, h.SetDisabled)
+	api.GET("/links/:short_url/rules", h.GetTargetingRules)
+	api.PUT("/links/:short_url/rules", h.RequireApiKey, h.SetTargetingRules)
+	api.GET("/links/:short_url/variants", h.GetVariants)
+	api.PUT("/links/:short_url/variants", h.RequireApiKey, h.SetVariants)
+	api.GET("/links/:short_url/analytics", h.GetAnalytics)
+	api.GET("/links/:short_url/metadata", h.GetUrlMetadata)
+	api.GET("/links/:short_url/qr", h.GetQrCode)
+
+	api.GET("/analytics/user_agent", h.AggregateByUserAgent)
+	api.GET("/analytics/date", h.AggregateByDate)
+	api.GET("/analytics/month", h.AggregateByMonth)
+	api.GET("/analytics/campaigns", h.AggregateByCampaign)
+	api.GET("/analytics/referrer", h.AggregateByReferrer)
+	api.GET("/analytics/tags", h.AggregateByTag)
+
+	keys := api.Group("/keys", h.RequireApiKey)
+	keys.GET("", h.ListApiKeys)
+	keys.POST("", h.IssueApiKey)
+	keys.DELETE("/:id", h.RevokeApiKey)
+}
+
+// registerLegacyRoutes keeps the routes that existed before the versioned
+// API working, with the same API key requirements. Their responses point
+// to the replacement.
+func registerLegacyRoutes(engine *ginext.Engine, h *handler.Handler) {
+	legacy := func(method, path, successor string, handlers ...ginext.HandlerFunc) {
+		engine.Handle(method, path, append([]ginext.HandlerFunc{handler.Deprecated(handler.APIPrefix + successor)}, handlers...)...)
+	}
+
+	legacy(http.MethodPost, "/shorten", "/links", h.IdentifyApiKey, h.CreateShortUrl)
+	legacy(http.MethodGet, "/analytics/:short_url", "/links/:short_url/analytics", h.GetAnalytics)
+	legacy(http.MethodGet, "/analytics/user_agent", "/analytics/user_agent", h.AggregateByUserAgent)
+	legacy(http.MethodGet, "/analytics/date", "/analytics/date", h.AggregateByDate)
+	legacy(http.MethodGet, "/analytics/month", "/analytics/month", h.AggregateByMonth)
+	legacy(http.MethodGet, "/analytics/campaigns", "/analytics/campaigns", h.AggregateByCampaign)
+	legacy(http.MethodGet, "/analytics/referrer", "/analytics/referrer", h.AggregateByReferrer)
+	legacy(http.MethodGet, "/analytics/tags", "/analytics/tags", h.AggregateByTag)
+	legacy(http.MethodGet, "/metadata/:short_url", "/links/:short_url/metadata", h.GetUrlMetadata)
+	legacy(http.MethodGet, "/qr/:short_url", "/links/:short_url/qr", h.GetQrCode)
+	legacy(http.MethodGet, "/links/unhealthy", "/health/links", h.GetUnhealthyUrls)
+	legacy(http.MethodGet, "/links", "/links", h.ListLinks)
+	legacy(http.MethodGet, "/links/:short_url/rules", "/links/:short_url/rules", h.GetTargetingRules)
+	legacy(http.MethodPut, "/links/:short_url/rules", "/links/:short_url/rules", h.RequireApiKey, h.SetTargetingRules)
+	legacy(http.MethodGet, "/links/:short_url/variants", "/links/:short_url/variants", h.GetVariants)
+	legacy(http.MethodPut, "/links/:short_url/variants", "/links/:short_url/variants", h.RequireApiKey, h.SetVariants)
+	legacy(http.MethodPut, "/links/:short_url/tags", "/links/:short_url/tags", h.RequireApiKey, h.SetTags)
+	legacy(http.MethodPut, "/links/:short_url/folder", "/links/:short_url/folder", h.RequireApiKey, h.SetFolder)
+	legacy(http.MethodPut, "/links/:short_url/disabled", "/links/:short_url/disabled", h.RequireApiKey, h.SetDisabled)
 }
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/config"
+	"github.com/Komilov31/url-shortener/internal/migrate"
+	"github.com/Komilov31/url-shortener/internal/repository/sqlite"
+	"github.com/pressly/goose/v3"
+)
+
+var errMigrateUsage = errors.New("usage: app migrate up|down|status|redo")
+
+// Migrate runs the migrate subcommand against the configured storage.
+func Migrate(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errMigrateUsage
+	}
+
+	db, migrator, err := openMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		results, err := migrator.Up(ctx)
+		printResults(results)
+		if err == nil && len(results) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+	case "down":
+		result, err := migrator.Down(ctx)
+		if result != nil {
+			printResults([]*goose.MigrationResult{result})
+		}
+		return err
+	case "redo":
+		results, err := migrator.Redo(ctx)
+		printResults(results)
+		return err
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(status)
+		return nil
+	default:
+		return errMigrateUsage
+	}
+}
+
+func openMigrator(cfg *config.Config) (*sql.DB, *migrate.Migrator, error) {
+	var (
+		db          *sql.DB
+		newMigrator func(*sql.DB) (*migrate.Migrator, error)
+	)
+	switch cfg.Storage.Backend {
+	case "", "postgres":
+		pg, err := openPostgres(cfg.Postgres)
+		if err != nil {
+			return nil, nil, err
+		}
+		db, newMigrator = pg.Master, migrate.Postgres
+	case "sqlite":
+		var err error
+		db, err = sqlite.Open(cfg.Sqlite.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not init db: %w", err)
+		}
+		newMigrator = migrate.Sqlite
+	default:
+		return nil, nil, fmt.Errorf("storage backend %q has no migrations", cfg.Storage.Backend)
+	}
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, migrator, nil
+}
+
+func printResults(results []*goose.MigrationResult) {
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		state := "OK"
+		if result.Error != nil {
+			state = "FAILED"
+		}
+		fmt.Printf("%-6s %-4s %s (%s)\n", state, result.Direction, result.Source.Path, result.Duration.Round(time.Millisecond))
+	}
+}
+
+func printStatus(status []*goose.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+	for _, migration := range status {
+		appliedAt := "-"
+		if migration.State == goose.StateApplied {
+			appliedAt = migration.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", migration.Source.Version, migration.State, appliedAt, migration.Source.Path)
+	}
+	w.Flush()
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/Komilov31/url-shortener/cmd/app"
 	_ "github.com/Komilov31/url-shortener/docs"
+	"github.com/Komilov31/url-shortener/internal/admin"
+	"github.com/Komilov31/url-shortener/internal/config"
 )
 
 // @title URL Shortener API
@@ -21,8 +27,46 @@ import (
 
 // @host localhost:8080
 // @BasePath /
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description Bearer followed by a key issued with "keys issue"
 func main() {
-	if err := app.Run(); err != nil {
+	configPath := flag.String("config", config.DefaultPath, "configuration file, empty to use defaults and environment only")
+	autoMigrate := flag.Bool("auto-migrate", false, "apply pending database migrations before starting")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: app [-config FILE] [-auto-migrate]\n       app [-config FILE] migrate up|down|status|redo\n       app [-config FILE] links|keys|analytics <subcommand> [flags]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := config.LoadDotEnv(".env"); err != nil {
+		log.Fatal(err)
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if flag.Arg(0) == "migrate" {
+		if err := app.Migrate(cfg, flag.Args()[1:]); err != nil {
+			log.Fatal("could not migrate database: ", err)
+		}
+		return
+	}
+
+	if app.IsAdminCommand(flag.Arg(0)) {
+		if err := app.Admin(cfg, flag.Args()); err != nil {
+			if !errors.Is(err, admin.ErrUsage) {
+				fmt.Fprintln(os.Stderr, "error:", err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := app.Run(cfg, app.Options{AutoMigrate: *autoMigrate}); err != nil {
 		log.Fatal("could not start server: ", err)
 	}
 }
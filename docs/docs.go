@@ -9,7 +9,16 @@ const docTemplate = `{
     "info": {
         "description": "{{escape .Description}}",
         "title": "{{.Title}}",
-        "contact": {},
+        "termsOfService": "http://swagger.io/terms/",
+        "contact": {
+            "name": "API Support",
+            "url": "http://www.swagger.io/support",
+            "email": "support@swagger.io"
+        },
+        "license": {
+            "name": "Apache 2.0",
+            "url": "http://www.apache.org/licenses/LICENSE-2.0.html"
+        },
         "version": "{{.Version}}"
     },
     "host": "{{.Host}}",
@@ -32,7 +41,75 @@ const docTemplate = `{
                 }
             }
         },
-        "/analytics/date": {
+        "/api/v1/analytics/campaigns": {
+            "get": {
+                "description": "Returns clicks and unique visitors grouped by period, utm_source, utm_medium and utm_campaign",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Analytics"
+                ],
+                "summary": "Get aggregated analytics by utm campaign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "day",
+                        "description": "Aggregation period: day, week or month",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links in this folder",
+                        "name": "folder",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.CampaignDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid period",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/analytics/date": {
             "get": {
                 "description": "Returns aggregated analytics data grouped by date",
                 "produces": [
@@ -42,6 +119,20 @@ const docTemplate = `{
                     "Analytics"
                 ],
                 "summary": "Get aggregated analytics by date",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links in this folder",
+                        "name": "folder",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -55,13 +146,25 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/ginext.H"
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
                         }
                     }
                 }
             }
         },
-        "/analytics/month": {
+        "/api/v1/analytics/month": {
             "get": {
                 "description": "Returns aggregated analytics data grouped by month",
                 "produces": [
@@ -71,6 +174,20 @@ const docTemplate = `{
                     "Analytics"
                 ],
                 "summary": "Get aggregated analytics by month",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links in this folder",
+                        "name": "folder",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -84,117 +201,288 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/ginext.H"
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
                         }
                     }
                 }
             }
         },
-        "/analytics/user_agent": {
+        "/api/v1/analytics/referrer": {
             "get": {
-                "description": "Returns aggregated analytics data grouped by user agent",
+                "description": "Returns click counts per referring domain for every short URL. Known social networks and apps are reported by name, clicks without referrer are counted as direct",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Analytics"
                 ],
-                "summary": "Get aggregated analytics by user agent",
+                "summary": "Get aggregated analytics by referrer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links in this folder",
+                        "name": "folder",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.UserAgentDTO"
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ReferrerDTO"
                             }
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/ginext.H"
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
                         }
                     }
                 }
             }
         },
-        "/analytics/{short_url}": {
+        "/api/v1/analytics/tags": {
             "get": {
-                "description": "Returns analytics data for the given short URL",
+                "description": "Returns the number of links, clicks and unique visitors for every tag. A click of a link with several tags is counted for each of them",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Analytics"
                 ],
-                "summary": "Get analytics data for a short URL",
+                "summary": "Get aggregated analytics by tag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Short URL",
-                        "name": "short_url",
-                        "in": "path",
-                        "required": true
+                        "description": "Only this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links in this folder",
+                        "name": "folder",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.RedirectInfo"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.TagDTO"
+                            }
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
                         }
                     }
                 }
             }
         },
-        "/s/{short_url}": {
+        "/api/v1/analytics/user_agent": {
             "get": {
-                "description": "Redirects to the original URL corresponding to the given short URL",
+                "description": "Returns aggregated analytics data grouped by user agent",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "URL"
+                    "Analytics"
                 ],
-                "summary": "Redirect to original URL by short URL",
+                "summary": "Get aggregated analytics by user agent",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Short URL",
-                        "name": "short_url",
-                        "in": "path",
-                        "required": true
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links in this folder",
+                        "name": "folder",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "301": {
-                        "description": "Redirect to original URL"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.UserAgentDTO"
+                            }
+                        }
                     },
-                    "400": {
-                        "description": "Invalid short URL or not found",
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/health/links": {
+            "get": {
+                "description": "Returns links whose destination failed the last scheduled health check",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Get links with unhealthy destinations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.UrlHealth"
                             }
                         }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
                     }
                 }
             }
         },
-        "/shorten": {
+        "/api/v1/keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns every issued key with its prefix and revocation time, keys themselves are never shown again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Keys"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.ApiKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, unknown or revoked API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Create a shortened URL from the provided original URL",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Issues a new key. The key is returned only in this response, only its hash is stored",
                 "consumes": [
                     "application/json"
                 ],
@@ -202,71 +490,1440 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "URL"
+                    "Keys"
                 ],
-                "summary": "Create a shortened URL",
+                "summary": "Issue an API key",
                 "parameters": [
                     {
-                        "description": "URL to shorten",
-                        "name": "url",
+                        "description": "Name of the key",
+                        "name": "key",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.Url"
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ApiKeyNameDTO"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.Url"
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.IssuedApiKeyDTO"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Invalid name",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, unknown or revoked API key",
                         "schema": {
-                            "$ref": "#/definitions/ginext.H"
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/ginext.H"
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "ginext.H": {
-            "type": "object",
-            "additionalProperties": {}
         },
-        "github_com_Komilov31_url-shortener_internal_dto.DateDTO": {
-            "type": "object",
-            "properties": {
-                "day": {
-                    "type": "integer"
-                },
-                "month": {
-                    "type": "integer"
-                },
-                "redirect_count": {
-                    "type": "integer"
-                },
-                "url_info": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.UrlInfo"
+        "/api/v1/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
                     }
-                },
+                ],
+                "description": "Revokes the key, revoking it again keeps the time of the first revocation",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Keys"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.ApiKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, unknown or revoked API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links": {
+            "get": {
+                "description": "Returns short URLs with their folder, tags, status and click counts. q matches a substring of the destination URL, alias, page title and tags",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Search short URLs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search text",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links in this folder",
+                        "name": "folder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links of this owner",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "active, expired or disabled",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after, RFC 3339 or YYYY-MM-DD",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before, RFC 3339 or YYYY-MM-DD",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum number of clicks",
+                        "name": "min_clicks",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of clicks",
+                        "name": "max_clicks",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "created",
+                        "description": "created, last_click or clicks",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "desc",
+                        "description": "asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of links to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.LinkDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid search parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a short link for the destination URL. A destination shortened before gets its existing short_url back, unless the request sets link options such as rules, variants, tags or expiration: then it fails with url_exists",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Create a short link",
+                "parameters": [
+                    {
+                        "description": "URL to shorten",
+                        "name": "url",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.Url"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes the request safe to retry: a retry with the same key and body gets the original result back. Requires an API key, keys are scoped to it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.Url"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the result of an earlier request with the same Idempotency-Key is replayed"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created link"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, link settings or idempotency key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "401": {
+                        "description": "Unknown or revoked API key, or an Idempotency-Key without an API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "409": {
+                        "description": "Destination shortened before and the request sets link options or its link is disabled or expired, or a request with the same Idempotency-Key is in progress",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key was used with another body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled or cache unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{short_url}": {
+            "get": {
+                "description": "Returns the short URL with its folder, tags, status and click counts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Get a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.LinkDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes the short URL with its analytics, rules, variants, tags and metadata. It stops redirecting right away",
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Delete a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Short URL deleted"
+                    },
+                    "401": {
+                        "description": "Missing, unknown or revoked API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{short_url}/analytics": {
+            "get": {
+                "description": "Returns analytics data for the given short URL",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Analytics"
+                ],
+                "summary": "Get analytics data for a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.RedirectInfo"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{short_url}/disabled": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Disabled short URLs respond with 410 Gone instead of redirecting",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Disable or enable a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Disabled flag",
+                        "name": "disabled",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.DisabledDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.LinkDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, unknown or revoked API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{short_url}/folder": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sets the folder of the short URL, an empty folder removes the link from its folder",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Move a short URL to a folder",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Folder",
+                        "name": "folder",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.FolderDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.LinkDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid folder",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, unknown or revoked API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{short_url}/metadata": {
+            "get": {
+                "description": "Returns title, description, Open Graph image and canonical URL of the destination page",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Get destination page metadata for a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.UrlMetadata"
+                        }
+                    },
+                    "404": {
+                        "description": "Metadata is not fetched yet",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{short_url}/qr": {
+            "get": {
+                "description": "Returns a PNG or SVG QR code of the full short URL. Scans are recorded with source=qr",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Get QR code for a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "png",
+                        "description": "Image format: png or svg",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 256,
+                        "description": "Image size in pixels",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "M",
+                        "description": "Error correction level: L, M, Q or H",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 4,
+                        "description": "Quiet zone size in modules",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "000000",
+                        "description": "Foreground color in hex",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "ffffff",
+                        "description": "Background color in hex",
+                        "name": "bg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code image"
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{short_url}/rules": {
+            "get": {
+                "description": "Returns the ordered list of targeting rules",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Get targeting rules of a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.TargetingRule"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replaces the ordered list of targeting rules. The first rule matching visitor OS, device, browser, language and country decides the destination",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Replace targeting rules of a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ordered targeting rules",
+                        "name": "rules",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.TargetingRule"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.TargetingRule"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid rules",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, unknown or revoked API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{short_url}/tags": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replaces the tags of the short URL. Tags are lowercased and may contain letters, digits, '-', '_' and '.'",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Replace tags of a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags",
+                        "name": "tags",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.LinkDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tags",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, unknown or revoked API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{short_url}/variants": {
+            "get": {
+                "description": "Returns destinations used for the A/B split and whether the split is sticky",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Get weighted destinations of a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.VariantsDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replaces destinations between which traffic is split proportionally to their weights. With sticky enabled a visitor keeps the same variant via cookie",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Replace weighted destinations of a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Weighted destinations",
+                        "name": "variants",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.VariantsDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.VariantsDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid variants",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, unknown or revoked API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Answers 200 while the process serves HTTP, dependencies are not checked",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Pings the storage and the cache. A failing cache only degrades the service and still answers 200, a failing storage or a graceful shutdown answers 503",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "Ready or degraded",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ReadinessDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Not ready or shutting down",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ReadinessDTO"
+                        }
+                    }
+                }
+            }
+        },
+        "/s/{short_url}": {
+            "get": {
+                "description": "Redirects to the original URL corresponding to the given short URL",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Redirect to original URL by short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "short_url",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirect to original URL"
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "410": {
+                        "description": "Short URL is disabled or expired",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "503": {
+                        "description": "Request cancelled or cache unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ProblemDTO"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_Komilov31_url-shortener_internal_dto.ApiKeyNameDTO": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.CampaignDTO": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "uniques": {
+                    "type": "integer"
+                },
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.DateDTO": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "type": "integer"
+                },
+                "month": {
+                    "type": "integer"
+                },
+                "redirect_count": {
+                    "type": "integer"
+                },
+                "url_info": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.UrlInfo"
+                    }
+                },
                 "year": {
                     "type": "integer"
                 }
             }
         },
+        "github_com_Komilov31_url-shortener_internal_dto.DependencyDTO": {
+            "type": "object",
+            "properties": {
+                "critical": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.DisabledDTO": {
+            "type": "object",
+            "properties": {
+                "disabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.FolderDTO": {
+            "type": "object",
+            "properties": {
+                "folder": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.IssuedApiKeyDTO": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.LinkDTO": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "folder": {
+                    "type": "string"
+                },
+                "last_click": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "short_url": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
         "github_com_Komilov31_url-shortener_internal_dto.MonthDTO": {
             "type": "object",
             "properties": {
@@ -295,12 +1952,61 @@ const docTemplate = `{
                 }
             }
         },
+        "github_com_Komilov31_url-shortener_internal_dto.ProblemDTO": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.ReadinessDTO": {
+            "type": "object",
+            "properties": {
+                "dependencies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.DependencyDTO"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "github_com_Komilov31_url-shortener_internal_dto.RedirectInfo": {
             "type": "object",
             "properties": {
+                "qr_scans": {
+                    "type": "integer"
+                },
                 "redirect_count": {
                     "type": "integer"
                 },
+                "referrers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ReferrerCount"
+                    }
+                },
                 "request_time": {
                     "type": "array",
                     "items": {
@@ -318,6 +2024,57 @@ const docTemplate = `{
                     "items": {
                         "type": "string"
                     }
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.VariantCount"
+                    }
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.ReferrerCount": {
+            "type": "object",
+            "properties": {
+                "redirect_count": {
+                    "type": "integer"
+                },
+                "referrer": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.ReferrerDTO": {
+            "type": "object",
+            "properties": {
+                "redirect_count": {
+                    "type": "integer"
+                },
+                "referrers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_dto.ReferrerCount"
+                    }
+                },
+                "short_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.TagDTO": {
+            "type": "object",
+            "properties": {
+                "links": {
+                    "type": "integer"
+                },
+                "redirect_count": {
+                    "type": "integer"
+                },
+                "tag": {
+                    "type": "string"
+                },
+                "uniques": {
+                    "type": "integer"
                 }
             }
         },
@@ -349,28 +2106,253 @@ const docTemplate = `{
                 }
             }
         },
+        "github_com_Komilov31_url-shortener_internal_dto.VariantCount": {
+            "type": "object",
+            "properties": {
+                "redirect_count": {
+                    "type": "integer"
+                },
+                "variant": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_dto.VariantsDTO": {
+            "type": "object",
+            "properties": {
+                "short_url": {
+                    "type": "string"
+                },
+                "sticky": {
+                    "type": "boolean"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.Variant"
+                    }
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_model.ApiKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_model.TargetingRule": {
+            "type": "object",
+            "properties": {
+                "browser": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "destination": {
+                    "type": "string"
+                },
+                "device": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                }
+            }
+        },
         "github_com_Komilov31_url-shortener_internal_model.Url": {
             "type": "object",
             "properties": {
+                "disabled": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "fallback_url": {
+                    "type": "string"
+                },
+                "folder": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "query_policy": {
+                    "type": "string"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.TargetingRule"
+                    }
+                },
+                "short_url": {
+                    "type": "string"
+                },
+                "sticky_variants": {
+                    "type": "boolean"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                },
+                "utm": {
+                    "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.Utm"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Komilov31_url-shortener_internal_model.Variant"
+                    }
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_model.UrlHealth": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "healthy": {
+                    "type": "boolean"
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "short_url": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_model.UrlMetadata": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "canonical_url": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "fetched_at": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
                 "short_url": {
                     "type": "string"
                 },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_model.Utm": {
+            "type": "object",
+            "properties": {
+                "utm_campaign": {
+                    "type": "string"
+                },
+                "utm_content": {
+                    "type": "string"
+                },
+                "utm_medium": {
+                    "type": "string"
+                },
+                "utm_source": {
+                    "type": "string"
+                },
+                "utm_term": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Komilov31_url-shortener_internal_model.Variant": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
                 "url": {
                     "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "Bearer followed by a key issued with \"keys issue\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "",
-	Host:             "",
-	BasePath:         "",
+	Version:          "1.0",
+	Host:             "localhost:8080",
+	BasePath:         "/",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "URL Shortener API",
+	Description:      "A URL shortener service with analytics.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
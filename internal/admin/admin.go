@@ -0,0 +1,226 @@
+// Package admin implements the administrative subcommands of the binary.
+// They talk to the service directly, so everything the HTTP API validates
+// is validated here the same way.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+const (
+	FormatTable = "table"
+	FormatJson  = "json"
+
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+const usage = `usage: app <command> <subcommand> [flags]
+
+commands:
+  links create -url URL [-fallback URL] [-folder F] [-tags a,b] [-owner O] [-expires TIME]
+  links list [-search S] [-folder F] [-tag T] [-owner O] [-status S] [-sort S] [-order O] [-limit N] [-offset N]
+  links update SHORT_URL [-folder F] [-tags a,b]
+  links disable|enable|delete|stats SHORT_URL
+  links export [-o FILE] [-search S] [-folder F] [-tag T] [-owner O] [-status S]
+  links import [-i FILE]
+  keys issue -name NAME
+  keys list
+  keys revoke ID
+  analytics purge (-before TIME | -older-than DURATION) [-link SHORT_URL]
+
+every subcommand accepts -format table|json, table is the default.
+TIME is RFC 3339 or YYYY-MM-DD.`
+
+// ErrUsage is returned when the command line can not be parsed. The usage
+// has already been printed to stderr by then.
+var ErrUsage = errors.New("invalid command line")
+
+type LinkService interface {
+	CreateShortUrl(context.Context, model.Url) (*model.Url, error)
+	SearchLinks(context.Context, dto.LinkQuery) ([]dto.LinkDTO, error)
+	SetDisabled(context.Context, string, bool) (*dto.LinkDTO, error)
+	SetTags(context.Context, string, []string) (*dto.LinkDTO, error)
+	SetFolder(context.Context, string, string) (*dto.LinkDTO, error)
+	DeleteLink(context.Context, string) (*model.Url, error)
+	GetAnalytics(context.Context, string) ([]dto.RedirectInfo, error)
+	ImportLink(context.Context, model.Url) (*model.Url, error)
+	ExportLinks(context.Context, dto.LinkQuery) ([]model.Url, error)
+	PurgeAnalytics(context.Context, string, time.Time) (int, error)
+	IssueApiKey(context.Context, string) (*dto.IssuedApiKeyDTO, error)
+	ListApiKeys(context.Context) ([]model.ApiKey, error)
+	RevokeApiKey(context.Context, int) (*model.ApiKey, error)
+}
+
+type CLI struct {
+	service LinkService
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	now     func() time.Time
+}
+
+func New(service LinkService, stdin io.Reader, stdout, stderr io.Writer) *CLI {
+	return &CLI{
+		service: service,
+		stdin:   stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+		now:     time.Now,
+	}
+}
+
+// IsCommand reports whether name is one of the commands handled by Run.
+func IsCommand(name string) bool {
+	switch name {
+	case "links", "keys", "analytics":
+		return true
+	}
+	return false
+}
+
+// Run executes a command, args start with the command name, for example
+// "links", "list", "-format", "json".
+func (c *CLI) Run(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return c.usageError("missing subcommand")
+	}
+
+	command := args[0] + " " + args[1]
+	args = args[2:]
+	switch command {
+	case "links create":
+		return c.createLink(ctx, args)
+	case "links list":
+		return c.listLinks(ctx, args)
+	case "links update":
+		return c.updateLink(ctx, args)
+	case "links disable":
+		return c.setDisabled(ctx, args, true)
+	case "links enable":
+		return c.setDisabled(ctx, args, false)
+	case "links delete":
+		return c.deleteLink(ctx, args)
+	case "links stats":
+		return c.linkStats(ctx, args)
+	case "links export":
+		return c.exportLinks(ctx, args)
+	case "links import":
+		return c.importLinks(ctx, args)
+	case "keys issue":
+		return c.issueKey(ctx, args)
+	case "keys list":
+		return c.listKeys(ctx, args)
+	case "keys revoke":
+		return c.revokeKey(ctx, args)
+	case "analytics purge":
+		return c.purgeAnalytics(ctx, args)
+	default:
+		return c.usageError(fmt.Sprintf("unknown command %q", command))
+	}
+}
+
+func (c *CLI) usageError(msg string) error {
+	fmt.Fprintln(c.stderr, msg)
+	fmt.Fprintln(c.stderr, usage)
+	return ErrUsage
+}
+
+// command is the flag set of a single subcommand with the flags every
+// subcommand shares.
+type command struct {
+	*flag.FlagSet
+	cli    *CLI
+	format string
+}
+
+func (c *CLI) newCommand(name string) *command {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	cmd := &command{FlagSet: fs, cli: c}
+	fs.StringVar(&cmd.format, "format", FormatTable, "output format, table or json")
+	return cmd
+}
+
+// parse parses flags and returns the positional arguments. Unlike
+// flag.FlagSet.Parse it allows a positional argument before the flags, as
+// in "links update abc123 -folder promo".
+func (cmd *command) parse(args []string, positional int) ([]string, error) {
+	var leading []string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		leading, args = args[:1], args[1:]
+	}
+
+	if err := cmd.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, ErrUsage
+	}
+
+	rest := append(leading, cmd.Args()...)
+	if len(rest) != positional {
+		return nil, cmd.cli.usageError(fmt.Sprintf("%s expects %d argument(s), got %d", cmd.Name(), positional, len(rest)))
+	}
+	if cmd.format != FormatTable && cmd.format != FormatJson {
+		return nil, cmd.cli.usageError(fmt.Sprintf("unknown format %q, expected table or json", cmd.format))
+	}
+	return rest, nil
+}
+
+// print writes value as indented JSON or as a table drawn by table.
+func (cmd *command) print(value any, table func(w io.Writer)) error {
+	if cmd.format == FormatJson {
+		encoder := json.NewEncoder(cmd.cli.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	w := tabwriter.NewWriter(cmd.cli.stdout, 0, 0, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+// parseTime accepts RFC 3339 timestamps and dates, dates are midnight UTC.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", value)
+	}
+	return t, nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(timeLayout)
+}
+
+func splitList(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
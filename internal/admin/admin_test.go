@@ -0,0 +1,228 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	memorycache "github.com/Komilov31/url-shortener/internal/cache/memory"
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	memoryrepo "github.com/Komilov31/url-shortener/internal/repository/memory"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopQueue struct{}
+
+func (noopQueue) Enqueue(model.Url) {}
+
+type testCLI struct {
+	*CLI
+	service *service.Service
+	stdin   *bytes.Buffer
+	stdout  *bytes.Buffer
+	stderr  *bytes.Buffer
+}
+
+func newTestCLI() *testCLI {
+	svc := service.New(memoryrepo.New(), memorycache.New(), noopQueue{}, time.Second)
+	cli := &testCLI{
+		service: svc,
+		stdin:   new(bytes.Buffer),
+		stdout:  new(bytes.Buffer),
+		stderr:  new(bytes.Buffer),
+	}
+	cli.CLI = New(svc, cli.stdin, cli.stdout, cli.stderr)
+	return cli
+}
+
+// run runs the command and returns its output, stdout is reset first.
+func (c *testCLI) run(t *testing.T, args ...string) string {
+	t.Helper()
+
+	c.stdout.Reset()
+	require.NoError(t, c.Run(context.Background(), args), c.stderr.String())
+	return c.stdout.String()
+}
+
+func TestCLI_Links(t *testing.T) {
+	cli := newTestCLI()
+
+	var created model.Url
+	out := cli.run(t, "links", "create", "-url", "example.com", "-folder", "marketing", "-tags", "Promo,email", "-format", "json")
+	require.NoError(t, json.Unmarshal([]byte(out), &created))
+	assert.Equal(t, "https://example.com", created.Url)
+
+	out = cli.run(t, "links", "list")
+	assert.Contains(t, out, "SHORT URL")
+	assert.Contains(t, out, created.ShortUrl)
+	assert.Contains(t, out, "email,promo")
+
+	var link dto.LinkDTO
+	out = cli.run(t, "links", "update", created.ShortUrl, "-folder", "sales", "-format", "json")
+	require.NoError(t, json.Unmarshal([]byte(out), &link))
+	assert.Equal(t, "sales", link.Folder)
+	assert.Equal(t, []string{"email", "promo"}, link.Tags)
+
+	out = cli.run(t, "links", "disable", created.ShortUrl)
+	assert.Contains(t, out, model.LinkStatusDisabled)
+
+	var links []dto.LinkDTO
+	out = cli.run(t, "links", "list", "-status", "disabled", "-format", "json")
+	require.NoError(t, json.Unmarshal([]byte(out), &links))
+	require.Len(t, links, 1)
+	assert.Equal(t, created.ShortUrl, links[0].ShortUrl)
+
+	cli.run(t, "links", "enable", created.ShortUrl)
+	out = cli.run(t, "links", "delete", created.ShortUrl)
+	assert.Contains(t, out, "deleted "+created.ShortUrl)
+
+	out = cli.run(t, "links", "list", "-format", "json")
+	assert.JSONEq(t, "[]", out)
+}
+
+func TestCLI_LinkStats(t *testing.T) {
+	cli := newTestCLI()
+	ctx := context.Background()
+	created, err := cli.service.CreateShortUrl(ctx, model.Url{Url: "https://example.com"})
+	require.NoError(t, err)
+	for _, referrer := range []string{"https://google.com/search", ""} {
+		_, err := cli.service.GetUrlByShort(ctx, created.ShortUrl, model.RedirectInfo{
+			ShortUrl:  created.ShortUrl,
+			UserAgent: "test-agent",
+			Referrer:  referrer,
+			Source:    model.SourceDirect,
+		})
+		require.NoError(t, err)
+	}
+
+	var stats linkStats
+	out := cli.run(t, "links", "stats", created.ShortUrl, "-format", "json")
+	require.NoError(t, json.Unmarshal([]byte(out), &stats))
+	assert.Equal(t, 2, stats.RedirectCount)
+	assert.Equal(t, 1, stats.UserAgents)
+	assert.Len(t, stats.Referrers, 2)
+	assert.NotEmpty(t, stats.LastClick)
+
+	out = cli.run(t, "links", "stats", created.ShortUrl)
+	assert.Contains(t, out, "clicks")
+	assert.Contains(t, out, "referrer google")
+}
+
+func TestCLI_ExportImport(t *testing.T) {
+	source := newTestCLI()
+	ctx := context.Background()
+	created, err := source.service.CreateShortUrl(ctx, model.Url{Url: "https://example.com", Tags: []string{"promo"}})
+	require.NoError(t, err)
+	_, err = source.service.SetDisabled(ctx, created.ShortUrl, true)
+	require.NoError(t, err)
+	_, err = source.service.CreateShortUrl(ctx, model.Url{Url: "https://example.org"})
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "links.json")
+	source.run(t, "links", "export", "-o", file)
+	assert.Contains(t, source.stderr.String(), "exported 2 links")
+
+	target := newTestCLI()
+	out := target.run(t, "links", "import", "-i", file, "-format", "json")
+	var results []importResult
+	require.NoError(t, json.Unmarshal([]byte(out), &results))
+	require.Len(t, results, 2)
+	assert.Empty(t, results[0].Error)
+
+	var links []dto.LinkDTO
+	out = target.run(t, "links", "list", "-tag", "promo", "-format", "json")
+	require.NoError(t, json.Unmarshal([]byte(out), &links))
+	require.Len(t, links, 1)
+	assert.Equal(t, created.ShortUrl, links[0].ShortUrl)
+	assert.Equal(t, model.LinkStatusDisabled, links[0].Status)
+
+	// Importing the same links into another instance with a conflicting
+	// destination reports the failure and imports the rest.
+	conflict := newTestCLI()
+	_, err = conflict.service.CreateShortUrl(ctx, model.Url{Url: "https://example.org"})
+	require.NoError(t, err)
+	source.stdout.Reset()
+	source.run(t, "links", "export")
+	conflict.stdin.WriteString(source.stdout.String())
+	conflict.stdout.Reset()
+	err = conflict.Run(ctx, []string{"links", "import"})
+	assert.ErrorIs(t, err, ErrImportFailed)
+	assert.Contains(t, conflict.stdout.String(), "imported")
+	assert.Contains(t, conflict.stdout.String(), "failed: url is already shortened")
+}
+
+func TestCLI_Keys(t *testing.T) {
+	cli := newTestCLI()
+
+	var issued dto.IssuedApiKeyDTO
+	out := cli.run(t, "keys", "issue", "-name", "ci", "-format", "json")
+	require.NoError(t, json.Unmarshal([]byte(out), &issued))
+	assert.True(t, strings.HasPrefix(issued.Key, issued.Prefix))
+	assert.NotContains(t, out, "key_hash")
+
+	out = cli.run(t, "keys", "list")
+	assert.Contains(t, out, issued.Prefix)
+	assert.NotContains(t, out, issued.Key)
+
+	out = cli.run(t, "keys", "revoke", "1")
+	assert.Contains(t, out, "revoked key 1 (ci)")
+
+	var keys []model.ApiKey
+	out = cli.run(t, "keys", "list", "-format", "json")
+	require.NoError(t, json.Unmarshal([]byte(out), &keys))
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].RevokedAt)
+}
+
+func TestCLI_PurgeAnalytics(t *testing.T) {
+	cli := newTestCLI()
+	ctx := context.Background()
+	created, err := cli.service.CreateShortUrl(ctx, model.Url{Url: "https://example.com"})
+	require.NoError(t, err)
+	_, err = cli.service.GetUrlByShort(ctx, created.ShortUrl, model.RedirectInfo{ShortUrl: created.ShortUrl, Source: model.SourceDirect})
+	require.NoError(t, err)
+
+	var result purgeResult
+	out := cli.run(t, "analytics", "purge", "-older-than", "1h", "-format", "json")
+	require.NoError(t, json.Unmarshal([]byte(out), &result))
+	assert.Zero(t, result.Deleted)
+
+	cli.now = func() time.Time { return time.Now().Add(time.Hour) }
+	out = cli.run(t, "analytics", "purge", "-link", created.ShortUrl, "-older-than", "1m")
+	assert.Contains(t, out, "deleted 1 clicks")
+}
+
+func TestCLI_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"MissingSubcommand", []string{"links"}},
+		{"UnknownCommand", []string{"links", "rename"}},
+		{"UnknownFormat", []string{"links", "list", "-format", "xml"}},
+		{"MissingArgument", []string{"links", "delete"}},
+		{"UnknownFlag", []string{"keys", "list", "-all"}},
+		{"NothingToUpdate", []string{"links", "update", "abc123"}},
+		{"InvalidKeyId", []string{"keys", "revoke", "first"}},
+		{"PurgeWithoutTime", []string{"analytics", "purge"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := newTestCLI()
+
+			err := cli.Run(context.Background(), tt.args)
+
+			assert.ErrorIs(t, err, ErrUsage)
+			assert.NotEmpty(t, cli.stderr.String())
+			assert.Empty(t, cli.stdout.String())
+		})
+	}
+}
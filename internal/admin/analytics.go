@@ -0,0 +1,51 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// purgeResult is the outcome of "analytics purge".
+type purgeResult struct {
+	ShortUrl string    `json:"short_url,omitempty"`
+	Before   time.Time `json:"before"`
+	Deleted  int       `json:"deleted"`
+}
+
+func (c *CLI) purgeAnalytics(ctx context.Context, args []string) error {
+	cmd := c.newCommand("analytics purge")
+	before := cmd.String("before", "", "delete clicks recorded before the time")
+	olderThan := cmd.Duration("older-than", 0, "delete clicks older than the duration, for example 2160h")
+	short_url := cmd.String("link", "", "only clicks of the link, all links by default")
+	if _, err := cmd.parse(args, 0); err != nil {
+		return err
+	}
+
+	result := purgeResult{ShortUrl: *short_url}
+	switch {
+	case (*before == "") == (*olderThan == 0):
+		return c.usageError("analytics purge: set either -before or -older-than")
+	case *olderThan < 0:
+		return c.usageError("analytics purge: -older-than must be positive")
+	case *before != "":
+		t, err := parseTime(*before)
+		if err != nil {
+			return err
+		}
+		result.Before = t
+	default:
+		result.Before = c.now().Add(-*olderThan)
+	}
+
+	deleted, err := c.service.PurgeAnalytics(ctx, *short_url, result.Before)
+	if err != nil {
+		return err
+	}
+	result.Deleted = deleted
+
+	return cmd.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "deleted %d clicks recorded before %s\n", result.Deleted, result.Before.Format(timeLayout))
+	})
+}
@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+func (c *CLI) issueKey(ctx context.Context, args []string) error {
+	cmd := c.newCommand("keys issue")
+	name := cmd.String("name", "", "what the key is used for")
+	if _, err := cmd.parse(args, 0); err != nil {
+		return err
+	}
+
+	issued, err := c.service.IssueApiKey(ctx, *name)
+	if err != nil {
+		return err
+	}
+
+	return cmd.print(issued, func(w io.Writer) {
+		fmt.Fprintf(w, "id\t%d\n", issued.Id)
+		fmt.Fprintf(w, "name\t%s\n", issued.Name)
+		fmt.Fprintf(w, "key\t%s\n", issued.Key)
+		fmt.Fprintln(w, "\nthe key is shown only once, store it now")
+	})
+}
+
+func (c *CLI) listKeys(ctx context.Context, args []string) error {
+	cmd := c.newCommand("keys list")
+	if _, err := cmd.parse(args, 0); err != nil {
+		return err
+	}
+
+	keys, err := c.service.ListApiKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if keys == nil {
+		keys = []model.ApiKey{}
+	}
+
+	return cmd.print(keys, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tCREATED AT\tREVOKED AT")
+		for _, key := range keys {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", key.Id, key.Name, key.Prefix, formatTime(&key.CreatedAt), formatTime(key.RevokedAt))
+		}
+	})
+}
+
+func (c *CLI) revokeKey(ctx context.Context, args []string) error {
+	cmd := c.newCommand("keys revoke")
+	positional, err := cmd.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(positional[0])
+	if err != nil {
+		return c.usageError(fmt.Sprintf("keys revoke: invalid id %q", positional[0]))
+	}
+
+	revoked, err := c.service.RevokeApiKey(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return cmd.print(revoked, func(w io.Writer) {
+		fmt.Fprintf(w, "revoked key %d (%s) at %s\n", revoked.Id, revoked.Name, formatTime(revoked.RevokedAt))
+	})
+}
@@ -0,0 +1,342 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+func (c *CLI) createLink(ctx context.Context, args []string) error {
+	cmd := c.newCommand("links create")
+	var (
+		url     model.Url
+		tags    string
+		expires string
+	)
+	cmd.StringVar(&url.Url, "url", "", "destination url")
+	cmd.StringVar(&url.FallbackUrl, "fallback", "", "url used while the destination is unhealthy")
+	cmd.StringVar(&url.Folder, "folder", "", "folder of the link")
+	cmd.StringVar(&tags, "tags", "", "comma separated tags")
+	cmd.StringVar(&url.Owner, "owner", "", "owner of the link")
+	cmd.StringVar(&expires, "expires", "", "expiration time")
+	if _, err := cmd.parse(args, 0); err != nil {
+		return err
+	}
+
+	if url.Url == "" {
+		return c.usageError("links create: -url is required")
+	}
+	url.Tags = splitList(tags)
+	if expires != "" {
+		expiresAt, err := parseTime(expires)
+		if err != nil {
+			return err
+		}
+		url.ExpiresAt = &expiresAt
+	}
+
+	created, err := c.service.CreateShortUrl(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	return cmd.print(created, func(w io.Writer) {
+		fmt.Fprintln(w, "SHORT URL\tURL")
+		fmt.Fprintf(w, "%s\t%s\n", created.ShortUrl, created.Url)
+	})
+}
+
+// addSearchFlags registers the link search flags shared by list and
+// export.
+func addSearchFlags(cmd *command, search *dto.LinkQuery) {
+	cmd.StringVar(&search.Search, "search", "", "substring of the url, short url, title or tags")
+	cmd.StringVar(&search.Folder, "folder", "", "only links in the folder")
+	cmd.StringVar(&search.Tag, "tag", "", "only links with the tag")
+	cmd.StringVar(&search.Owner, "owner", "", "only links of the owner")
+	cmd.StringVar(&search.Status, "status", "", "only active, expired or disabled links")
+}
+
+func (c *CLI) listLinks(ctx context.Context, args []string) error {
+	cmd := c.newCommand("links list")
+	var search dto.LinkQuery
+	addSearchFlags(cmd, &search)
+	cmd.StringVar(&search.Sort, "sort", "", "created, last_click or clicks")
+	cmd.StringVar(&search.Order, "order", "", "asc or desc")
+	cmd.IntVar(&search.Limit, "limit", 0, "maximum number of links")
+	cmd.IntVar(&search.Offset, "offset", 0, "number of links to skip")
+	if _, err := cmd.parse(args, 0); err != nil {
+		return err
+	}
+
+	links, err := c.service.SearchLinks(ctx, search)
+	if err != nil {
+		return err
+	}
+	if links == nil {
+		links = []dto.LinkDTO{}
+	}
+
+	return cmd.print(links, func(w io.Writer) {
+		fmt.Fprintln(w, "SHORT URL\tURL\tSTATUS\tFOLDER\tTAGS\tCLICKS\tLAST CLICK\tCREATED AT")
+		for _, link := range links {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				link.ShortUrl,
+				link.Url,
+				link.Status,
+				orDash(link.Folder),
+				orDash(strings.Join(link.Tags, ",")),
+				link.Clicks,
+				formatTime(link.LastClick),
+				formatTime(&link.CreatedAt),
+			)
+		}
+	})
+}
+
+func (c *CLI) printLink(cmd *command, link *dto.LinkDTO) error {
+	return cmd.print(link, func(w io.Writer) {
+		fmt.Fprintln(w, "SHORT URL\tURL\tSTATUS\tFOLDER\tTAGS")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			link.ShortUrl,
+			link.Url,
+			link.Status,
+			orDash(link.Folder),
+			orDash(strings.Join(link.Tags, ",")),
+		)
+	})
+}
+
+func (c *CLI) updateLink(ctx context.Context, args []string) error {
+	cmd := c.newCommand("links update")
+	folder := cmd.String("folder", "", "new folder, empty moves the link out of its folder")
+	tags := cmd.String("tags", "", "new comma separated tags, empty removes all tags")
+	positional, err := cmd.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	set := make(map[string]bool)
+	cmd.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if !set["folder"] && !set["tags"] {
+		return c.usageError("links update: nothing to update, set -folder and/or -tags")
+	}
+
+	short_url := positional[0]
+	var link *dto.LinkDTO
+	if set["folder"] {
+		if link, err = c.service.SetFolder(ctx, short_url, *folder); err != nil {
+			return err
+		}
+	}
+	if set["tags"] {
+		if link, err = c.service.SetTags(ctx, short_url, splitList(*tags)); err != nil {
+			return err
+		}
+	}
+
+	return c.printLink(cmd, link)
+}
+
+func (c *CLI) setDisabled(ctx context.Context, args []string, disabled bool) error {
+	name := "links enable"
+	if disabled {
+		name = "links disable"
+	}
+	cmd := c.newCommand(name)
+	positional, err := cmd.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	link, err := c.service.SetDisabled(ctx, positional[0], disabled)
+	if err != nil {
+		return err
+	}
+
+	return c.printLink(cmd, link)
+}
+
+func (c *CLI) deleteLink(ctx context.Context, args []string) error {
+	cmd := c.newCommand("links delete")
+	positional, err := cmd.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := c.service.DeleteLink(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	return cmd.print(deleted, func(w io.Writer) {
+		fmt.Fprintf(w, "deleted %s (%s)\n", deleted.ShortUrl, deleted.Url)
+	})
+}
+
+// linkStats is the summary printed by "links stats".
+type linkStats struct {
+	ShortUrl      string              `json:"short_url"`
+	Url           string              `json:"url,omitempty"`
+	RedirectCount int                 `json:"redirect_count"`
+	QrScans       int                 `json:"qr_scans"`
+	UserAgents    int                 `json:"user_agents"`
+	LastClick     string              `json:"last_click,omitempty"`
+	Referrers     []dto.ReferrerCount `json:"referrers"`
+	Variants      []dto.VariantCount  `json:"variants,omitempty"`
+}
+
+func (c *CLI) linkStats(ctx context.Context, args []string) error {
+	cmd := c.newCommand("links stats")
+	positional, err := cmd.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	analytics, err := c.service.GetAnalytics(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	stats := linkStats{ShortUrl: positional[0], Referrers: []dto.ReferrerCount{}}
+	for _, info := range analytics {
+		stats.Url = info.Url
+		stats.RedirectCount += info.RedirectCount
+		stats.QrScans += info.QrScans
+		stats.UserAgents += len(info.UserAgent)
+		stats.Referrers = append(stats.Referrers, info.Referrers...)
+		stats.Variants = append(stats.Variants, info.Variants...)
+		for _, requestTime := range info.RequestTime {
+			if requestTime > stats.LastClick {
+				stats.LastClick = requestTime
+			}
+		}
+	}
+
+	return cmd.print(stats, func(w io.Writer) {
+		fmt.Fprintf(w, "short url\t%s\n", stats.ShortUrl)
+		fmt.Fprintf(w, "url\t%s\n", orDash(stats.Url))
+		fmt.Fprintf(w, "clicks\t%d\n", stats.RedirectCount)
+		fmt.Fprintf(w, "qr scans\t%d\n", stats.QrScans)
+		fmt.Fprintf(w, "user agents\t%d\n", stats.UserAgents)
+		fmt.Fprintf(w, "last click\t%s\n", orDash(stats.LastClick))
+		for _, referrer := range stats.Referrers {
+			fmt.Fprintf(w, "referrer %s\t%d\n", referrer.Referrer, referrer.RedirectCount)
+		}
+		for _, variant := range stats.Variants {
+			fmt.Fprintf(w, "variant %s\t%d\n", variant.Variant, variant.RedirectCount)
+		}
+	})
+}
+
+// exportLinks always writes JSON, it is the input format of "links import".
+func (c *CLI) exportLinks(ctx context.Context, args []string) error {
+	cmd := c.newCommand("links export")
+	var search dto.LinkQuery
+	addSearchFlags(cmd, &search)
+	output := cmd.String("o", "", "output file, stdout by default")
+	if _, err := cmd.parse(args, 0); err != nil {
+		return err
+	}
+
+	urls, err := c.service.ExportLinks(ctx, search)
+	if err != nil {
+		return err
+	}
+	if urls == nil {
+		urls = []model.Url{}
+	}
+
+	out := c.stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("could not create export file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(urls); err != nil {
+		return fmt.Errorf("could not write export: %w", err)
+	}
+	if *output != "" {
+		fmt.Fprintf(c.stderr, "exported %d links to %s\n", len(urls), *output)
+	}
+	return nil
+}
+
+// importResult is the outcome of importing a single link.
+type importResult struct {
+	ShortUrl string `json:"short_url"`
+	Url      string `json:"url"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ErrImportFailed is returned when some links of an import could not be
+// stored, the others are imported anyway.
+var ErrImportFailed = errors.New("some links were not imported")
+
+func (c *CLI) importLinks(ctx context.Context, args []string) error {
+	cmd := c.newCommand("links import")
+	input := cmd.String("i", "", "input file in the format of links export, stdin by default")
+	if _, err := cmd.parse(args, 0); err != nil {
+		return err
+	}
+
+	in := c.stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return fmt.Errorf("could not open import file: %w", err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	var urls []model.Url
+	if err := json.NewDecoder(in).Decode(&urls); err != nil {
+		return fmt.Errorf("could not parse import: %w", err)
+	}
+
+	results := make([]importResult, 0, len(urls))
+	failed := 0
+	for _, url := range urls {
+		result := importResult{ShortUrl: url.ShortUrl, Url: url.Url}
+		if imported, err := c.service.ImportLink(ctx, url); err != nil {
+			result.Error = err.Error()
+			failed++
+		} else {
+			result.Url = imported.Url
+		}
+		results = append(results, result)
+	}
+
+	err := cmd.print(results, func(w io.Writer) {
+		fmt.Fprintln(w, "SHORT URL\tURL\tRESULT")
+		for _, result := range results {
+			status := "imported"
+			if result.Error != "" {
+				status = "failed: " + result.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", result.ShortUrl, result.Url, status)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d failed", ErrImportFailed, failed, len(urls))
+	}
+	return nil
+}
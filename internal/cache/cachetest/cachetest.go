@@ -0,0 +1,107 @@
+// Package cachetest is a contract test suite for cache backends. Every
+// implementation of service.Cache must pass it so that switching backends
+// does not change the behaviour of the service.
+package cachetest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the cache contract against caches returned by newCache. Keys are
+// unique per run, so the backend does not have to be empty.
+func Run(t *testing.T, newCache func(t *testing.T) service.Cache) {
+	prefix := fmt.Sprintf("cachetest:%d:", time.Now().UnixNano())
+	key := func(t *testing.T) string {
+		return prefix + t.Name()
+	}
+
+	t.Run("MissingKey", func(t *testing.T) {
+		cache := newCache(t)
+
+		_, err := cache.Get(context.Background(), key(t))
+
+		assert.ErrorIs(t, err, redis.Nil)
+	})
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		cache := newCache(t)
+
+		require.NoError(t, cache.Set(context.Background(), key(t), "abc123"))
+		value, err := cache.Get(context.Background(), key(t))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc123", value)
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		cache := newCache(t)
+
+		require.NoError(t, cache.Set(context.Background(), key(t), "first"))
+		require.NoError(t, cache.Set(context.Background(), key(t), "second"))
+		value, err := cache.Get(context.Background(), key(t))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "second", value)
+	})
+
+	t.Run("NonStringValue", func(t *testing.T) {
+		cache := newCache(t)
+
+		require.NoError(t, cache.Set(context.Background(), key(t), 42))
+		value, err := cache.Get(context.Background(), key(t))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "42", value)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		cache := newCache(t)
+
+		require.NoError(t, cache.Set(context.Background(), key(t)+":1", "first"))
+		require.NoError(t, cache.Set(context.Background(), key(t)+":2", "second"))
+		require.NoError(t, cache.Delete(context.Background(), key(t)+":1", key(t)+":2", key(t)+":missing"))
+
+		_, err := cache.Get(context.Background(), key(t)+":1")
+		assert.ErrorIs(t, err, redis.Nil)
+		_, err = cache.Get(context.Background(), key(t)+":2")
+		assert.ErrorIs(t, err, redis.Nil)
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		cache := newCache(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.ErrorIs(t, cache.Set(ctx, key(t), "abc123"), context.Canceled)
+		_, err := cache.Get(ctx, key(t))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		cache := newCache(t)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				k := key(t) + ":" + strconv.Itoa(i)
+				assert.NoError(t, cache.Set(context.Background(), k, i))
+				value, err := cache.Get(context.Background(), k)
+				assert.NoError(t, err)
+				assert.Equal(t, strconv.Itoa(i), value)
+			}(i)
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,119 @@
+// Package memory is an in-memory cache with the semantics of the Redis
+// cache: values are stored as strings, expire after a day and a missing key
+// is reported with redis.Nil.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+type Cache struct {
+	mu      sync.RWMutex
+	entries map[string]entry
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+func New() *Cache {
+	return &Cache{
+		entries: make(map[string]entry),
+		ttl:     time.Hour * 24,
+		now:     time.Now,
+	}
+}
+
+// Ping and Close exist for parity with the Redis cache, the memory cache
+// is always available and there is nothing to release.
+func (c *Cache) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (c *Cache) Close() error {
+	return nil
+}
+
+func (c *Cache) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok {
+		return "", redis.Nil
+	}
+	if !c.now().Before(e.expiresAt) {
+		c.mu.Lock()
+		if current, ok := c.entries[key]; ok && current == e {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+		return "", redis.Nil
+	}
+
+	return e.value, nil
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry{
+		value:     format(value),
+		expiresAt: c.now().Add(c.ttl),
+	}
+
+	return nil
+}
+
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+
+	return nil
+}
+
+// format converts value to the string Redis would store for it.
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/cache/cachetest"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Contract(t *testing.T) {
+	cachetest.Run(t, func(t *testing.T) service.Cache {
+		return New()
+	})
+}
+
+func TestCache_Expiration(t *testing.T) {
+	cache := New()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	assert.NoError(t, cache.Set(context.Background(), "key", "value"))
+
+	now = now.Add(23 * time.Hour)
+	value, err := cache.Get(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	now = now.Add(time.Hour)
+	_, err = cache.Get(context.Background(), "key")
+	assert.ErrorIs(t, err, redis.Nil)
+	assert.Empty(t, cache.entries)
+}
@@ -2,10 +2,8 @@ package redis
 
 import (
 	"context"
-	"os"
 	"time"
 
-	"github.com/Komilov31/url-shortener/internal/config"
 	"github.com/wb-go/wbf/redis"
 )
 
@@ -13,11 +11,9 @@ type Redis struct {
 	client redis.Client
 }
 
-func New() *Redis {
-	password := os.Getenv("REDIS_PASSWORD")
-
+func New(addr, password string) *Redis {
 	client := redis.New(
-		config.Cfg.Redis.Host+":"+config.Cfg.Redis.Port,
+		addr,
 		password,
 		0,
 	)
@@ -27,10 +23,22 @@ func New() *Redis {
 	}
 }
 
-func (r *Redis) Get(key string) (string, error) {
-	return r.client.Get(context.Background(), key)
+func (r *Redis) Get(ctx context.Context, key string) (string, error) {
+	return r.client.Get(ctx, key)
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value interface{}) error {
+	return r.client.SetEX(ctx, key, value, time.Hour*24).Err()
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	return r.client.Del(ctx, keys...).Err()
+}
+
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
 }
 
-func (r *Redis) Set(key string, value interface{}) error {
-	return r.client.SetEX(context.Background(), key, value, time.Hour*24).Err()
+func (r *Redis) Close() error {
+	return r.client.Close()
 }
@@ -0,0 +1,21 @@
+package redis
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Komilov31/url-shortener/internal/cache/cachetest"
+	"github.com/Komilov31/url-shortener/internal/service"
+)
+
+// TestRedis_Contract runs against a real Redis when TEST_REDIS_ADDR is set.
+func TestRedis_Contract(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	cachetest.Run(t, func(t *testing.T) service.Cache {
+		return New(addr, os.Getenv("TEST_REDIS_PASSWORD"))
+	})
+}
@@ -1,35 +1,209 @@
+// Package config loads the application configuration. Values come, from
+// lowest to highest priority, from Default, the YAML file and environment
+// variables. Nothing is read when the package is imported.
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/wb-go/wbf/config"
 )
 
-var Cfg = initConfig("config/config.yaml")
+// DefaultPath is the configuration file used when no other is given.
+const DefaultPath = "config/config.yaml"
 
-func initConfig(path string) *Config {
-	wbfConfig := config.New()
+// EnvPrefix starts the name of every environment variable that overrides
+// a configuration field, for example APP_HTTP_SERVER_ADDRESS overrides
+// http_server.address.
+const EnvPrefix = "APP_"
 
-	err := wbfConfig.Load(path)
-	if err != nil {
-		log.Fatal("could not read config file: ", err)
+// Default returns the configuration used for fields that are set neither
+// in the file nor in the environment.
+func Default() Config {
+	return Config{
+		Storage:  StorageConfig{Backend: "postgres"},
+		Cache:    CacheConfig{Backend: "redis"},
+		Postgres: PostgresConfig{Host: "localhost", Port: 5432},
+		Sqlite:   SqliteConfig{Path: "url_shortener.db"},
+		HttpServer: HttpServerConfig{
+			Address:           ":8080",
+			Timeout:           4,
+			ReadTimeout:       10,
+			ReadHeaderTimeout: 5,
+			WriteTimeout:      10,
+			IdleTimeout:       60,
+			ShutdownTimeout:   15,
+			ShutdownDelay:     0,
+			ReadinessTimeout:  1,
+		},
+		Redis: RedisConfig{Host: "localhost", Port: "6379"},
+		Metadata: MetadataConfig{
+			Workers:      4,
+			QueueSize:    1000,
+			Timeout:      5,
+			MaxBodyBytes: 1 << 20,
+			MaxRedirects: 5,
+			MaxAttempts:  3,
+			RetryDelay:   2,
+		},
+		HealthCheck: HealthCheckConfig{
+			Enabled:          true,
+			Interval:         600,
+			Timeout:          5,
+			Concurrency:      8,
+			PerHostDelay:     1,
+			MaxRedirects:     5,
+			FailureThreshold: 3,
+		},
+		Metrics: MetricsConfig{Enabled: true},
+		Tracing: TracingConfig{Exporter: "none", ServiceName: "url-shortener"},
+		Grpc:    GrpcConfig{Address: ":50051"},
+		Idempotency: IdempotencyConfig{
+			TTL:           86400,
+			Lease:         60,
+			PurgeInterval: 3600,
+		},
+	}
+}
+
+// Load reads the configuration file at path on top of Default, applies the
+// environment overrides and validates the result. An empty path skips the
+// file.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		wbfConfig := config.New()
+		if err := wbfConfig.Load(path); err != nil {
+			return nil, fmt.Errorf("could not read config file: %w", err)
+		}
+		if err := wbfConfig.Unmarshal(&cfg); err != nil {
+			return nil, fmt.Errorf("could not parse config file: %w", err)
+		}
+	}
+
+	if err := applyEnv(&cfg, os.LookupEnv); err != nil {
+		return nil, err
 	}
 
-	var cfg Config
-	if err := wbfConfig.Unmarshal(&cfg); err != nil {
-		log.Fatal("could not parse config file: ", err)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
-	err = godotenv.Load(".env")
+	return &cfg, nil
+}
+
+// LoadDotEnv adds the variables of a .env file to the environment without
+// overriding variables that are already set. A missing file is not an
+// error.
+func LoadDotEnv(path string) error {
+	err := godotenv.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		log.Fatal("could not load .env file: ", err)
+		return fmt.Errorf("could not load %s: %w", path, err)
+	}
+	return nil
+}
+
+// EnvNames returns the environment variables that override the field with
+// the given key, like "postgres.password", highest priority first.
+func EnvNames(key string) []string {
+	names := []string{EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))}
+	if alias, ok := envAliases[key]; ok {
+		names = append(names, alias)
+	}
+	return names
+}
+
+// envAliases keeps the variable names used by docker-compose.yml and
+// .env.example before every field could be overridden.
+var envAliases = map[string]string{
+	"postgres.host":     "DB_HOST",
+	"postgres.port":     "DB_PORT",
+	"postgres.user":     "DB_USER",
+	"postgres.password": "DB_PASSWORD",
+	"postgres.name":     "DB_NAME",
+	"redis.password":    "REDIS_PASSWORD",
+}
+
+// applyEnv overrides every field of cfg that has an environment variable
+// set, fields are found by their mapstructure keys.
+func applyEnv(cfg *Config, lookupEnv func(string) (string, bool)) error {
+	var errs []error
+	walkFields(reflect.ValueOf(cfg).Elem(), "", func(key string, field reflect.Value) {
+		for _, name := range EnvNames(key) {
+			value, ok := lookupEnv(name)
+			if !ok {
+				continue
+			}
+			if err := setField(field, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			return
+		}
+	})
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid environment: %w", errors.Join(errs...))
 	}
+	return nil
+}
+
+func walkFields(v reflect.Value, prefix string, visit func(key string, field reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("mapstructure")
+		if prefix != "" {
+			key = prefix + "." + key
+		}
 
-	value, _ := os.LookupEnv("DB_PASSWORD")
-	cfg.Postgres.Password = value
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			walkFields(field, key, visit)
+			continue
+		}
+		visit(key, field)
+	}
+}
 
-	return &cfg
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", field.Type())
+		}
+		// Lists are comma separated, empty items are skipped.
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Kind())
+	}
+	return nil
 }
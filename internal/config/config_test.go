@@ -0,0 +1,180 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load("")
+
+	require.Error(t, err)
+	assert.Nil(t, cfg)
+	assert.Contains(t, err.Error(), "postgres.name: is required")
+
+	t.Setenv("APP_STORAGE_BACKEND", "memory")
+	t.Setenv("APP_CACHE_BACKEND", "memory")
+	cfg, err = Load("")
+
+	require.NoError(t, err)
+	expected := Default()
+	expected.Storage.Backend = "memory"
+	expected.Cache.Backend = "memory"
+	assert.Equal(t, &expected, cfg)
+}
+
+func TestLoad_File(t *testing.T) {
+	path := writeConfig(t, `
+storage:
+  backend: "sqlite"
+sqlite:
+  path: "/tmp/links.db"
+postgres:
+  replicas:
+    - "host=replica-1 dbname=links"
+http_server:
+  timeout: 6
+metadata:
+  workers: 2
+health_check:
+  enabled: false
+`)
+
+	cfg, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", cfg.Storage.Backend)
+	assert.Equal(t, "/tmp/links.db", cfg.Sqlite.Path)
+	assert.Equal(t, []string{"host=replica-1 dbname=links"}, cfg.Postgres.Replicas)
+	assert.Equal(t, 6, cfg.HttpServer.Timeout)
+	assert.Equal(t, 2, cfg.Metadata.Workers)
+	assert.False(t, cfg.HealthCheck.Enabled)
+	// Fields missing in the file keep their defaults.
+	assert.Equal(t, ":8080", cfg.HttpServer.Address)
+	assert.Equal(t, 1000, cfg.Metadata.QueueSize)
+	assert.Equal(t, "redis", cfg.Cache.Backend)
+}
+
+func TestLoad_RepositoryConfig(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "secret")
+
+	cfg, err := Load(filepath.Join("..", "..", DefaultPath))
+
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", cfg.Storage.Backend)
+	assert.Equal(t, "secret", cfg.Postgres.Password)
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	assert.ErrorContains(t, err, "could not read config file")
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	path := writeConfig(t, `
+storage:
+  backend: "postgres"
+postgres:
+  host: "postgres"
+  name: "links"
+  user: "user"
+  port: 5432
+http_server:
+  address: ":8080"
+`)
+	t.Setenv("APP_HTTP_SERVER_ADDRESS", ":9090")
+	t.Setenv("APP_METADATA_MAX_BODY_BYTES", "2048")
+	t.Setenv("APP_HEALTH_CHECK_ENABLED", "false")
+	t.Setenv("DB_HOST", "db.internal")
+	t.Setenv("DB_PORT", "5433")
+	t.Setenv("DB_PASSWORD", "legacy")
+	t.Setenv("APP_POSTGRES_PASSWORD", "secret")
+	t.Setenv("REDIS_PASSWORD", "redis-secret")
+	t.Setenv("APP_POSTGRES_REPLICAS", "host=replica-1 dbname=links, ,host=replica-2 dbname=links")
+
+	cfg, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.HttpServer.Address)
+	assert.Equal(t, int64(2048), cfg.Metadata.MaxBodyBytes)
+	assert.False(t, cfg.HealthCheck.Enabled)
+	assert.Equal(t, "db.internal", cfg.Postgres.Host)
+	assert.Equal(t, 5433, cfg.Postgres.Port)
+	assert.Equal(t, "secret", cfg.Postgres.Password)
+	assert.Equal(t, "redis-secret", cfg.Redis.Password)
+	assert.Equal(t, "links", cfg.Postgres.Name)
+	assert.Equal(t, []string{"host=replica-1 dbname=links", "host=replica-2 dbname=links"}, cfg.Postgres.Replicas)
+}
+
+func TestLoad_InvalidEnv(t *testing.T) {
+	t.Setenv("APP_STORAGE_BACKEND", "memory")
+	t.Setenv("APP_CACHE_BACKEND", "memory")
+	t.Setenv("APP_METADATA_WORKERS", "many")
+	t.Setenv("APP_HEALTH_CHECK_ENABLED", "maybe")
+
+	_, err := Load("")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `APP_METADATA_WORKERS: "many" is not an integer`)
+	assert.Contains(t, err.Error(), `APP_HEALTH_CHECK_ENABLED: "maybe" is not a boolean`)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Storage.Backend = "mysql"
+	cfg.Cache.Backend = "redis"
+	cfg.Redis.Port = "redis"
+	cfg.HttpServer.Address = ""
+	cfg.Metadata.Workers = 0
+	cfg.HealthCheck.Concurrency = 0
+	cfg.HttpServer.WriteTimeout = cfg.HttpServer.Timeout
+	cfg.HttpServer.ReadinessTimeout = 0
+	cfg.Tracing.Exporter = "jaeger"
+	cfg.Grpc.Enabled = true
+	cfg.Grpc.Address = ":9090"
+	cfg.Metrics.Address = ":9090"
+	cfg.Idempotency.TTL = 0
+	cfg.Idempotency.Lease = cfg.HttpServer.Timeout
+	cfg.HttpServer.PublicUrl = "sho.rt"
+
+	err := cfg.Validate()
+
+	require.Error(t, err)
+	for _, msg := range []string{
+		`storage.backend: unknown backend "mysql", expected postgres, sqlite or memory`,
+		`redis.port: "redis" is not a port number`,
+		"http_server.address: is required",
+		"metadata.workers: must be at least 1, got 0",
+		"health_check.concurrency: must be at least 1, got 0",
+		"http_server.write_timeout: must be greater than http_server.timeout (4), got 4",
+		"http_server.readiness_timeout: must be at least 1, got 0",
+		`tracing.exporter: unknown exporter "jaeger", expected none, stdout or otlp`,
+		"grpc.address: must differ from http_server.address and metrics.address",
+		"idempotency.ttl: must be at least 1, got 0",
+		"idempotency.lease: must be greater than http_server.timeout (4), got 4",
+		`http_server.public_url: "sho.rt" is not an absolute http or https URL`,
+	} {
+		assert.Contains(t, err.Error(), msg)
+	}
+
+	cfg.HealthCheck.Enabled = false
+	assert.NotContains(t, cfg.Validate().Error(), "health_check")
+}
+
+func TestEnvNames(t *testing.T) {
+	assert.Equal(t, []string{"APP_HTTP_SERVER_IDLE_TIMEOUT"}, EnvNames("http_server.idle_timeout"))
+	assert.Equal(t, []string{"APP_POSTGRES_PASSWORD", "DB_PASSWORD"}, EnvNames("postgres.password"))
+}
@@ -1,27 +1,133 @@
 package config
 
+// Config is the whole application configuration. Every field can be set in
+// the YAML file under its mapstructure key or overridden with an
+// environment variable, see EnvNames.
 type Config struct {
-	Postgres   PostgresConfig   `mapstructure:"postgres"`
-	HttpServer HttpServerConfig `mapstructure:"http_server"`
-	Redis      RedisConfig      `mapstructure:"redis"`
+	Storage     StorageConfig     `mapstructure:"storage"`
+	Cache       CacheConfig       `mapstructure:"cache"`
+	Postgres    PostgresConfig    `mapstructure:"postgres"`
+	Sqlite      SqliteConfig      `mapstructure:"sqlite"`
+	HttpServer  HttpServerConfig  `mapstructure:"http_server"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	Metadata    MetadataConfig    `mapstructure:"metadata"`
+	HealthCheck HealthCheckConfig `mapstructure:"health_check"`
+	Metrics     MetricsConfig     `mapstructure:"metrics"`
+	Tracing     TracingConfig     `mapstructure:"tracing"`
+	Grpc        GrpcConfig        `mapstructure:"grpc"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
 }
 
+// StorageConfig selects where links and analytics are kept: "postgres",
+// "sqlite" or "memory". The in-memory backend loses everything on restart.
+type StorageConfig struct {
+	Backend string `mapstructure:"backend"`
+}
+
+// CacheConfig selects the cache: "redis" or "memory".
+type CacheConfig struct {
+	Backend string `mapstructure:"backend"`
+}
+
+// PostgresConfig is the postgres storage, Password is usually set with the
+// DB_PASSWORD environment variable. Replicas are the connection strings of
+// read replicas, reads go to the first one.
 type PostgresConfig struct {
-	Name     string `mapstructure:"name"`
-	User     string `mapstructure:"user"`
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	Password string `mapstructure:"password"`
+	Name     string   `mapstructure:"name"`
+	User     string   `mapstructure:"user"`
+	Host     string   `mapstructure:"host"`
+	Port     int      `mapstructure:"port"`
+	Password string   `mapstructure:"password"`
+	Replicas []string `mapstructure:"replicas"`
+}
+
+// SqliteConfig is the database file of the sqlite storage backend, it is
+// created when missing.
+type SqliteConfig struct {
+	Path string `mapstructure:"path"`
 }
 
+// HttpServerConfig holds the server timeouts in seconds. Timeout bounds
+// the storage and cache operations of a single request, WriteTimeout must
+// leave room for it. ShutdownTimeout is how long in-flight requests may
+// take to finish after SIGINT or SIGTERM. ShutdownDelay keeps serving with
+// /readyz reporting not ready for a while before that, so that load
+// balancers stop sending new requests first. ReadinessTimeout bounds each
+// dependency check of /readyz.
 type HttpServerConfig struct {
-	Address     string `mapstructure:"address"`
-	Timeout     int    `mapstructure:"timeout"`
-	IdleTimeout int    `mapstructure:"idle_timeout"`
+	Address           string `mapstructure:"address"`
+	Timeout           int    `mapstructure:"timeout"`
+	ReadTimeout       int    `mapstructure:"read_timeout"`
+	ReadHeaderTimeout int    `mapstructure:"read_header_timeout"`
+	WriteTimeout      int    `mapstructure:"write_timeout"`
+	IdleTimeout       int    `mapstructure:"idle_timeout"`
+	ShutdownTimeout   int    `mapstructure:"shutdown_timeout"`
+	ShutdownDelay     int    `mapstructure:"shutdown_delay"`
+	ReadinessTimeout  int    `mapstructure:"readiness_timeout"`
+	// PublicUrl is where clients reach the service, e.g. https://sho.rt.
+	// QR codes point at it, without it they use the Host of the request.
+	PublicUrl string `mapstructure:"public_url"`
 }
 
+// RedisConfig is the redis cache, Password is usually set with the
+// REDIS_PASSWORD environment variable.
 type RedisConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
 	Password string `mapstructure:"password"`
 }
+
+type MetadataConfig struct {
+	Workers      int   `mapstructure:"workers"`
+	QueueSize    int   `mapstructure:"queue_size"`
+	Timeout      int   `mapstructure:"timeout"`
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+	MaxRedirects int   `mapstructure:"max_redirects"`
+	MaxAttempts  int   `mapstructure:"max_attempts"`
+	RetryDelay   int   `mapstructure:"retry_delay"`
+}
+
+type HealthCheckConfig struct {
+	Enabled          bool `mapstructure:"enabled"`
+	Interval         int  `mapstructure:"interval"`
+	Timeout          int  `mapstructure:"timeout"`
+	Concurrency      int  `mapstructure:"concurrency"`
+	PerHostDelay     int  `mapstructure:"per_host_delay"`
+	MaxRedirects     int  `mapstructure:"max_redirects"`
+	FailureThreshold int  `mapstructure:"failure_threshold"`
+}
+
+// MetricsConfig enables /metrics. With an empty Address it is served by
+// the main HTTP server, otherwise by a separate listener, e.g. ":9090", so
+// that it can be kept off the public port.
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`
+}
+
+// TracingConfig selects where spans go: "none", "stdout" or "otlp". The
+// OTLP exporter speaks HTTP to Endpoint (host:port), an empty Endpoint
+// falls back to OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318.
+type TracingConfig struct {
+	Exporter    string `mapstructure:"exporter"`
+	Endpoint    string `mapstructure:"endpoint"`
+	Insecure    bool   `mapstructure:"insecure"`
+	ServiceName string `mapstructure:"service_name"`
+}
+
+// GrpcConfig enables the gRPC API for internal services on its own
+// Address, e.g. ":50051". Calls are bounded by http_server.timeout like
+// HTTP requests.
+type GrpcConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`
+}
+
+// IdempotencyConfig keeps the results of requests with an Idempotency-Key
+// for TTL seconds. A request that has not completed holds its key for
+// Lease seconds. Expired keys are deleted every PurgeInterval seconds.
+type IdempotencyConfig struct {
+	TTL           int `mapstructure:"ttl"`
+	Lease         int `mapstructure:"lease"`
+	PurgeInterval int `mapstructure:"purge_interval"`
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Validate reports every invalid field at once, each error names the field
+// by its key in the configuration file.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	switch c.Storage.Backend {
+	case "postgres":
+		v.require("postgres.host", c.Postgres.Host)
+		v.require("postgres.name", c.Postgres.Name)
+		v.require("postgres.user", c.Postgres.User)
+		v.port("postgres.port", c.Postgres.Port)
+	case "sqlite":
+		v.require("sqlite.path", c.Sqlite.Path)
+	case "memory":
+	default:
+		v.errorf("storage.backend", "unknown backend %q, expected postgres, sqlite or memory", c.Storage.Backend)
+	}
+
+	switch c.Cache.Backend {
+	case "redis":
+		v.require("redis.host", c.Redis.Host)
+		if port, err := strconv.Atoi(c.Redis.Port); err != nil {
+			v.errorf("redis.port", "%q is not a port number", c.Redis.Port)
+		} else {
+			v.port("redis.port", port)
+		}
+	case "memory":
+	default:
+		v.errorf("cache.backend", "unknown backend %q, expected redis or memory", c.Cache.Backend)
+	}
+
+	v.require("http_server.address", c.HttpServer.Address)
+	v.min("http_server.timeout", c.HttpServer.Timeout, 0)
+	v.min("http_server.read_timeout", c.HttpServer.ReadTimeout, 0)
+	v.min("http_server.read_header_timeout", c.HttpServer.ReadHeaderTimeout, 0)
+	v.min("http_server.write_timeout", c.HttpServer.WriteTimeout, 0)
+	v.min("http_server.idle_timeout", c.HttpServer.IdleTimeout, 0)
+	v.min("http_server.shutdown_timeout", c.HttpServer.ShutdownTimeout, 0)
+	v.min("http_server.shutdown_delay", c.HttpServer.ShutdownDelay, 0)
+	v.min("http_server.readiness_timeout", c.HttpServer.ReadinessTimeout, 1)
+	if c.HttpServer.WriteTimeout > 0 && c.HttpServer.WriteTimeout <= c.HttpServer.Timeout {
+		v.errorf("http_server.write_timeout", "must be greater than http_server.timeout (%d), got %d",
+			c.HttpServer.Timeout, c.HttpServer.WriteTimeout)
+	}
+
+	if c.HttpServer.PublicUrl != "" {
+		publicUrl, err := url.Parse(c.HttpServer.PublicUrl)
+		switch {
+		case err != nil:
+			v.errorf("http_server.public_url", "%v", err)
+		case publicUrl.Scheme != "http" && publicUrl.Scheme != "https" || publicUrl.Host == "":
+			v.errorf("http_server.public_url", "%q is not an absolute http or https URL", c.HttpServer.PublicUrl)
+		case publicUrl.RawQuery != "" || publicUrl.Fragment != "":
+			v.errorf("http_server.public_url", "must not have a query or fragment")
+		}
+	}
+
+	v.min("metadata.workers", c.Metadata.Workers, 1)
+	v.min("metadata.queue_size", c.Metadata.QueueSize, 1)
+	v.min("metadata.timeout", c.Metadata.Timeout, 1)
+	v.min("metadata.max_body_bytes", int(c.Metadata.MaxBodyBytes), 1)
+	v.min("metadata.max_redirects", c.Metadata.MaxRedirects, 0)
+	v.min("metadata.max_attempts", c.Metadata.MaxAttempts, 1)
+	v.min("metadata.retry_delay", c.Metadata.RetryDelay, 0)
+
+	if c.HealthCheck.Enabled {
+		v.min("health_check.interval", c.HealthCheck.Interval, 1)
+		v.min("health_check.timeout", c.HealthCheck.Timeout, 1)
+		v.min("health_check.concurrency", c.HealthCheck.Concurrency, 1)
+		v.min("health_check.per_host_delay", c.HealthCheck.PerHostDelay, 0)
+		v.min("health_check.max_redirects", c.HealthCheck.MaxRedirects, 0)
+		v.min("health_check.failure_threshold", c.HealthCheck.FailureThreshold, 1)
+	}
+
+	if c.Metrics.Enabled && c.Metrics.Address != "" && c.Metrics.Address == c.HttpServer.Address {
+		v.errorf("metrics.address", "must differ from http_server.address, leave it empty to serve metrics on the same port")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		v.errorf("tracing.exporter", "unknown exporter %q, expected none, stdout or otlp", c.Tracing.Exporter)
+	}
+	v.require("tracing.service_name", c.Tracing.ServiceName)
+
+	if c.Grpc.Enabled {
+		v.require("grpc.address", c.Grpc.Address)
+		taken := c.Grpc.Address == c.HttpServer.Address || c.Metrics.Enabled && c.Grpc.Address == c.Metrics.Address
+		if c.Grpc.Address != "" && taken {
+			v.errorf("grpc.address", "must differ from http_server.address and metrics.address")
+		}
+	}
+
+	v.min("idempotency.ttl", c.Idempotency.TTL, 1)
+	if c.Idempotency.Lease <= c.HttpServer.Timeout {
+		v.errorf("idempotency.lease", "must be greater than http_server.timeout (%d), got %d",
+			c.HttpServer.Timeout, c.Idempotency.Lease)
+	}
+	v.min("idempotency.purge_interval", c.Idempotency.PurgeInterval, 1)
+
+	if len(v.errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(v.errs...))
+	}
+	return nil
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) errorf(key, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+}
+
+func (v *validator) require(key, value string) {
+	if value == "" {
+		v.errorf(key, "is required")
+	}
+}
+
+func (v *validator) min(key string, value, min int) {
+	if value < min {
+		v.errorf(key, "must be at least %d, got %d", min, value)
+	}
+}
+
+func (v *validator) port(key string, port int) {
+	if port < 1 || port > 65535 {
+		v.errorf(key, "must be between 1 and 65535, got %d", port)
+	}
+}
@@ -1,5 +1,11 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
 type UserAgentDTO struct {
 	ShortUrl      string   `json:"short_url"`
 	UserAgent     []string `json:"user_agent"`
@@ -30,10 +36,142 @@ type MonthDTO struct {
 }
 
 type RedirectInfo struct {
-	Id            int      `json:"-"`
-	Url           string   `json:"url"`
-	ShortUrl      string   `json:"short_url"`
-	RedirectCount int      `json:"redirect_count"`
-	RequestTime   []string `json:"request_time"`
-	UserAgent     []string `json:"user_agent"`
+	Id            int             `json:"-"`
+	Url           string          `json:"url"`
+	ShortUrl      string          `json:"short_url"`
+	RedirectCount int             `json:"redirect_count"`
+	QrScans       int             `json:"qr_scans"`
+	RequestTime   []string        `json:"request_time"`
+	UserAgent     []string        `json:"user_agent"`
+	Variants      []VariantCount  `json:"variants,omitempty"`
+	Referrers     []ReferrerCount `json:"referrers"`
+}
+
+type VariantCount struct {
+	Variant       string `json:"variant"`
+	RedirectCount int    `json:"redirect_count"`
+}
+
+type VariantsDTO struct {
+	ShortUrl string          `json:"short_url"`
+	Sticky   bool            `json:"sticky"`
+	Variants []model.Variant `json:"variants"`
+}
+
+type CampaignDTO struct {
+	Period   time.Time `json:"period"`
+	Source   string    `json:"utm_source"`
+	Medium   string    `json:"utm_medium"`
+	Campaign string    `json:"utm_campaign"`
+	Clicks   int       `json:"clicks"`
+	Uniques  int       `json:"uniques"`
+}
+
+type ReferrerDTO struct {
+	ShortUrl      string          `json:"short_url"`
+	Referrers     []ReferrerCount `json:"referrers"`
+	RedirectCount int             `json:"redirect_count"`
+}
+
+type ReferrerCount struct {
+	Referrer      string `json:"referrer"`
+	RedirectCount int    `json:"redirect_count"`
+}
+
+// LinkFilter restricts link listings and analytics aggregations to links
+// in a folder and/or marked with a tag. Empty fields do not filter.
+type LinkFilter struct {
+	Tag    string `json:"tag,omitempty"`
+	Folder string `json:"folder,omitempty"`
+}
+
+const (
+	LinkSortCreated   = "created"
+	LinkSortLastClick = "last_click"
+	LinkSortClicks    = "clicks"
+
+	LinkOrderAsc  = "asc"
+	LinkOrderDesc = "desc"
+)
+
+// LinkQuery describes a link search. Zero values do not filter, Limit and
+// Sort fall back to defaults chosen by the service.
+type LinkQuery struct {
+	LinkFilter
+	Search      string
+	Owner       string
+	Status      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MinClicks   *int
+	MaxClicks   *int
+	Sort        string
+	Order       string
+	Limit       int
+	Offset      int
+}
+
+type LinkDTO struct {
+	ShortUrl  string     `json:"short_url"`
+	Url       string     `json:"url"`
+	Title     string     `json:"title"`
+	Folder    string     `json:"folder"`
+	Tags      []string   `json:"tags"`
+	Owner     string     `json:"owner"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Clicks    int        `json:"clicks"`
+	LastClick *time.Time `json:"last_click,omitempty"`
+}
+
+type DisabledDTO struct {
+	Disabled bool `json:"disabled"`
+}
+
+type FolderDTO struct {
+	Folder string `json:"folder"`
+}
+
+type TagDTO struct {
+	Tag           string `json:"tag"`
+	Links         int    `json:"links"`
+	RedirectCount int    `json:"redirect_count"`
+	Uniques       int    `json:"uniques"`
+}
+
+type ApiKeyNameDTO struct {
+	Name string `json:"name"`
+}
+
+// IssuedApiKeyDTO is a newly issued key, Key is shown only once.
+type IssuedApiKeyDTO struct {
+	model.ApiKey
+	Key string `json:"key"`
+}
+
+// ReadinessDTO is the answer of /readyz: ready, degraded, not_ready or
+// shutting_down together with the state of every dependency.
+type ReadinessDTO struct {
+	Status       string          `json:"status"`
+	Dependencies []DependencyDTO `json:"dependencies"`
+}
+
+type DependencyDTO struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ProblemDTO is the RFC 9457 problem details body of every error response.
+// Code is stable and meant for clients to switch on.
+type ProblemDTO struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
 }
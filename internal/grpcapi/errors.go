@@ -0,0 +1,64 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Komilov31/url-shortener/internal/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the ErrorInfo domain of the errors returned by the API,
+// their reason is the same code the HTTP API puts in problem details.
+const errorDomain = "url-shortener"
+
+var kindCode = map[service.Kind]codes.Code{
+	service.KindNotFound:        codes.NotFound,
+	service.KindConflict:        codes.AlreadyExists,
+	service.KindUnprocessable:   codes.InvalidArgument,
+	service.KindValidation:      codes.InvalidArgument,
+	service.KindUnauthenticated: codes.Unauthenticated,
+	service.KindExpired:         codes.FailedPrecondition,
+	service.KindForbidden:       codes.PermissionDenied,
+	service.KindUnavailable:     codes.Unavailable,
+}
+
+// statusError turns a service error into a gRPC status carrying its code in
+// an ErrorInfo. Like the HTTP API, internal errors are not described to the
+// client.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	code, reason, message := statusFor(err)
+	st := status.New(code, message)
+	if withInfo, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}); err == nil {
+		st = withInfo
+	}
+	return st.Err()
+}
+
+func statusFor(err error) (codes.Code, string, string) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded, "timeout", "request timed out"
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled, "request_cancelled", "request cancelled"
+	}
+
+	var domainErr *service.Error
+	if errors.As(err, &domainErr) {
+		code, ok := kindCode[domainErr.Kind]
+		if !ok {
+			return codes.Internal, "internal", "internal server error"
+		}
+		message := err.Error()
+		if code == codes.Unavailable {
+			message = domainErr.Message
+		}
+		return code, domainErr.Code, message
+	}
+	return codes.Internal, "internal", "internal server error"
+}
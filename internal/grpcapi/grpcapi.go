@@ -0,0 +1,95 @@
+// Package grpcapi serves the gRPC API of api/shortener/v1 for internal
+// services. It is a thin transport over the same service as the HTTP
+// handlers, every call is authenticated with an API key.
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	shortenerv1 "github.com/Komilov31/url-shortener/api/shortener/v1"
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type Service interface {
+	CreateShortUrl(context.Context, model.Url) (*model.Url, error)
+	CreateShortUrlIdempotent(context.Context, *model.ApiKey, string, model.Url) (*model.Url, bool, error)
+	GetLink(context.Context, string) (*dto.LinkDTO, error)
+	GetUrlByShort(context.Context, string, model.RedirectInfo) (*model.Url, error)
+	GetAnalytics(context.Context, string) ([]dto.RedirectInfo, error)
+	AuthenticateApiKey(context.Context, string) (*model.ApiKey, error)
+}
+
+type Server struct {
+	shortenerv1.UnimplementedShortenerServiceServer
+	service Service
+}
+
+func New(service Service) *Server {
+	return &Server{
+		service: service,
+	}
+}
+
+// NewServer returns a gRPC server with the shortener and the standard
+// health service registered. Health checks need no API key so that probes
+// do not have to carry one.
+func NewServer(service Service) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryLogging, unaryAuth(service)),
+		grpc.ChainStreamInterceptor(streamLogging, streamAuth(service)),
+	)
+	shortenerv1.RegisterShortenerServiceServer(srv, New(service))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	return srv
+}
+
+// Run listens on address, see Serve.
+func Run(ctx context.Context, srv *grpc.Server, address string, shutdownTimeout time.Duration) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", address, err)
+	}
+	return Serve(ctx, srv, listener, shutdownTimeout)
+}
+
+// Serve serves calls until ctx is cancelled, then stops accepting new ones
+// and waits up to shutdownTimeout for the running ones. Calls still
+// running after that are cut off.
+func Serve(ctx context.Context, srv *grpc.Server, listener net.Listener, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	var deadline <-chan time.Time
+	if shutdownTimeout > 0 {
+		deadline = time.After(shutdownTimeout)
+	}
+	select {
+	case <-stopped:
+	case <-deadline:
+		srv.Stop()
+		<-stopped
+		return fmt.Errorf("could not drain calls: %w", context.DeadlineExceeded)
+	}
+	return <-serveErr
+}
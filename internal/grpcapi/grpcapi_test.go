@@ -0,0 +1,303 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	shortenerv1 "github.com/Komilov31/url-shortener/api/shortener/v1"
+	memorycache "github.com/Komilov31/url-shortener/internal/cache/memory"
+	"github.com/Komilov31/url-shortener/internal/model"
+	memoryrepo "github.com/Komilov31/url-shortener/internal/repository/memory"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// serve runs the API over an in-process listener backed by the memory
+// storage and cache. It returns a connection without credentials and the
+// service, to issue keys with.
+func serve(t *testing.T) (*grpc.ClientConn, *service.Service) {
+	t.Helper()
+
+	svc := service.New(memoryrepo.New(), memorycache.New(), nopQueue{}, time.Second)
+	listener := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, NewServer(svc), listener, time.Second)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		require.NoError(t, <-served)
+	})
+	return conn, svc
+}
+
+// client returns a client authenticated with a freshly issued key.
+func client(t *testing.T) shortenerv1.ShortenerServiceClient {
+	t.Helper()
+
+	conn, svc := serve(t)
+	issued, err := svc.IssueApiKey(context.Background(), "test")
+	require.NoError(t, err)
+
+	return shortenerv1.NewShortenerServiceClient(&authConn{ClientConn: conn, key: issued.Key})
+}
+
+// authConn adds the key to every call, as grpc.WithPerRPCCredentials would
+// on a dialled connection.
+type authConn struct {
+	*grpc.ClientConn
+	key string
+}
+
+func (c *authConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	opts = append(opts, grpc.PerRPCCredentials(shortenerv1.ApiKey(c.key)))
+	return c.ClientConn.Invoke(ctx, method, args, reply, opts...)
+}
+
+func errorInfo(t *testing.T, err error) (codes.Code, string) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok, "not a status error: %v", err)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			assert.Equal(t, errorDomain, info.Domain)
+			return st.Code(), info.Reason
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", err)
+	return st.Code(), ""
+}
+
+func TestAuth(t *testing.T) {
+	conn, svc := serve(t)
+	unauthenticated := shortenerv1.NewShortenerServiceClient(conn)
+	ctx := context.Background()
+
+	revoked, err := svc.IssueApiKey(ctx, "revoked")
+	require.NoError(t, err)
+	_, err = svc.RevokeApiKey(ctx, revoked.Id)
+	require.NoError(t, err)
+
+	for name, key := range map[string]string{
+		"Missing": "",
+		"Unknown": "us_" + strings.Repeat("0", 40),
+		"Revoked": revoked.Key,
+	} {
+		t.Run(name, func(t *testing.T) {
+			callCtx := ctx
+			if key != "" {
+				callCtx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+key)
+			}
+			_, err := unauthenticated.GetLink(callCtx, &shortenerv1.GetLinkRequest{ShortUrl: "abc123"})
+			code, reason := errorInfo(t, err)
+			assert.Equal(t, codes.Unauthenticated, code)
+			assert.Equal(t, "unauthenticated", reason)
+		})
+	}
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.Status)
+}
+
+func TestLinkLifecycle(t *testing.T) {
+	client := client(t)
+	ctx := context.Background()
+
+	var header metadata.MD
+	created, err := client.CreateLink(ctx, &shortenerv1.CreateLinkRequest{
+		Url:         "example.com/landing",
+		QueryPolicy: model.QueryPolicyKeep,
+		Folder:      "marketing",
+		Tags:        []string{"promo"},
+		ExpiresAt:   timestamppb.New(time.Now().Add(time.Hour)),
+		Rules: []*shortenerv1.TargetingRule{
+			{Country: "de", Destination: "https://example.de"},
+		},
+	}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/landing", created.Url)
+	assert.NotEmpty(t, created.ShortUrl)
+	assert.Len(t, header.Get("x-request-id"), 1)
+
+	resolved, err := client.ResolveLink(ctx, &shortenerv1.ResolveLinkRequest{
+		ShortUrl: created.ShortUrl,
+		Country:  "DE",
+		Source:   model.SourceQr,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.de", resolved.Url)
+
+	resolved, err = client.ResolveLink(ctx, &shortenerv1.ResolveLinkRequest{
+		ShortUrl: created.ShortUrl,
+		Referrer: "https://news.example.org/post",
+		Query:    "utm_source=mail",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/landing?utm_source=mail", resolved.Url)
+
+	link, err := client.GetLink(ctx, &shortenerv1.GetLinkRequest{ShortUrl: created.ShortUrl})
+	require.NoError(t, err)
+	assert.Equal(t, created.ShortUrl, link.ShortUrl)
+	assert.Equal(t, "marketing", link.Folder)
+	assert.Equal(t, []string{"promo"}, link.Tags)
+	assert.Equal(t, model.LinkStatusActive, link.Status)
+	assert.EqualValues(t, 2, link.Clicks)
+	assert.NotNil(t, link.ExpiresAt)
+	assert.NotNil(t, link.LastClick)
+
+	stats, err := client.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{ShortUrl: created.ShortUrl})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, stats.Clicks)
+	assert.EqualValues(t, 1, stats.QrScans)
+	require.NotEmpty(t, stats.Referrers)
+}
+
+func TestGetLinkStats_NoClicks(t *testing.T) {
+	client := client(t)
+	ctx := context.Background()
+
+	created, err := client.CreateLink(ctx, &shortenerv1.CreateLinkRequest{Url: "https://example.com"})
+	require.NoError(t, err)
+
+	stats, err := client.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{ShortUrl: created.ShortUrl})
+	require.NoError(t, err)
+	assert.Equal(t, created.ShortUrl, stats.ShortUrl)
+	assert.Zero(t, stats.Clicks)
+}
+
+func TestBatchCreateLinks(t *testing.T) {
+	client := client(t)
+	ctx := context.Background()
+
+	resp, err := client.BatchCreateLinks(ctx, &shortenerv1.BatchCreateLinksRequest{
+		Links: []*shortenerv1.CreateLinkRequest{
+			{Url: "https://example.com/a"},
+			{Url: "https://example.com/b", QueryPolicy: "merge"},
+			{Url: "https://example.com/c"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, "https://example.com/a", resp.Results[0].GetLink().GetUrl())
+	assert.Equal(t, "invalid_query_policy", resp.Results[1].GetError().GetCode())
+	assert.Nil(t, resp.Results[1].GetLink())
+	assert.Equal(t, "https://example.com/c", resp.Results[2].GetLink().GetUrl())
+
+	_, err = client.BatchCreateLinks(ctx, &shortenerv1.BatchCreateLinksRequest{
+		Links: make([]*shortenerv1.CreateLinkRequest, maxBatchSize+1),
+	})
+	code, reason := errorInfo(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	assert.Equal(t, "invalid_body", reason)
+}
+
+func TestErrors(t *testing.T) {
+	client := client(t)
+	ctx := context.Background()
+
+	_, err := client.GetLink(ctx, &shortenerv1.GetLinkRequest{ShortUrl: "missing"})
+	code, reason := errorInfo(t, err)
+	assert.Equal(t, codes.NotFound, code)
+	assert.Equal(t, "link_not_found", reason)
+
+	_, err = client.ResolveLink(ctx, &shortenerv1.ResolveLinkRequest{ShortUrl: "missing", Source: "email"})
+	code, reason = errorInfo(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	assert.Equal(t, "invalid_body", reason)
+
+	_, err = client.CreateLink(ctx, &shortenerv1.CreateLinkRequest{
+		Url:       "https://example.com",
+		ExpiresAt: timestamppb.New(time.Now().Add(-time.Hour)),
+	})
+	code, reason = errorInfo(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	assert.Equal(t, "invalid_expiration", reason)
+}
+
+func TestStatusFor(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err     error
+		code    codes.Code
+		reason  string
+		message string
+	}{
+		"Timeout":     {context.DeadlineExceeded, codes.DeadlineExceeded, "timeout", "request timed out"},
+		"Cancelled":   {context.Canceled, codes.Canceled, "request_cancelled", "request cancelled"},
+		"Expired":     {service.ErrLinkUnavailable, codes.FailedPrecondition, "link_unavailable", "link is disabled or expired"},
+		"Conflict":    {service.ErrUrlExists, codes.AlreadyExists, "url_exists", "url is already shortened"},
+		"Unavailable": {service.ErrCacheUnavailable, codes.Unavailable, "cache_unavailable", "cache is unavailable"},
+		"Internal":    {assert.AnError, codes.Internal, "internal", "internal server error"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code, reason, message := statusFor(tc.err)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.reason, reason)
+			assert.Equal(t, tc.message, message)
+		})
+	}
+}
+
+type nopQueue struct{}
+
+func (nopQueue) Enqueue(model.Url) {}
+
+func TestCreateLink_IdempotencyKey(t *testing.T) {
+	c := client(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), idempotencyKeyKey, "key-1")
+
+	var header metadata.MD
+	created, err := c.CreateLink(ctx, &shortenerv1.CreateLinkRequest{Url: "https://example.com"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Empty(t, header.Get(idempotentReplayedKey))
+
+	replayed, err := c.CreateLink(ctx, &shortenerv1.CreateLinkRequest{Url: "https://example.com"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, created.GetShortUrl(), replayed.GetShortUrl())
+	assert.Equal(t, []string{"true"}, header.Get(idempotentReplayedKey))
+
+	_, err = c.CreateLink(ctx, &shortenerv1.CreateLinkRequest{Url: "https://example.org"})
+	code, reason := errorInfo(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	assert.Equal(t, "idempotency_key_reused", reason)
+}
+
+func TestCreateLink_IdempotencyKeyPerApiKey(t *testing.T) {
+	conn, svc := serve(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), idempotencyKeyKey, "key-1")
+
+	for _, url := range []string{"https://example.com", "https://example.org"} {
+		issued, err := svc.IssueApiKey(context.Background(), url)
+		require.NoError(t, err)
+		c := shortenerv1.NewShortenerServiceClient(&authConn{ClientConn: conn, key: issued.Key})
+
+		var header metadata.MD
+		created, err := c.CreateLink(ctx, &shortenerv1.CreateLinkRequest{Url: url}, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Equal(t, url, created.GetUrl())
+		assert.Empty(t, header.Get(idempotentReplayedKey))
+	}
+}
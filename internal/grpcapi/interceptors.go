@@ -0,0 +1,134 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDKey is the metadata key of the request id, the gRPC counterpart
+// of the X-Request-ID header.
+const requestIDKey = "x-request-id"
+
+var healthPrefix = "/" + healthpb.Health_ServiceDesc.ServiceName + "/"
+
+func unaryAuth(svc Service) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, svc, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(svc Service) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, err := authenticate(stream.Context(), svc, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// authenticate checks the key in the "authorization: Bearer" metadata of
+// every call except health checks and returns ctx carrying the key.
+func authenticate(ctx context.Context, svc Service, method string) (context.Context, error) {
+	if strings.HasPrefix(method, healthPrefix) {
+		return ctx, nil
+	}
+
+	var key string
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		key, _ = strings.CutPrefix(values[0], "Bearer ")
+	}
+	if key == "" {
+		return nil, statusError(service.ErrUnauthenticated)
+	}
+
+	apiKey, err := svc.AuthenticateApiKey(ctx, strings.TrimSpace(key))
+	if err != nil {
+		logging.FromContext(ctx).Warn().Err(err).Msg("could not authenticate api key")
+		return nil, statusError(err)
+	}
+
+	logging.FromContext(ctx).Debug().Int("api_key_id", apiKey.Id).Msg("authenticated api key")
+	return service.WithApiKey(ctx, apiKey), nil
+}
+
+func unaryLogging(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx = withRequestID(ctx)
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func streamLogging(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := withRequestID(stream.Context())
+	err := handler(srv, &loggedStream{ServerStream: stream, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+type loggedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *loggedStream) Context() context.Context {
+	return s.ctx
+}
+
+// withRequestID takes the request id from the x-request-id metadata or
+// generates one and echoes it in the response header.
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if values := metadata.ValueFromIncomingContext(ctx, requestIDKey); len(values) > 0 {
+		id = values[0]
+	}
+	ctx, id = logging.NewContext(ctx, id)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	return ctx
+}
+
+// logCall writes one access log line per call, leveled like the HTTP
+// access log: info for success, warn for client errors and error for
+// server errors. Successful health checks are logged at debug.
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	logger := logging.FromContext(ctx)
+
+	var event *zerolog.Event
+	switch code {
+	case codes.OK:
+		if strings.HasPrefix(method, healthPrefix) {
+			event = logger.Debug()
+		} else {
+			event = logger.Info()
+		}
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable, codes.DeadlineExceeded, codes.Unimplemented:
+		event = logger.Error()
+	default:
+		event = logger.Warn()
+	}
+
+	event = event.
+		Str("method", method).
+		Str("code", code.String()).
+		Float64("latency_ms", float64(time.Since(start).Microseconds())/1000)
+	if err != nil {
+		event = event.Str("errors", err.Error())
+	}
+	event.Msg("rpc")
+}
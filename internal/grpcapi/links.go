@@ -0,0 +1,245 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	shortenerv1 "github.com/Komilov31/url-shortener/api/shortener/v1"
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Metadata keys of idempotent CreateLink calls.
+const (
+	idempotencyKeyKey     = "idempotency-key"
+	idempotentReplayedKey = "idempotent-replayed"
+)
+
+// maxBatchSize bounds BatchCreateLinks, a batch is created one link after
+// another within a single call.
+const maxBatchSize = 1000
+
+// CreateLink is made safe to retry with idempotency-key metadata, like the
+// Idempotency-Key header of the HTTP API. Keys are scoped to the API key of
+// the caller.
+func (s *Server) CreateLink(ctx context.Context, req *shortenerv1.CreateLinkRequest) (*shortenerv1.CreateLinkResponse, error) {
+	var urlInfo *model.Url
+	var err error
+	if values := metadata.ValueFromIncomingContext(ctx, idempotencyKeyKey); len(values) > 0 && values[0] != "" {
+		var replayed bool
+		urlInfo, replayed, err = s.service.CreateShortUrlIdempotent(ctx, service.ApiKeyFromContext(ctx), values[0], urlFromProto(req))
+		if replayed {
+			grpc.SetHeader(ctx, metadata.Pairs(idempotentReplayedKey, "true"))
+		}
+	} else {
+		urlInfo, err = s.service.CreateShortUrl(ctx, urlFromProto(req))
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error().Err(err).Msg("could not create short_url")
+		return nil, statusError(err)
+	}
+
+	return &shortenerv1.CreateLinkResponse{ShortUrl: urlInfo.ShortUrl, Url: urlInfo.Url}, nil
+}
+
+func (s *Server) BatchCreateLinks(ctx context.Context, req *shortenerv1.BatchCreateLinksRequest) (*shortenerv1.BatchCreateLinksResponse, error) {
+	if len(req.GetLinks()) > maxBatchSize {
+		err := fmt.Errorf("%w: a batch holds at most %d links, got %d", service.ErrInvalidBody, maxBatchSize, len(req.GetLinks()))
+		return nil, statusError(err)
+	}
+
+	results := make([]*shortenerv1.BatchCreateLinkResult, 0, len(req.GetLinks()))
+	for i, link := range req.GetLinks() {
+		urlInfo, err := s.service.CreateShortUrl(ctx, urlFromProto(link))
+
+		var domainErr *service.Error
+		switch {
+		case err == nil:
+			results = append(results, &shortenerv1.BatchCreateLinkResult{
+				Result: &shortenerv1.BatchCreateLinkResult_Link{
+					Link: &shortenerv1.CreateLinkResponse{ShortUrl: urlInfo.ShortUrl, Url: urlInfo.Url},
+				},
+			})
+		case errors.As(err, &domainErr) && domainErr.Kind != service.KindUnavailable:
+			results = append(results, &shortenerv1.BatchCreateLinkResult{
+				Result: &shortenerv1.BatchCreateLinkResult_Error{
+					Error: &shortenerv1.Error{Code: domainErr.Code, Message: err.Error()},
+				},
+			})
+		default:
+			logging.FromContext(ctx).Error().Err(err).Int("created", i).Msg("could not create batch of short urls")
+			return nil, statusError(err)
+		}
+	}
+
+	return &shortenerv1.BatchCreateLinksResponse{Results: results}, nil
+}
+
+func (s *Server) GetLink(ctx context.Context, req *shortenerv1.GetLinkRequest) (*shortenerv1.Link, error) {
+	link, err := s.service.GetLink(ctx, req.GetShortUrl())
+	if err != nil {
+		logging.FromContext(ctx).Error().Err(err).Msg("could not get link")
+		return nil, statusError(err)
+	}
+
+	return linkToProto(link), nil
+}
+
+func (s *Server) ResolveLink(ctx context.Context, req *shortenerv1.ResolveLinkRequest) (*shortenerv1.ResolveLinkResponse, error) {
+	redirectInfo, err := redirectInfoFromProto(req)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	urlInfo, err := s.service.GetUrlByShort(ctx, req.GetShortUrl(), redirectInfo)
+	if err != nil {
+		logging.FromContext(ctx).Error().Err(err).Msg("could not get short url")
+		return nil, statusError(err)
+	}
+
+	return &shortenerv1.ResolveLinkResponse{
+		Url:            urlInfo.Url,
+		Variant:        urlInfo.Variant,
+		StickyVariants: urlInfo.StickyVariants,
+	}, nil
+}
+
+func (s *Server) GetLinkStats(ctx context.Context, req *shortenerv1.GetLinkStatsRequest) (*shortenerv1.LinkStats, error) {
+	// GetAnalytics knows nothing about links without clicks, GetLink
+	// tells them apart from missing ones.
+	link, err := s.service.GetLink(ctx, req.GetShortUrl())
+	if err != nil {
+		logging.FromContext(ctx).Error().Err(err).Msg("could not get link")
+		return nil, statusError(err)
+	}
+
+	analytics, err := s.service.GetAnalytics(ctx, link.ShortUrl)
+	if err != nil {
+		logging.FromContext(ctx).Error().Err(err).Msg("could not get analytics")
+		return nil, statusError(err)
+	}
+
+	stats := &shortenerv1.LinkStats{ShortUrl: link.ShortUrl}
+	for _, a := range analytics {
+		stats.Clicks += int64(a.RedirectCount)
+		stats.QrScans += int64(a.QrScans)
+		for _, v := range a.Variants {
+			stats.Variants = append(stats.Variants, &shortenerv1.VariantCount{Variant: v.Variant, Clicks: int64(v.RedirectCount)})
+		}
+		for _, r := range a.Referrers {
+			stats.Referrers = append(stats.Referrers, &shortenerv1.ReferrerCount{Referrer: r.Referrer, Clicks: int64(r.RedirectCount)})
+		}
+	}
+	return stats, nil
+}
+
+func urlFromProto(req *shortenerv1.CreateLinkRequest) model.Url {
+	url := model.Url{
+		Url:            req.GetUrl(),
+		FallbackUrl:    req.GetFallbackUrl(),
+		StickyVariants: req.GetStickyVariants(),
+		QueryPolicy:    req.GetQueryPolicy(),
+		Folder:         req.GetFolder(),
+		Tags:           req.GetTags(),
+		Owner:          req.GetOwner(),
+		ExpiresAt:      timeFromProto(req.GetExpiresAt()),
+	}
+	for i, rule := range req.GetRules() {
+		url.Rules = append(url.Rules, model.TargetingRule{
+			Position:    i,
+			Os:          rule.GetOs(),
+			Device:      rule.GetDevice(),
+			Browser:     rule.GetBrowser(),
+			Language:    rule.GetLanguage(),
+			Country:     rule.GetCountry(),
+			Destination: rule.GetDestination(),
+		})
+	}
+	for _, variant := range req.GetVariants() {
+		url.Variants = append(url.Variants, model.Variant{
+			Name:   variant.GetName(),
+			Url:    variant.GetUrl(),
+			Weight: int(variant.GetWeight()),
+		})
+	}
+	if utm := req.GetUtm(); utm != nil {
+		url.Utm = model.Utm{
+			Source:   utm.GetSource(),
+			Medium:   utm.GetMedium(),
+			Campaign: utm.GetCampaign(),
+			Term:     utm.GetTerm(),
+			Content:  utm.GetContent(),
+		}
+	}
+	return url
+}
+
+func redirectInfoFromProto(req *shortenerv1.ResolveLinkRequest) (model.RedirectInfo, error) {
+	redirectInfo := model.RedirectInfo{
+		ShortUrl:  req.GetShortUrl(),
+		UserAgent: req.GetUserAgent(),
+		ClientIp:  req.GetClientIp(),
+		Referrer:  req.GetReferrer(),
+		Languages: req.GetLanguages(),
+		Country:   strings.ToUpper(strings.TrimSpace(req.GetCountry())),
+		Variant:   req.GetVariant(),
+		Source:    model.SourceDirect,
+	}
+
+	switch req.GetSource() {
+	case "", model.SourceDirect:
+	case model.SourceQr:
+		redirectInfo.Source = model.SourceQr
+	default:
+		return redirectInfo, fmt.Errorf("%w: unknown source %q, expected %s or %s", service.ErrInvalidBody, req.GetSource(), model.SourceDirect, model.SourceQr)
+	}
+
+	if req.GetQuery() != "" {
+		query, err := url.ParseQuery(req.GetQuery())
+		if err != nil {
+			return redirectInfo, fmt.Errorf("%w: invalid query: %w", service.ErrInvalidBody, err)
+		}
+		redirectInfo.Query = query
+	}
+	return redirectInfo, nil
+}
+
+func linkToProto(link *dto.LinkDTO) *shortenerv1.Link {
+	return &shortenerv1.Link{
+		ShortUrl:  link.ShortUrl,
+		Url:       link.Url,
+		Title:     link.Title,
+		Folder:    link.Folder,
+		Tags:      link.Tags,
+		Owner:     link.Owner,
+		Status:    link.Status,
+		CreatedAt: timestamppb.New(link.CreatedAt),
+		ExpiresAt: timeToProto(link.ExpiresAt),
+		Clicks:    int64(link.Clicks),
+		LastClick: timeToProto(link.LastClick),
+	}
+}
+
+func timeFromProto(t *timestamppb.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	value := t.AsTime()
+	return &value
+}
+
+func timeToProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -3,10 +3,9 @@ package handler
 import (
 	"net/http"
 
-	_ "github.com/Komilov31/url-shortener/internal/dto"
-
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
 	"github.com/wb-go/wbf/ginext"
-	"github.com/wb-go/wbf/zlog"
 )
 
 // AggregateByUserAgent godoc
@@ -14,20 +13,22 @@ import (
 // @Description Returns aggregated analytics data grouped by user agent
 // @Tags Analytics
 // @Produce json
+// @Param tag query string false "Only links with this tag"
+// @Param folder query string false "Only links in this folder"
 // @Success 200 {array} dto.UserAgentDTO
-// @Failure 500 {object} ginext.H "Internal server error"
-// @Router /analytics/user_agent [get]
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/analytics/user_agent [get]
 func (h *Handler) AggregateByUserAgent(c *ginext.Context) {
-	analytics, err := h.service.AggregateByUserAgent()
+	analytics, err := h.service.AggregateByUserAgent(c.Request.Context(), linkFilter(c))
 	if err != nil {
-		zlog.Logger.Error().Msg("could not get aggregated data by user agent from db: " + err.Error())
-		c.JSON(http.StatusInternalServerError, ginext.H{
-			"error": "could not get aggregated data by user agent from db: " + err.Error(),
-		})
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get aggregated data by user agent from db")
+		writeError(c, err)
 		return
 	}
 
-	zlog.Logger.Info().Msg("succesfully handled GET request for getting aggreagated by user_agent data")
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting aggreagated by user_agent data")
 	c.JSON(http.StatusOK, analytics)
 }
 
@@ -36,20 +37,22 @@ func (h *Handler) AggregateByUserAgent(c *ginext.Context) {
 // @Description Returns aggregated analytics data grouped by date
 // @Tags Analytics
 // @Produce json
+// @Param tag query string false "Only links with this tag"
+// @Param folder query string false "Only links in this folder"
 // @Success 200 {array} dto.DateDTO
-// @Failure 500 {object} ginext.H "Internal server error"
-// @Router /analytics/date [get]
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/analytics/date [get]
 func (h *Handler) AggregateByDate(c *ginext.Context) {
-	analytics, err := h.service.AggregateByDate()
+	analytics, err := h.service.AggregateByDate(c.Request.Context(), linkFilter(c))
 	if err != nil {
-		zlog.Logger.Error().Msg("could not get aggregated data by date from db: " + err.Error())
-		c.JSON(http.StatusInternalServerError, ginext.H{
-			"error": "could not get aggregated data by date from db: " + err.Error(),
-		})
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get aggregated data by date from db")
+		writeError(c, err)
 		return
 	}
 
-	zlog.Logger.Info().Msg("succesfully handled GET request for getting aggreagated by date data")
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting aggreagated by date data")
 	c.JSON(http.StatusOK, analytics)
 }
 
@@ -58,19 +61,102 @@ func (h *Handler) AggregateByDate(c *ginext.Context) {
 // @Description Returns aggregated analytics data grouped by month
 // @Tags Analytics
 // @Produce json
+// @Param tag query string false "Only links with this tag"
+// @Param folder query string false "Only links in this folder"
 // @Success 200 {array} dto.MonthDTO
-// @Failure 500 {object} ginext.H "Internal server error"
-// @Router /analytics/month [get]
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/analytics/month [get]
 func (h *Handler) AggregateByMonth(c *ginext.Context) {
-	analytics, err := h.service.AggregateByMonth()
+	analytics, err := h.service.AggregateByMonth(c.Request.Context(), linkFilter(c))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get aggregated data by month from db")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting aggreagated by month data")
+	c.JSON(http.StatusOK, analytics)
+}
+
+// AggregateByReferrer godoc
+// @Summary Get aggregated analytics by referrer
+// @Description Returns click counts per referring domain for every short URL. Known social networks and apps are reported by name, clicks without referrer are counted as direct
+// @Tags Analytics
+// @Produce json
+// @Param tag query string false "Only links with this tag"
+// @Param folder query string false "Only links in this folder"
+// @Success 200 {array} dto.ReferrerDTO
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/analytics/referrer [get]
+func (h *Handler) AggregateByReferrer(c *ginext.Context) {
+	analytics, err := h.service.AggregateByReferrer(c.Request.Context(), linkFilter(c))
 	if err != nil {
-		zlog.Logger.Error().Msg("could not get aggregated data by month from db: " + err.Error())
-		c.JSON(http.StatusInternalServerError, ginext.H{
-			"error": "could not get aggregated data by month from db: " + err.Error(),
-		})
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get aggregated data by referrer from db")
+		writeError(c, err)
 		return
 	}
 
-	zlog.Logger.Info().Msg("succesfully handled GET request for getting aggreagated by month data")
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting aggreagated by referrer data")
 	c.JSON(http.StatusOK, analytics)
 }
+
+// AggregateByCampaign godoc
+// @Summary Get aggregated analytics by utm campaign
+// @Description Returns clicks and unique visitors grouped by period, utm_source, utm_medium and utm_campaign
+// @Tags Analytics
+// @Produce json
+// @Param period query string false "Aggregation period: day, week or month" default(day)
+// @Param tag query string false "Only links with this tag"
+// @Param folder query string false "Only links in this folder"
+// @Success 200 {array} dto.CampaignDTO
+// @Failure 400 {object} dto.ProblemDTO "Invalid period"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/analytics/campaigns [get]
+func (h *Handler) AggregateByCampaign(c *ginext.Context) {
+	analytics, err := h.service.AggregateByCampaign(c.Request.Context(), c.Query("period"), linkFilter(c))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get aggregated data by campaign from db")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting aggreagated by campaign data")
+	c.JSON(http.StatusOK, analytics)
+}
+
+// AggregateByTag godoc
+// @Summary Get aggregated analytics by tag
+// @Description Returns the number of links, clicks and unique visitors for every tag. A click of a link with several tags is counted for each of them
+// @Tags Analytics
+// @Produce json
+// @Param tag query string false "Only this tag"
+// @Param folder query string false "Only links in this folder"
+// @Success 200 {array} dto.TagDTO
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/analytics/tags [get]
+func (h *Handler) AggregateByTag(c *ginext.Context) {
+	analytics, err := h.service.AggregateByTag(c.Request.Context(), linkFilter(c))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get aggregated data by tag from db")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting aggreagated by tag data")
+	c.JSON(http.StatusOK, analytics)
+}
+
+func linkFilter(c *ginext.Context) dto.LinkFilter {
+	return dto.LinkFilter{
+		Tag:    c.Query("tag"),
+		Folder: c.Query("folder"),
+	}
+}
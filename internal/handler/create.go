@@ -1,42 +1,97 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
+	_ "github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
 	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/service"
 	"github.com/wb-go/wbf/ginext"
-	"github.com/wb-go/wbf/zlog"
 )
 
-// CreateShortUrl godoc
-// @Summary Create a shortened URL
-// @Description Create a shortened URL from the provided original URL
+const (
+	// IdempotencyKeyHeader makes link creation safe to retry, see
+	// service.CreateShortUrlIdempotent.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed for a retry.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// CreateLink godoc
+// @Summary Create a short link
+// @Description Creates a short link for the destination URL. A destination shortened before gets its existing short_url back, unless the request sets link options such as rules, variants, tags or expiration: then it fails with url_exists
 // @Tags URL
 // @Accept json
 // @Produce json
 // @Param url body model.Url true "URL to shorten"
-// @Success 200 {object} model.Url
-// @Failure 400 {object} ginext.H "Invalid request body"
-// @Failure 500 {object} ginext.H "Internal server error"
-// @Router /shorten [post]
+// @Param Idempotency-Key header string false "Makes the request safe to retry: a retry with the same key and body gets the original result back. Requires an API key, keys are scoped to it"
+// @Security ApiKeyAuth
+// @Success 201 {object} model.Url
+// @Header 201 {string} Location "URL of the created link"
+// @Header 201 {string} Idempotent-Replayed "true when the result of an earlier request with the same Idempotency-Key is replayed"
+// @Failure 400 {object} dto.ProblemDTO "Invalid request body, link settings or idempotency key"
+// @Failure 401 {object} dto.ProblemDTO "Unknown or revoked API key, or an Idempotency-Key without an API key"
+// @Failure 409 {object} dto.ProblemDTO "Destination shortened before and the request sets link options or its link is disabled or expired, or a request with the same Idempotency-Key is in progress"
+// @Failure 422 {object} dto.ProblemDTO "Idempotency-Key was used with another body"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled or cache unavailable"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links [post]
+func (h *Handler) CreateLink(c *ginext.Context) {
+	urlInfo, ok := h.createShortUrl(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Location", linkPath(urlInfo.ShortUrl))
+	c.JSON(http.StatusCreated, urlInfo)
+}
+
+// CreateShortUrl is the legacy POST /shorten, unlike CreateLink it answers
+// 200 without Location.
 func (h *Handler) CreateShortUrl(c *ginext.Context) {
-	var url model.Url
-	if err := c.BindJSON(&url); err != nil {
-		zlog.Logger.Error().Msg("could not bind json to object: " + err.Error())
-		c.JSON(http.StatusBadRequest, ginext.H{
-			"error": "invalid request body",
-		})
+	urlInfo, ok := h.createShortUrl(c)
+	if !ok {
 		return
 	}
 
-	urlInfo, err := h.service.CreateShortUrl(url)
+	c.JSON(http.StatusOK, urlInfo)
+}
+
+// createShortUrl creates the link described by the request body, only once
+// for requests with an Idempotency-Key. It reports false when it has already
+// answered with an error.
+func (h *Handler) createShortUrl(c *ginext.Context) (*model.Url, bool) {
+	var url model.Url
+	if err := c.ShouldBindJSON(&url); err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not bind json to object")
+		writeError(c, fmt.Errorf("%w: %w", service.ErrInvalidBody, err))
+		return nil, false
+	}
+
+	var urlInfo *model.Url
+	var replayed bool
+	var err error
+	if key := c.GetHeader(IdempotencyKeyHeader); key != "" {
+		urlInfo, replayed, err = h.service.CreateShortUrlIdempotent(c.Request.Context(), service.ApiKeyFromContext(c.Request.Context()), key, url)
+	} else {
+		urlInfo, err = h.service.CreateShortUrl(c.Request.Context(), url)
+	}
 	if err != nil {
-		zlog.Logger.Error().Msg("could not create short_url: " + err.Error())
-		c.JSON(http.StatusInternalServerError, ginext.H{
-			"error": err.Error(),
-		})
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not create short_url")
+		if errors.Is(err, service.ErrUnauthenticated) {
+			c.Header("WWW-Authenticate", "Bearer")
+		}
+		writeError(c, err)
+		return nil, false
+	}
+	if replayed {
+		c.Header(IdempotentReplayedHeader, "true")
 	}
 
-	zlog.Logger.Info().Msg("successfully handled GET request and created short url for url")
-	c.JSON(http.StatusOK, urlInfo)
+	logging.FromContext(c.Request.Context()).Debug().Msg("successfully handled POST request and created short url for url")
+	return urlInfo, true
 }
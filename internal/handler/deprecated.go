@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wb-go/wbf/ginext"
+)
+
+// legacyDeprecatedAt is when the routes outside of APIPrefix were
+// deprecated in favour of the versioned API.
+var legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Deprecated marks the responses of a legacy route as deprecated (RFC 9745)
+// and links the route of the versioned API replacing it. Path parameters
+// like :short_url in successor are filled in from the request.
+func Deprecated(successor string) ginext.HandlerFunc {
+	return func(c *ginext.Context) {
+		link := successor
+		for _, param := range c.Params {
+			link = strings.ReplaceAll(link, ":"+param.Key, url.PathEscape(param.Value))
+		}
+
+		c.Header("Deprecation", "@"+strconv.FormatInt(legacyDeprecatedAt.Unix(), 10))
+		c.Header("Link", "<"+link+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+func linkPath(short_url string) string {
+	return APIPrefix + "/links/" + url.PathEscape(short_url)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/wb-go/wbf/ginext"
+)
+
+const problemContentType = "application/problem+json"
+
+var kindStatus = map[service.Kind]int{
+	service.KindNotFound:        http.StatusNotFound,
+	service.KindConflict:        http.StatusConflict,
+	service.KindUnprocessable:   http.StatusUnprocessableEntity,
+	service.KindValidation:      http.StatusBadRequest,
+	service.KindUnauthenticated: http.StatusUnauthorized,
+	service.KindExpired:         http.StatusGone,
+	service.KindForbidden:       http.StatusForbidden,
+	service.KindUnavailable:     http.StatusServiceUnavailable,
+}
+
+// writeError answers with the problem details of err. Domain errors keep
+// their message, anything unexpected becomes a 500 whose detail does not
+// reveal internals, those are only logged. It answers 504 when the request
+// ran out of its time budget and 503 when it was cancelled before the work
+// could finish.
+func writeError(c *ginext.Context, err error) {
+	problem := problemFor(err)
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+	problem.Instance = c.Request.URL.Path
+	problem.RequestID = logging.RequestID(c.Request.Context())
+
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+func problemFor(err error) dto.ProblemDTO {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return dto.ProblemDTO{Status: http.StatusGatewayTimeout, Code: "timeout", Detail: "request timed out"}
+	case errors.Is(err, context.Canceled):
+		return dto.ProblemDTO{Status: http.StatusServiceUnavailable, Code: "request_cancelled", Detail: "request cancelled"}
+	}
+
+	var domain *service.Error
+	if !errors.As(err, &domain) {
+		return dto.ProblemDTO{Status: http.StatusInternalServerError, Code: "internal", Detail: "internal server error"}
+	}
+
+	status, ok := kindStatus[domain.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	detail := err.Error()
+	if status >= http.StatusInternalServerError {
+		detail = domain.Message
+	}
+	return dto.ProblemDTO{Status: status, Code: domain.Code, Detail: detail}
+}
@@ -1,14 +1,13 @@
 package handler
 
 import (
-	"errors"
 	"net/http"
+	"net/url"
 
 	_ "github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
 	"github.com/Komilov31/url-shortener/internal/model"
-	"github.com/Komilov31/url-shortener/internal/repository"
 	"github.com/wb-go/wbf/ginext"
-	"github.com/wb-go/wbf/zlog"
 )
 
 // RedirectByShortUrl godoc
@@ -17,28 +16,62 @@ import (
 // @Tags URL
 // @Produce plain
 // @Param short_url path string true "Short URL"
-// @Success 301 "Redirect to original URL"
-// @Failure 400 {object} map[string]string "Invalid short URL or not found"
+// @Success 302 "Redirect to original URL"
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 410 {object} dto.ProblemDTO "Short URL is disabled or expired"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled or cache unavailable"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
 // @Router /s/{short_url} [get]
 func (h *Handler) RedirectByShortUrl(c *ginext.Context) {
 	short_url := c.Param("short_url")
 	var redirectInfo model.RedirectInfo
 	redirectInfo.ShortUrl = short_url
 	redirectInfo.UserAgent = c.Request.UserAgent()
+	redirectInfo.ClientIp = c.ClientIP()
+	redirectInfo.Referrer = c.Request.Referer()
+	redirectInfo.Source = model.SourceDirect
+	redirectInfo.Query = requestQuery(c)
+	if c.Query("source") == model.SourceQr {
+		redirectInfo.Source = model.SourceQr
+	}
+	redirectInfo.Languages = parseAcceptLanguage(c.GetHeader("Accept-Language"))
+	redirectInfo.Country = requestCountry(c)
+	if variant, err := c.Cookie(variantCookieName(short_url)); err == nil {
+		redirectInfo.Variant = variant
+	}
 
-	url, err := h.service.GetUrlByShort(short_url, redirectInfo)
+	url, err := h.service.GetUrlByShort(c.Request.Context(), short_url, redirectInfo)
 	if err != nil {
-		zlog.Logger.Error().Msg("could not get short url: " + err.Error())
-		if errors.Is(err, repository.ErrAliasNotFound) {
-			c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get short url")
+		writeError(c, err)
 		return
 	}
 
-	zlog.Logger.Info().Msg("successfully handled GET request: " + url.Url)
-	c.Redirect(http.StatusMovedPermanently, url.Url)
+	if url.StickyVariants && url.Variant != "" {
+		setVariantCookie(c, short_url, url.Variant)
+	}
+
+	if isPreviewBot(redirectInfo.UserAgent) && h.renderPreview(c, url) {
+		logging.FromContext(c.Request.Context()).Debug().Str("url", url.Url).Msg("successfully handled GET request with preview")
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Str("url", url.Url).Msg("successfully handled GET request")
+	c.Redirect(http.StatusFound, url.Url)
+}
+
+// requestQuery returns the query of the short link request without the
+// source marker added to QR codes, or nil when nothing is left.
+func requestQuery(c *ginext.Context) url.Values {
+	query := c.Request.URL.Query()
+	if query.Get("source") == model.SourceQr {
+		query.Del("source")
+	}
+	if len(query) == 0 {
+		return nil
+	}
+	return query
 }
 
 // GetAnalytics godoc
@@ -48,17 +81,19 @@ func (h *Handler) RedirectByShortUrl(c *ginext.Context) {
 // @Produce json
 // @Param short_url path string true "Short URL"
 // @Success 200 {object} dto.RedirectInfo
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /analytics/{short_url} [get]
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/analytics [get]
 func (h *Handler) GetAnalytics(c *ginext.Context) {
 	short_url := c.Param("short_url")
-	analytics, err := h.service.GetAnalytics(short_url)
+	analytics, err := h.service.GetAnalytics(c.Request.Context(), short_url)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
-	zlog.Logger.Info().Msg("succesfully handled GET request for geting analytics data")
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for geting analytics data")
 	c.JSON(http.StatusOK, analytics)
 }
 
@@ -1,21 +1,51 @@
 package handler
 
 import (
+	"context"
+	"net/url"
+
 	"github.com/Komilov31/url-shortener/internal/dto"
 	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/qr"
 )
 
+// APIPrefix is where the versioned API is served, the routes outside of it
+// are deprecated.
+const APIPrefix = "/api/v1"
+
 type ShortnerServcie interface {
-	GetAnalytics(string) ([]dto.RedirectInfo, error)
-	GetUrlByShort(string, model.RedirectInfo) (*model.Url, error)
-	CreateShortUrl(model.Url) (*model.Url, error)
-	AggregateByUserAgent() ([]dto.UserAgentDTO, error)
-	AggregateByDate() ([]dto.DateDTO, error)
-	AggregateByMonth() ([]dto.MonthDTO, error)
+	GetAnalytics(context.Context, string) ([]dto.RedirectInfo, error)
+	GetUrlByShort(context.Context, string, model.RedirectInfo) (*model.Url, error)
+	CreateShortUrl(context.Context, model.Url) (*model.Url, error)
+	CreateShortUrlIdempotent(context.Context, *model.ApiKey, string, model.Url) (*model.Url, bool, error)
+	AggregateByUserAgent(context.Context, dto.LinkFilter) ([]dto.UserAgentDTO, error)
+	AggregateByDate(context.Context, dto.LinkFilter) ([]dto.DateDTO, error)
+	AggregateByMonth(context.Context, dto.LinkFilter) ([]dto.MonthDTO, error)
+	AggregateByCampaign(context.Context, string, dto.LinkFilter) ([]dto.CampaignDTO, error)
+	AggregateByReferrer(context.Context, dto.LinkFilter) ([]dto.ReferrerDTO, error)
+	AggregateByTag(context.Context, dto.LinkFilter) ([]dto.TagDTO, error)
+	GetUrlMetadata(context.Context, string) (*model.UrlMetadata, error)
+	GetUnhealthyUrls(context.Context) ([]model.UrlHealth, error)
+	GetQrCode(context.Context, string, string, qr.Options) ([]byte, error)
+	SetTargetingRules(context.Context, string, []model.TargetingRule) ([]model.TargetingRule, error)
+	GetTargetingRules(context.Context, string) ([]model.TargetingRule, error)
+	SetVariants(context.Context, dto.VariantsDTO) (*dto.VariantsDTO, error)
+	GetVariants(context.Context, string) (*dto.VariantsDTO, error)
+	SearchLinks(context.Context, dto.LinkQuery) ([]dto.LinkDTO, error)
+	SetDisabled(context.Context, string, bool) (*dto.LinkDTO, error)
+	SetTags(context.Context, string, []string) (*dto.LinkDTO, error)
+	SetFolder(context.Context, string, string) (*dto.LinkDTO, error)
+	GetLink(context.Context, string) (*dto.LinkDTO, error)
+	DeleteLink(context.Context, string) (*model.Url, error)
+	IssueApiKey(context.Context, string) (*dto.IssuedApiKeyDTO, error)
+	ListApiKeys(context.Context) ([]model.ApiKey, error)
+	RevokeApiKey(context.Context, int) (*model.ApiKey, error)
+	AuthenticateApiKey(context.Context, string) (*model.ApiKey, error)
 }
 
 type Handler struct {
-	service ShortnerServcie
+	service   ShortnerServcie
+	publicUrl *url.URL
 }
 
 func New(service ShortnerServcie) *Handler {
@@ -23,3 +53,9 @@ func New(service ShortnerServcie) *Handler {
 		service: service,
 	}
 }
+
+// SetPublicUrl makes QR codes point at publicUrl, e.g. https://sho.rt,
+// instead of the host the request was sent to.
+func (h *Handler) SetPublicUrl(publicUrl *url.URL) {
+	h.publicUrl = publicUrl
+}
@@ -1,14 +1,21 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Komilov31/url-shortener/internal/dto"
 	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/qr"
+	"github.com/Komilov31/url-shortener/internal/repository"
+	"github.com/Komilov31/url-shortener/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,36 +27,141 @@ type MockShortnerService struct {
 	mock.Mock
 }
 
-func (m *MockShortnerService) CreateShortUrl(url model.Url) (*model.Url, error) {
-	args := m.Called(url)
+func (m *MockShortnerService) CreateShortUrl(ctx context.Context, url model.Url) (*model.Url, error) {
+	args := m.Called(ctx, url)
 	return args.Get(0).(*model.Url), args.Error(1)
 }
 
-func (m *MockShortnerService) GetUrlByShort(short_url string, redirectInfo model.RedirectInfo) (*model.Url, error) {
-	args := m.Called(short_url, redirectInfo)
+func (m *MockShortnerService) CreateShortUrlIdempotent(ctx context.Context, client *model.ApiKey, key string, url model.Url) (*model.Url, bool, error) {
+	args := m.Called(ctx, client, key, url)
+	return args.Get(0).(*model.Url), args.Bool(1), args.Error(2)
+}
+
+func (m *MockShortnerService) GetUrlByShort(ctx context.Context, short_url string, redirectInfo model.RedirectInfo) (*model.Url, error) {
+	args := m.Called(ctx, short_url, redirectInfo)
 	return args.Get(0).(*model.Url), args.Error(1)
 }
 
-func (m *MockShortnerService) GetAnalytics(short_url string) ([]dto.RedirectInfo, error) {
-	args := m.Called(short_url)
+func (m *MockShortnerService) GetAnalytics(ctx context.Context, short_url string) ([]dto.RedirectInfo, error) {
+	args := m.Called(ctx, short_url)
 	return args.Get(0).([]dto.RedirectInfo), args.Error(1)
 }
 
-func (m *MockShortnerService) AggregateByUserAgent() ([]dto.UserAgentDTO, error) {
-	args := m.Called()
+func (m *MockShortnerService) AggregateByUserAgent(ctx context.Context, filter dto.LinkFilter) ([]dto.UserAgentDTO, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]dto.UserAgentDTO), args.Error(1)
 }
 
-func (m *MockShortnerService) AggregateByDate() ([]dto.DateDTO, error) {
-	args := m.Called()
+func (m *MockShortnerService) AggregateByDate(ctx context.Context, filter dto.LinkFilter) ([]dto.DateDTO, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]dto.DateDTO), args.Error(1)
 }
 
-func (m *MockShortnerService) AggregateByMonth() ([]dto.MonthDTO, error) {
-	args := m.Called()
+func (m *MockShortnerService) AggregateByMonth(ctx context.Context, filter dto.LinkFilter) ([]dto.MonthDTO, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]dto.MonthDTO), args.Error(1)
 }
 
+func (m *MockShortnerService) AggregateByCampaign(ctx context.Context, period string, filter dto.LinkFilter) ([]dto.CampaignDTO, error) {
+	args := m.Called(ctx, period, filter)
+	return args.Get(0).([]dto.CampaignDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) AggregateByReferrer(ctx context.Context, filter dto.LinkFilter) ([]dto.ReferrerDTO, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]dto.ReferrerDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) AggregateByTag(ctx context.Context, filter dto.LinkFilter) ([]dto.TagDTO, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]dto.TagDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) SearchLinks(ctx context.Context, search dto.LinkQuery) ([]dto.LinkDTO, error) {
+	args := m.Called(ctx, search)
+	return args.Get(0).([]dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) SetDisabled(ctx context.Context, short_url string, disabled bool) (*dto.LinkDTO, error) {
+	args := m.Called(ctx, short_url, disabled)
+	return args.Get(0).(*dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) SetTags(ctx context.Context, short_url string, tags []string) (*dto.LinkDTO, error) {
+	args := m.Called(ctx, short_url, tags)
+	return args.Get(0).(*dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) SetFolder(ctx context.Context, short_url string, folder string) (*dto.LinkDTO, error) {
+	args := m.Called(ctx, short_url, folder)
+	return args.Get(0).(*dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) GetLink(ctx context.Context, short_url string) (*dto.LinkDTO, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).(*dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) DeleteLink(ctx context.Context, short_url string) (*model.Url, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).(*model.Url), args.Error(1)
+}
+
+func (m *MockShortnerService) IssueApiKey(ctx context.Context, name string) (*dto.IssuedApiKeyDTO, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*dto.IssuedApiKeyDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) ListApiKeys(ctx context.Context) ([]model.ApiKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.ApiKey), args.Error(1)
+}
+
+func (m *MockShortnerService) RevokeApiKey(ctx context.Context, id int) (*model.ApiKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.ApiKey), args.Error(1)
+}
+
+func (m *MockShortnerService) AuthenticateApiKey(ctx context.Context, key string) (*model.ApiKey, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(*model.ApiKey), args.Error(1)
+}
+
+func (m *MockShortnerService) GetUrlMetadata(ctx context.Context, short_url string) (*model.UrlMetadata, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).(*model.UrlMetadata), args.Error(1)
+}
+
+func (m *MockShortnerService) GetUnhealthyUrls(ctx context.Context) ([]model.UrlHealth, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.UrlHealth), args.Error(1)
+}
+
+func (m *MockShortnerService) GetQrCode(ctx context.Context, short_url, content string, opts qr.Options) ([]byte, error) {
+	args := m.Called(ctx, short_url, content, opts)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockShortnerService) SetTargetingRules(ctx context.Context, short_url string, rules []model.TargetingRule) ([]model.TargetingRule, error) {
+	args := m.Called(ctx, short_url, rules)
+	return args.Get(0).([]model.TargetingRule), args.Error(1)
+}
+
+func (m *MockShortnerService) GetTargetingRules(ctx context.Context, short_url string) ([]model.TargetingRule, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).([]model.TargetingRule), args.Error(1)
+}
+
+func (m *MockShortnerService) SetVariants(ctx context.Context, variants dto.VariantsDTO) (*dto.VariantsDTO, error) {
+	args := m.Called(ctx, variants)
+	return args.Get(0).(*dto.VariantsDTO), args.Error(1)
+}
+
+func (m *MockShortnerService) GetVariants(ctx context.Context, short_url string) (*dto.VariantsDTO, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).(*dto.VariantsDTO), args.Error(1)
+}
+
 func TestHandler_CreateShortUrl_Success(t *testing.T) {
 	mockService := new(MockShortnerService)
 	handler := New(mockService)
@@ -57,7 +169,7 @@ func TestHandler_CreateShortUrl_Success(t *testing.T) {
 	url := model.Url{Url: "https://example.com"}
 	shortUrl := &model.Url{Url: url.Url, ShortUrl: "abc123"}
 
-	mockService.On("CreateShortUrl", url).Return(shortUrl, nil)
+	mockService.On("CreateShortUrl", mock.Anything, url).Return(shortUrl, nil)
 
 	reqBody := `{"url": "https://example.com"}`
 	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(reqBody))
@@ -89,10 +201,43 @@ func TestHandler_CreateShortUrl_InvalidJSON(t *testing.T) {
 	handler.CreateShortUrl((*ginext.Context)(c))
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	var response map[string]string
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var response dto.ProblemDTO
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "invalid request body", response["error"])
+	assert.Equal(t, "invalid_body", response.Code)
+	assert.Equal(t, http.StatusBadRequest, response.Status)
+	assert.Contains(t, response.Detail, "invalid request body")
+}
+
+func TestHandler_CreateShortUrl_InternalErrorIsHidden(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	url := model.Url{Url: "https://example.com"}
+	mockService.On("CreateShortUrl", mock.Anything, url).Return((*model.Url)(nil), fmt.Errorf("pq: relation \"urls\" does not exist"))
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.CreateShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "pq:")
+	var response dto.ProblemDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, dto.ProblemDTO{
+		Type:     "about:blank",
+		Title:    "Internal Server Error",
+		Status:   http.StatusInternalServerError,
+		Detail:   "internal server error",
+		Instance: "/shorten",
+		Code:     "internal",
+	}, response)
+	mockService.AssertExpectations(t)
 }
 
 func TestHandler_RedirectByShortUrl_Success(t *testing.T) {
@@ -101,10 +246,10 @@ func TestHandler_RedirectByShortUrl_Success(t *testing.T) {
 
 	shortUrl := "abc123"
 	originalUrl := "https://example.com"
-	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl, UserAgent: "test-agent"}
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl, UserAgent: "test-agent", ClientIp: "192.0.2.1", Source: model.SourceDirect}
 	urlInfo := &model.Url{ShortUrl: shortUrl, Url: originalUrl}
 
-	mockService.On("GetUrlByShort", shortUrl, redirectInfo).Return(urlInfo, nil)
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl, nil)
 	req.Header.Set("User-Agent", "test-agent")
@@ -115,7 +260,7 @@ func TestHandler_RedirectByShortUrl_Success(t *testing.T) {
 	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
 	handler.RedirectByShortUrl((*ginext.Context)(c))
 
-	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, http.StatusFound, w.Code)
 	assert.Equal(t, originalUrl, w.Header().Get("Location"))
 	mockService.AssertExpectations(t)
 }
@@ -129,7 +274,7 @@ func TestHandler_GetAnalytics_Success(t *testing.T) {
 		{Url: "https://example.com", ShortUrl: shortUrl, RedirectCount: 5, RequestTime: []string{"10:00"}, UserAgent: []string{"Mozilla/5.0"}},
 	}
 
-	mockService.On("GetAnalytics", shortUrl).Return(analytics, nil)
+	mockService.On("GetAnalytics", mock.Anything, shortUrl).Return(analytics, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/analytics/"+shortUrl, nil)
 	w := httptest.NewRecorder()
@@ -155,7 +300,7 @@ func TestHandler_AggregateByUserAgent_Success(t *testing.T) {
 		{ShortUrl: "abc123", UserAgent: []string{"Mozilla/5.0"}, RedirectCount: 5},
 	}
 
-	mockService.On("AggregateByUserAgent").Return(expected, nil)
+	mockService.On("AggregateByUserAgent", mock.Anything, dto.LinkFilter{}).Return(expected, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/analytics/user_agent", nil)
 	w := httptest.NewRecorder()
@@ -180,7 +325,7 @@ func TestHandler_AggregateByDate_Success(t *testing.T) {
 		{Day: 1, Month: 1, Year: 2023, UrlInfo: []dto.UrlInfo{{ShortUrl: "abc123", Time: "10:00"}}, RedirectCount: 10},
 	}
 
-	mockService.On("AggregateByDate").Return(expected, nil)
+	mockService.On("AggregateByDate", mock.Anything, dto.LinkFilter{}).Return(expected, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/analytics/date", nil)
 	w := httptest.NewRecorder()
@@ -208,7 +353,7 @@ func TestHandler_AggregateByMonth_Success(t *testing.T) {
 		}{{ShortUrl: "abc123", Time: "10:00"}}, RedirectCount: 100},
 	}
 
-	mockService.On("AggregateByMonth").Return(expected, nil)
+	mockService.On("AggregateByMonth", mock.Anything, dto.LinkFilter{}).Return(expected, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/analytics/month", nil)
 	w := httptest.NewRecorder()
@@ -224,3 +369,1013 @@ func TestHandler_AggregateByMonth_Success(t *testing.T) {
 	assert.Equal(t, expected, response)
 	mockService.AssertExpectations(t)
 }
+
+func TestHandler_RedirectByShortUrl_PreviewBot(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	originalUrl := "https://example.com"
+	userAgent := "Twitterbot/1.0"
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl, UserAgent: userAgent, ClientIp: "192.0.2.1", Source: model.SourceDirect}
+	urlInfo := &model.Url{ShortUrl: shortUrl, Url: originalUrl}
+	metadata := &model.UrlMetadata{
+		ShortUrl: shortUrl,
+		Title:    "Example <Domain>",
+		Image:    "https://example.com/image.png",
+		Status:   model.MetadataStatusOk,
+	}
+
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+	mockService.On("GetUrlMetadata", mock.Anything, shortUrl).Return(metadata, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl, nil)
+	req.Header.Set("User-Agent", userAgent)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.RedirectByShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<meta property="og:title" content="Example &lt;Domain&gt;">`)
+	assert.Contains(t, w.Body.String(), `<meta property="og:image" content="https://example.com/image.png">`)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetUrlMetadata_Success(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	metadata := &model.UrlMetadata{ShortUrl: shortUrl, Title: "Example", Status: model.MetadataStatusOk}
+
+	mockService.On("GetUrlMetadata", mock.Anything, shortUrl).Return(metadata, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/metadata/"+shortUrl, nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.GetUrlMetadata((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response model.UrlMetadata
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.Title, response.Title)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetUnhealthyUrls_Success(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	expected := []model.UrlHealth{
+		{ShortUrl: "abc123", Url: "https://example.com", StatusCode: 500, LatencyMs: 12},
+	}
+
+	mockService.On("GetUnhealthyUrls", mock.Anything).Return(expected, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health/links", nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.GetUnhealthyUrls((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []model.UrlHealth
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_RedirectByShortUrl_QrSource(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl, UserAgent: "test-agent", ClientIp: "192.0.2.1", Source: model.SourceQr}
+	urlInfo := &model.Url{ShortUrl: shortUrl, Url: "https://example.com"}
+
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl+"?source=qr", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.RedirectByShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetQrCode_Success(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	opts := qr.DefaultOptions()
+	opts.Format = qr.FormatSVG
+	opts.Size = 512
+	opts.Level = "H"
+	opts.Foreground, _ = qr.ParseColor("ff0000")
+	image := []byte("<svg></svg>")
+
+	mockService.On("GetQrCode", mock.Anything, shortUrl, "http://sho.rt/s/abc123?source=qr", opts).Return(image, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "http://sho.rt/qr/"+shortUrl+"?format=svg&size=512&level=H&fg=ff0000", nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.GetQrCode((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	assert.Equal(t, image, w.Body.Bytes())
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetQrCode_IgnoresForwardedHost(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	mockService.On("GetQrCode", mock.Anything, "abc123", "http://sho.rt/s/abc123?source=qr", qr.DefaultOptions()).Return([]byte("png"), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "http://sho.rt/api/v1/links/abc123/qr", nil)
+	req.Header.Set("X-Forwarded-Host", "evil.example")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: "abc123"}}
+	handler.GetQrCode((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "private, max-age=86400", w.Header().Get("Cache-Control"))
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetQrCode_PublicUrl(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+	publicUrl, _ := url.Parse("https://sho.rt/go/")
+	handler.SetPublicUrl(publicUrl)
+
+	mockService.On("GetQrCode", mock.Anything, "abc123", "https://sho.rt/go/s/abc123?source=qr", qr.DefaultOptions()).Return([]byte("png"), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "http://10.0.0.5:8080/api/v1/links/abc123/qr", nil)
+	req.Header.Set("X-Forwarded-Host", "evil.example")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: "abc123"}}
+	handler.GetQrCode((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=86400", w.Header().Get("Cache-Control"))
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetQrCode_InvalidSize(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/qr/abc123?size=10", nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: "abc123"}}
+	handler.GetQrCode((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "GetQrCode", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_RedirectByShortUrl_TargetingAttributes(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{
+		ShortUrl:  shortUrl,
+		UserAgent: "test-agent",
+		ClientIp:  "192.0.2.1",
+		Source:    model.SourceDirect,
+		Languages: []string{"de-DE", "en"},
+		Country:   "DE",
+	}
+	urlInfo := &model.Url{ShortUrl: shortUrl, Url: "https://example.de"}
+
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl, nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Accept-Language", "en;q=0.8, de-DE, *;q=0.1")
+	req.Header.Set("CF-IPCountry", "de")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.RedirectByShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.de", w.Header().Get("Location"))
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_SetTargetingRules_Success(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	rules := []model.TargetingRule{{Os: "ios", Destination: "https://apps.apple.com/app"}}
+	saved := []model.TargetingRule{{Id: 1, Position: 1, Os: "ios", Destination: "https://apps.apple.com/app"}}
+
+	mockService.On("SetTargetingRules", mock.Anything, shortUrl, rules).Return(saved, nil)
+
+	reqBody := `[{"os": "ios", "destination": "https://apps.apple.com/app"}]`
+	req := httptest.NewRequest(http.MethodPut, "/links/"+shortUrl+"/rules", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.SetTargetingRules((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []model.TargetingRule
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, saved, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_SetTargetingRules_Invalid(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	rules := []model.TargetingRule{{Os: "symbian", Destination: "https://example.com"}}
+
+	mockService.On("SetTargetingRules", mock.Anything, shortUrl, rules).Return([]model.TargetingRule(nil), service.ErrInvalidRule)
+
+	reqBody := `[{"os": "symbian", "destination": "https://example.com"}]`
+	req := httptest.NewRequest(http.MethodPut, "/links/"+shortUrl+"/rules", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.SetTargetingRules((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_RedirectByShortUrl_StickyVariant(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{
+		ShortUrl:  shortUrl,
+		UserAgent: "test-agent",
+		ClientIp:  "192.0.2.1",
+		Source:    model.SourceDirect,
+		Variant:   "a",
+	}
+	urlInfo := &model.Url{ShortUrl: shortUrl, Url: "https://b.example.com", StickyVariants: true, Variant: "b"}
+
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl, nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.AddCookie(&http.Cookie{Name: "ab_" + shortUrl, Value: "a"})
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.RedirectByShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://b.example.com", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "ab_"+shortUrl, cookies[0].Name)
+		assert.Equal(t, "b", cookies[0].Value)
+		assert.Equal(t, "/s/"+shortUrl, cookies[0].Path)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_SetVariants_Success(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	variants := dto.VariantsDTO{
+		ShortUrl: shortUrl,
+		Sticky:   true,
+		Variants: []model.Variant{{Name: "a", Url: "https://a.example.com", Weight: 70}},
+	}
+	saved := &dto.VariantsDTO{
+		ShortUrl: shortUrl,
+		Sticky:   true,
+		Variants: []model.Variant{{Id: 1, Name: "a", Url: "https://a.example.com", Weight: 70}},
+	}
+
+	mockService.On("SetVariants", mock.Anything, variants).Return(saved, nil)
+
+	reqBody := `{"sticky": true, "variants": [{"name": "a", "url": "https://a.example.com", "weight": 70}]}`
+	req := httptest.NewRequest(http.MethodPut, "/links/"+shortUrl+"/variants", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.SetVariants((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response dto.VariantsDTO
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, *saved, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_SetVariants_Invalid(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	variants := dto.VariantsDTO{
+		ShortUrl: shortUrl,
+		Variants: []model.Variant{{Name: "a", Url: "https://a.example.com"}},
+	}
+
+	mockService.On("SetVariants", mock.Anything, variants).Return((*dto.VariantsDTO)(nil), service.ErrInvalidVariant)
+
+	reqBody := `{"variants": [{"name": "a", "url": "https://a.example.com"}]}`
+	req := httptest.NewRequest(http.MethodPut, "/links/"+shortUrl+"/variants", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.SetVariants((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_RedirectByShortUrl_QueryPassthrough(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{
+		ShortUrl:  shortUrl,
+		UserAgent: "test-agent",
+		ClientIp:  "192.0.2.1",
+		Source:    model.SourceQr,
+		Query:     url.Values{"utm_source": {"newsletter"}},
+	}
+	urlInfo := &model.Url{ShortUrl: shortUrl, Url: "https://example.com/?utm_source=newsletter"}
+
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl+"?utm_source=newsletter&source=qr", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.RedirectByShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, urlInfo.Url, w.Header().Get("Location"))
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_AggregateByCampaign_Success(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	expected := []dto.CampaignDTO{
+		{Period: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Source: "newsletter", Medium: "email", Campaign: "spring", Clicks: 10, Uniques: 7},
+	}
+
+	mockService.On("AggregateByCampaign", mock.Anything, "month", dto.LinkFilter{}).Return(expected, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/campaigns?period=month", nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.AggregateByCampaign((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []dto.CampaignDTO
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_AggregateByCampaign_InvalidPeriod(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	mockService.On("AggregateByCampaign", mock.Anything, "hour", dto.LinkFilter{}).Return([]dto.CampaignDTO(nil), service.ErrInvalidPeriod)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/campaigns?period=hour", nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.AggregateByCampaign((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_RedirectByShortUrl_Referrer(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{
+		ShortUrl:  shortUrl,
+		UserAgent: "test-agent",
+		ClientIp:  "192.0.2.1",
+		Source:    model.SourceDirect,
+		Referrer:  "https://t.co/xyz",
+	}
+	urlInfo := &model.Url{ShortUrl: shortUrl, Url: "https://example.com"}
+
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl, nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Referer", "https://t.co/xyz")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.RedirectByShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_AggregateByReferrer_Success(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	expected := []dto.ReferrerDTO{
+		{
+			ShortUrl:      "abc123",
+			Referrers:     []dto.ReferrerCount{{Referrer: "twitter", RedirectCount: 3}, {Referrer: "direct", RedirectCount: 1}},
+			RedirectCount: 4,
+		},
+	}
+
+	mockService.On("AggregateByReferrer", mock.Anything, dto.LinkFilter{}).Return(expected, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/referrer", nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.AggregateByReferrer((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []dto.ReferrerDTO
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_AggregateByDate_Filter(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	filter := dto.LinkFilter{Tag: "black-friday", Folder: "marketing"}
+	mockService.On("AggregateByDate", mock.Anything, filter).Return([]dto.DateDTO{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/date?tag=black-friday&folder=marketing", nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.AggregateByDate((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_ListLinks_Success(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	createdFrom := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	minClicks := 10
+	expected := []dto.LinkDTO{{
+		ShortUrl:  "abc123",
+		Url:       "https://example.com",
+		Folder:    "marketing",
+		Tags:      []string{"black-friday"},
+		Status:    model.LinkStatusActive,
+		CreatedAt: createdFrom,
+		Clicks:    12,
+	}}
+	search := dto.LinkQuery{
+		LinkFilter:  dto.LinkFilter{Tag: "black-friday"},
+		Search:      "example",
+		Status:      model.LinkStatusActive,
+		CreatedFrom: &createdFrom,
+		MinClicks:   &minClicks,
+		Sort:        dto.LinkSortClicks,
+		Limit:       20,
+	}
+	mockService.On("SearchLinks", mock.Anything, search).Return(expected, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/links?tag=black-friday&q=example&status=active&created_from=2026-10-01&min_clicks=10&sort=clicks&limit=20", nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.ListLinks((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []dto.LinkDTO
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_SetTags_NotFound(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "missing"
+	mockService.On("SetTags", mock.Anything, shortUrl, []string{"email"}).Return((*dto.LinkDTO)(nil), fmt.Errorf("%w: %w", service.ErrLinkNotFound, repository.ErrAliasNotFound))
+
+	req := httptest.NewRequest(http.MethodPut, "/links/"+shortUrl+"/tags", strings.NewReader(`["email"]`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.SetTags((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_SetFolder_Success(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	link := &dto.LinkDTO{ShortUrl: shortUrl, Url: "https://example.com", Folder: "marketing", Tags: []string{}}
+	mockService.On("SetFolder", mock.Anything, shortUrl, "marketing").Return(link, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/links/"+shortUrl+"/folder", strings.NewReader(`{"folder": "marketing"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.SetFolder((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response dto.LinkDTO
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, *link, response)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_ListLinks_InvalidParam(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/links?min_clicks=many", nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.ListLinks((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "SearchLinks", mock.Anything, mock.Anything)
+}
+
+func TestHandler_RedirectByShortUrl_Unavailable(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl, UserAgent: "test-agent", ClientIp: "192.0.2.1", Source: model.SourceDirect}
+
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return((*model.Url)(nil), service.ErrLinkUnavailable)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl, nil)
+	req.Header.Set("User-Agent", "test-agent")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.RedirectByShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_RedirectByShortUrl_NotFound(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "missing"
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl, ClientIp: "192.0.2.1", Source: model.SourceDirect}
+	notFound := fmt.Errorf("%w: %w", service.ErrLinkNotFound, repository.ErrAliasNotFound)
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return((*model.Url)(nil), notFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl, nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.RedirectByShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var response dto.ProblemDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "link_not_found", response.Code)
+	assert.Equal(t, "Not Found", response.Title)
+	assert.Equal(t, "/s/missing", response.Instance)
+	mockService.AssertExpectations(t)
+}
+
+func TestProblemFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"Validation", fmt.Errorf("%w: \"a b\" contains ' '", service.ErrInvalidTag), http.StatusBadRequest, "invalid_tag", "invalid tag: \"a b\" contains ' '"},
+		{"NotFound", fmt.Errorf("%w: %w", service.ErrMetadataNotFound, repository.ErrMetadataNotFound), http.StatusNotFound, "metadata_not_found", "metadata is not fetched yet: metadata for short_url is not fetched yet"},
+		{"Conflict", service.ErrUrlExists, http.StatusConflict, "url_exists", "url is already shortened"},
+		{"Expired", service.ErrLinkUnavailable, http.StatusGone, "link_unavailable", "link is disabled or expired"},
+		{"Forbidden", &service.Error{Kind: service.KindForbidden, Code: "forbidden", Message: "forbidden"}, http.StatusForbidden, "forbidden", "forbidden"},
+		{"Unavailable", fmt.Errorf("%w: dial tcp 10.0.0.1:6379: connection refused", service.ErrCacheUnavailable), http.StatusServiceUnavailable, "cache_unavailable", "cache is unavailable"},
+		{"Timeout", fmt.Errorf("%w: %w", context.DeadlineExceeded, service.ErrLinkNotFound), http.StatusGatewayTimeout, "timeout", "request timed out"},
+		{"Cancelled", context.Canceled, http.StatusServiceUnavailable, "request_cancelled", "request cancelled"},
+		{"Internal", fmt.Errorf("sql: connection is already closed"), http.StatusInternalServerError, "internal", "internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := problemFor(tt.err)
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.detail, problem.Detail)
+		})
+	}
+}
+
+func TestHandler_GetUnhealthyUrls_PassesRequestContext(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health/links", nil)
+	mockService.On("GetUnhealthyUrls", req.Context()).Return([]model.UrlHealth{}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.GetUnhealthyUrls((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_RedirectByShortUrl_Timeout(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	timeoutErr := fmt.Errorf("%w: could not get url from db", context.DeadlineExceeded)
+	mockService.On("GetUrlByShort", mock.Anything, shortUrl, mock.Anything).Return((*model.Url)(nil), timeoutErr)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+shortUrl, nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.RedirectByShortUrl((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetAnalytics_Cancelled(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	mockService.On("GetAnalytics", mock.Anything, shortUrl).Return([]dto.RedirectInfo(nil), context.Canceled)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/"+shortUrl, nil)
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.GetAnalytics((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_SetTags_Timeout(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	shortUrl := "abc123"
+	mockService.On("SetTags", mock.Anything, shortUrl, []string{"email"}).Return((*dto.LinkDTO)(nil), context.DeadlineExceeded)
+
+	req := httptest.NewRequest(http.MethodPut, "/links/"+shortUrl+"/tags", strings.NewReader(`["email"]`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+	handler.SetTags((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_CreateLink(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	url := model.Url{Url: "https://example.com"}
+	mockService.On("CreateShortUrl", mock.Anything, url).Return(&model.Url{Url: "https://example.com", ShortUrl: "abc123"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.CreateLink((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v1/links/abc123", w.Header().Get("Location"))
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_CreateLink_IdempotentReplay(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	apiKey := &model.ApiKey{Id: 1, Name: "ci"}
+	url := model.Url{Url: "https://example.com"}
+	mockService.On("AuthenticateApiKey", mock.Anything, "us_valid").Return(apiKey, nil)
+	mockService.On("CreateShortUrlIdempotent", mock.Anything, apiKey, "key-1", url).Return(&model.Url{Url: "https://example.com", ShortUrl: "abc123"}, true, nil)
+
+	router := gin.New()
+	router.POST("/api/v1/links",
+		func(c *gin.Context) { handler.IdentifyApiKey((*ginext.Context)(c)) },
+		func(c *gin.Context) { handler.CreateLink((*ginext.Context)(c)) },
+	)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer us_valid")
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "CreateShortUrl", mock.Anything, mock.Anything)
+}
+
+func TestHandler_CreateLink_IdempotencyKeyReused(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	url := model.Url{Url: "https://example.org"}
+	mockService.On("CreateShortUrlIdempotent", mock.Anything, (*model.ApiKey)(nil), "key-1", url).Return((*model.Url)(nil), false, service.ErrIdempotencyKeyReused)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"url":"https://example.org"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.CreateLink((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	var response dto.ProblemDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "idempotency_key_reused", response.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetLink(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	link := &dto.LinkDTO{ShortUrl: "abc123", Url: "https://example.com", Status: model.LinkStatusActive, Clicks: 3}
+	mockService.On("GetLink", mock.Anything, "abc123").Return(link, nil)
+	mockService.On("GetLink", mock.Anything, "missing").Return((*dto.LinkDTO)(nil), service.ErrLinkNotFound)
+
+	for shortUrl, status := range map[string]int{"abc123": http.StatusOK, "missing": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/links/"+shortUrl, nil)
+		c.Params = gin.Params{{Key: "short_url", Value: shortUrl}}
+		handler.GetLink((*ginext.Context)(c))
+
+		assert.Equal(t, status, w.Code, shortUrl)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_DeleteLink(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	mockService.On("DeleteLink", mock.Anything, "abc123").Return(&model.Url{ShortUrl: "abc123", Url: "https://example.com"}, nil)
+
+	router := gin.New()
+	router.DELETE("/api/v1/links/:short_url", func(c *gin.Context) { handler.DeleteLink((*ginext.Context)(c)) })
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/links/abc123", nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_RequireApiKey(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	mockService.On("AuthenticateApiKey", mock.Anything, "us_valid").Return(&model.ApiKey{Id: 1, Name: "ci"}, nil)
+	mockService.On("AuthenticateApiKey", mock.Anything, "us_revoked").Return((*model.ApiKey)(nil), service.ErrUnauthenticated)
+	mockService.On("ListApiKeys", mock.Anything).Return([]model.ApiKey{{Id: 1, Name: "ci"}}, nil)
+
+	router := gin.New()
+	router.GET("/api/v1/keys",
+		func(c *gin.Context) { handler.RequireApiKey((*ginext.Context)(c)) },
+		func(c *gin.Context) { handler.ListApiKeys((*ginext.Context)(c)) },
+	)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"Missing", "", http.StatusUnauthorized},
+		{"NotBearer", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		{"Revoked", "Bearer us_revoked", http.StatusUnauthorized},
+		{"Valid", "Bearer us_valid", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/keys", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+				assert.Contains(t, w.Body.String(), `"code":"unauthenticated"`)
+			}
+		})
+	}
+	mockService.AssertNumberOfCalls(t, "ListApiKeys", 1)
+}
+
+func TestHandler_IdentifyApiKey(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	apiKey := &model.ApiKey{Id: 1, Name: "ci"}
+	mockService.On("AuthenticateApiKey", mock.Anything, "us_valid").Return(apiKey, nil)
+	mockService.On("AuthenticateApiKey", mock.Anything, "us_revoked").Return((*model.ApiKey)(nil), service.ErrUnauthenticated)
+
+	var identified *model.ApiKey
+	router := gin.New()
+	router.POST("/api/v1/links",
+		func(c *gin.Context) { handler.IdentifyApiKey((*ginext.Context)(c)) },
+		func(c *gin.Context) {
+			identified = service.ApiKeyFromContext(c.Request.Context())
+			c.Status(http.StatusCreated)
+		},
+	)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		identified    *model.ApiKey
+	}{
+		{"Anonymous", "", http.StatusCreated, nil},
+		{"NotBearer", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, nil},
+		{"Revoked", "Bearer us_revoked", http.StatusUnauthorized, nil},
+		{"Valid", "Bearer us_valid", http.StatusCreated, apiKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identified = nil
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/links", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.identified, identified)
+		})
+	}
+}
+
+func TestHandler_CreateLink_IdempotencyKeyWithoutApiKey(t *testing.T) {
+	mockService := new(MockShortnerService)
+	handler := New(mockService)
+
+	url := model.Url{Url: "https://example.com"}
+	mockService.On("CreateShortUrlIdempotent", mock.Anything, (*model.ApiKey)(nil), "key-1", url).Return((*model.Url)(nil), false, service.ErrUnauthenticated)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	handler.CreateLink((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	mockService.AssertExpectations(t)
+}
+
+func TestDeprecated(t *testing.T) {
+	router := gin.New()
+	router.GET("/analytics/:short_url",
+		func(c *gin.Context) { Deprecated(APIPrefix + "/links/:short_url/analytics")((*ginext.Context)(c)) },
+		func(c *gin.Context) { c.Status(http.StatusOK) },
+	)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/analytics/abc123", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, fmt.Sprintf("@%d", time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC).Unix()), w.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v1/links/abc123/analytics>; rel="successor-version"`, w.Header().Get("Link"))
+}
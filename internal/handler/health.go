@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	_ "github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	_ "github.com/Komilov31/url-shortener/internal/model"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// GetUnhealthyUrls godoc
+// @Summary Get links with unhealthy destinations
+// @Description Returns links whose destination failed the last scheduled health check
+// @Tags URL
+// @Produce json
+// @Success 200 {array} model.UrlHealth
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/health/links [get]
+func (h *Handler) GetUnhealthyUrls(c *ginext.Context) {
+	unhealthy, err := h.service.GetUnhealthyUrls(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get unhealthy urls")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting unhealthy urls")
+	c.JSON(http.StatusOK, unhealthy)
+}
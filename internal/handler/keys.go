@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	_ "github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// RequireApiKey lets through requests carrying a valid key in the
+// Authorization: Bearer header. The first key is issued with the admin CLI.
+func (h *Handler) RequireApiKey(c *ginext.Context) {
+	key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		c.Header("WWW-Authenticate", "Bearer")
+		writeError(c, service.ErrUnauthenticated)
+		return
+	}
+
+	apiKey, err := h.service.AuthenticateApiKey(c.Request.Context(), strings.TrimSpace(key))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn().Err(err).Msg("could not authenticate api key")
+		if errors.Is(err, service.ErrUnauthenticated) {
+			c.Header("WWW-Authenticate", "Bearer")
+		}
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Int("api_key_id", apiKey.Id).Msg("authenticated api key")
+	c.Request = c.Request.WithContext(service.WithApiKey(c.Request.Context(), apiKey))
+	c.Next()
+}
+
+// IdentifyApiKey checks the key of requests carrying an Authorization
+// header like RequireApiKey and lets anonymous requests through.
+func (h *Handler) IdentifyApiKey(c *ginext.Context) {
+	if c.GetHeader("Authorization") == "" {
+		c.Next()
+		return
+	}
+	h.RequireApiKey(c)
+}
+
+// ListApiKeys godoc
+// @Summary List API keys
+// @Description Returns every issued key with its prefix and revocation time, keys themselves are never shown again
+// @Tags Keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} model.ApiKey
+// @Failure 401 {object} dto.ProblemDTO "Missing, unknown or revoked API key"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/keys [get]
+func (h *Handler) ListApiKeys(c *ginext.Context) {
+	keys, err := h.service.ListApiKeys(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not list api keys")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for listing api keys")
+	c.JSON(http.StatusOK, keys)
+}
+
+// IssueApiKey godoc
+// @Summary Issue an API key
+// @Description Issues a new key. The key is returned only in this response, only its hash is stored
+// @Tags Keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param key body dto.ApiKeyNameDTO true "Name of the key"
+// @Success 201 {object} dto.IssuedApiKeyDTO
+// @Failure 400 {object} dto.ProblemDTO "Invalid name"
+// @Failure 401 {object} dto.ProblemDTO "Missing, unknown or revoked API key"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/keys [post]
+func (h *Handler) IssueApiKey(c *ginext.Context) {
+	var name dto.ApiKeyNameDTO
+	if err := c.ShouldBindJSON(&name); err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not bind json to object")
+		writeError(c, fmt.Errorf("%w: %w", service.ErrInvalidBody, err))
+		return
+	}
+
+	issued, err := h.service.IssueApiKey(c.Request.Context(), name.Name)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not issue api key")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info().Int("api_key_id", issued.Id).Msg("issued api key")
+	c.JSON(http.StatusCreated, issued)
+}
+
+// RevokeApiKey godoc
+// @Summary Revoke an API key
+// @Description Revokes the key, revoking it again keeps the time of the first revocation
+// @Tags Keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Key id"
+// @Success 200 {object} model.ApiKey
+// @Failure 400 {object} dto.ProblemDTO "Invalid id"
+// @Failure 401 {object} dto.ProblemDTO "Missing, unknown or revoked API key"
+// @Failure 404 {object} dto.ProblemDTO "API key not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/keys/{id} [delete]
+func (h *Handler) RevokeApiKey(c *ginext.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		writeError(c, fmt.Errorf("%w: id must be an integer", service.ErrInvalidApiKey))
+		return
+	}
+
+	revoked, err := h.service.RevokeApiKey(c.Request.Context(), id)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not revoke api key")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info().Int("api_key_id", revoked.Id).Msg("revoked api key")
+	c.JSON(http.StatusOK, revoked)
+}
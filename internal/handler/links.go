@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// ListLinks godoc
+// @Summary Search short URLs
+// @Description Returns short URLs with their folder, tags, status and click counts. q matches a substring of the destination URL, alias, page title and tags
+// @Tags URL
+// @Produce json
+// @Param q query string false "Search text"
+// @Param tag query string false "Only links with this tag"
+// @Param folder query string false "Only links in this folder"
+// @Param owner query string false "Only links of this owner"
+// @Param status query string false "active, expired or disabled"
+// @Param created_from query string false "Created at or after, RFC 3339 or YYYY-MM-DD"
+// @Param created_to query string false "Created before, RFC 3339 or YYYY-MM-DD"
+// @Param min_clicks query int false "Minimum number of clicks"
+// @Param max_clicks query int false "Maximum number of clicks"
+// @Param sort query string false "created, last_click or clicks" default(created)
+// @Param order query string false "asc or desc" default(desc)
+// @Param limit query int false "Page size, at most 500" default(50)
+// @Param offset query int false "Number of links to skip" default(0)
+// @Success 200 {array} dto.LinkDTO
+// @Failure 400 {object} dto.ProblemDTO "Invalid search parameters"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links [get]
+func (h *Handler) ListLinks(c *ginext.Context) {
+	search, err := parseLinkQuery(c)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not parse link search")
+		writeError(c, err)
+		return
+	}
+
+	links, err := h.service.SearchLinks(c.Request.Context(), search)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not list links")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for listing links")
+	c.JSON(http.StatusOK, links)
+}
+
+// GetLink godoc
+// @Summary Get a short URL
+// @Description Returns the short URL with its folder, tags, status and click counts
+// @Tags URL
+// @Produce json
+// @Param short_url path string true "Short URL"
+// @Success 200 {object} dto.LinkDTO
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url} [get]
+func (h *Handler) GetLink(c *ginext.Context) {
+	link, err := h.service.GetLink(c.Request.Context(), c.Param("short_url"))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get link")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting link")
+	c.JSON(http.StatusOK, link)
+}
+
+// DeleteLink godoc
+// @Summary Delete a short URL
+// @Description Deletes the short URL with its analytics, rules, variants, tags and metadata. It stops redirecting right away
+// @Tags URL
+// @Param short_url path string true "Short URL"
+// @Security ApiKeyAuth
+// @Success 204 "Short URL deleted"
+// @Failure 401 {object} dto.ProblemDTO "Missing, unknown or revoked API key"
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url} [delete]
+func (h *Handler) DeleteLink(c *ginext.Context) {
+	deleted, err := h.service.DeleteLink(c.Request.Context(), c.Param("short_url"))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not delete link")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info().Str("short_url", deleted.ShortUrl).Msg("deleted link")
+	c.Status(http.StatusNoContent)
+}
+
+// SetTags godoc
+// @Summary Replace tags of a short URL
+// @Description Replaces the tags of the short URL. Tags are lowercased and may contain letters, digits, '-', '_' and '.'
+// @Tags URL
+// @Accept json
+// @Produce json
+// @Param short_url path string true "Short URL"
+// @Param tags body []string true "Tags"
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.LinkDTO
+// @Failure 400 {object} dto.ProblemDTO "Invalid tags"
+// @Failure 401 {object} dto.ProblemDTO "Missing, unknown or revoked API key"
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/tags [put]
+func (h *Handler) SetTags(c *ginext.Context) {
+	var tags []string
+	if err := c.ShouldBindJSON(&tags); err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not bind json to object")
+		writeError(c, fmt.Errorf("%w: %w", service.ErrInvalidBody, err))
+		return
+	}
+
+	link, err := h.service.SetTags(c.Request.Context(), c.Param("short_url"), tags)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not set tags")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled PUT request for setting tags")
+	c.JSON(http.StatusOK, link)
+}
+
+// SetFolder godoc
+// @Summary Move a short URL to a folder
+// @Description Sets the folder of the short URL, an empty folder removes the link from its folder
+// @Tags URL
+// @Accept json
+// @Produce json
+// @Param short_url path string true "Short URL"
+// @Param folder body dto.FolderDTO true "Folder"
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.LinkDTO
+// @Failure 400 {object} dto.ProblemDTO "Invalid folder"
+// @Failure 401 {object} dto.ProblemDTO "Missing, unknown or revoked API key"
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/folder [put]
+func (h *Handler) SetFolder(c *ginext.Context) {
+	var folder dto.FolderDTO
+	if err := c.ShouldBindJSON(&folder); err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not bind json to object")
+		writeError(c, fmt.Errorf("%w: %w", service.ErrInvalidBody, err))
+		return
+	}
+
+	link, err := h.service.SetFolder(c.Request.Context(), c.Param("short_url"), folder.Folder)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not set folder")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled PUT request for setting folder")
+	c.JSON(http.StatusOK, link)
+}
+
+// SetDisabled godoc
+// @Summary Disable or enable a short URL
+// @Description Disabled short URLs respond with 410 Gone instead of redirecting
+// @Tags URL
+// @Accept json
+// @Produce json
+// @Param short_url path string true "Short URL"
+// @Param disabled body dto.DisabledDTO true "Disabled flag"
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.LinkDTO
+// @Failure 400 {object} dto.ProblemDTO "Invalid request body"
+// @Failure 401 {object} dto.ProblemDTO "Missing, unknown or revoked API key"
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/disabled [put]
+func (h *Handler) SetDisabled(c *ginext.Context) {
+	var disabled dto.DisabledDTO
+	if err := c.ShouldBindJSON(&disabled); err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not bind json to object")
+		writeError(c, fmt.Errorf("%w: %w", service.ErrInvalidBody, err))
+		return
+	}
+
+	link, err := h.service.SetDisabled(c.Request.Context(), c.Param("short_url"), disabled.Disabled)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not set disabled flag")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled PUT request for disabling link")
+	c.JSON(http.StatusOK, link)
+}
+
+func parseLinkQuery(c *ginext.Context) (dto.LinkQuery, error) {
+	search := dto.LinkQuery{
+		LinkFilter: linkFilter(c),
+		Search:     c.Query("q"),
+		Owner:      c.Query("owner"),
+		Status:     c.Query("status"),
+		Sort:       c.Query("sort"),
+		Order:      c.Query("order"),
+	}
+
+	var err error
+	if search.CreatedFrom, err = parseTimeParam(c, "created_from"); err != nil {
+		return search, err
+	}
+	if search.CreatedTo, err = parseTimeParam(c, "created_to"); err != nil {
+		return search, err
+	}
+	if search.MinClicks, err = parseIntParam(c, "min_clicks"); err != nil {
+		return search, err
+	}
+	if search.MaxClicks, err = parseIntParam(c, "max_clicks"); err != nil {
+		return search, err
+	}
+
+	if limit, err := parseIntParam(c, "limit"); err != nil {
+		return search, err
+	} else if limit != nil {
+		search.Limit = *limit
+	}
+	if offset, err := parseIntParam(c, "offset"); err != nil {
+		return search, err
+	} else if offset != nil {
+		search.Offset = *offset
+	}
+
+	return search, nil
+}
+
+func parseIntParam(c *ginext.Context, name string) (*int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an integer", service.ErrInvalidSearch, name)
+	}
+	return &n, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps and plain dates.
+func parseTimeParam(c *ginext.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s must be a date like 2006-01-02 or an RFC 3339 timestamp", service.ErrInvalidSearch, name)
+}
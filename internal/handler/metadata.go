@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+
+	_ "github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// previewBots are user agent fragments of crawlers that render link previews
+// and therefore should get Open Graph tags instead of a plain redirect.
+var previewBots = []string{
+	"facebookexternalhit",
+	"facebot",
+	"twitterbot",
+	"linkedinbot",
+	"slackbot",
+	"discordbot",
+	"telegrambot",
+	"whatsapp",
+	"vkshare",
+	"skypeuripreview",
+}
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:url" content="{{or .CanonicalUrl .Url}}">
+{{if .Title}}<meta property="og:title" content="{{.Title}}">
+{{end}}{{if .Description}}<meta name="description" content="{{.Description}}">
+<meta property="og:description" content="{{.Description}}">
+{{end}}{{if .Image}}<meta property="og:image" content="{{.Image}}">
+<meta name="twitter:card" content="summary_large_image">
+{{end}}<meta http-equiv="refresh" content="0; url={{.Url}}">
+</head>
+<body><a href="{{.Url}}">{{.Url}}</a></body>
+</html>
+`))
+
+// GetUrlMetadata godoc
+// @Summary Get destination page metadata for a short URL
+// @Description Returns title, description, Open Graph image and canonical URL of the destination page
+// @Tags URL
+// @Produce json
+// @Param short_url path string true "Short URL"
+// @Success 200 {object} model.UrlMetadata
+// @Failure 404 {object} dto.ProblemDTO "Metadata is not fetched yet"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/metadata [get]
+func (h *Handler) GetUrlMetadata(c *ginext.Context) {
+	short_url := c.Param("short_url")
+	metadata, err := h.service.GetUrlMetadata(c.Request.Context(), short_url)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get url metadata")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting url metadata")
+	c.JSON(http.StatusOK, metadata)
+}
+
+func isPreviewBot(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, bot := range previewBots {
+		if strings.Contains(userAgent, bot) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderPreview writes a page with Open Graph tags of the destination.
+// It returns false when there is no usable metadata and the caller should
+// fall back to a regular redirect.
+func (h *Handler) renderPreview(c *ginext.Context, url *model.Url) bool {
+	metadata, err := h.service.GetUrlMetadata(c.Request.Context(), url.ShortUrl)
+	if err != nil || metadata.Status != model.MetadataStatusOk {
+		return false
+	}
+
+	var page bytes.Buffer
+	err = previewTemplate.Execute(&page, map[string]string{
+		"Url":          url.Url,
+		"Title":        metadata.Title,
+		"Description":  metadata.Description,
+		"Image":        metadata.Image,
+		"CanonicalUrl": metadata.CanonicalUrl,
+	})
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not render preview page")
+		return false
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+	return true
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/qr"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// GetQrCode godoc
+// @Summary Get QR code for a short URL
+// @Description Returns a PNG or SVG QR code of the full short URL. Scans are recorded with source=qr
+// @Tags URL
+// @Produce png
+// @Produce image/svg+xml
+// @Param short_url path string true "Short URL"
+// @Param format query string false "Image format: png or svg" default(png)
+// @Param size query int false "Image size in pixels" default(256)
+// @Param level query string false "Error correction level: L, M, Q or H" default(M)
+// @Param margin query int false "Quiet zone size in modules" default(4)
+// @Param fg query string false "Foreground color in hex" default(000000)
+// @Param bg query string false "Background color in hex" default(ffffff)
+// @Success 200 "QR code image"
+// @Failure 400 {object} dto.ProblemDTO "Invalid parameters"
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/qr [get]
+func (h *Handler) GetQrCode(c *ginext.Context) {
+	short_url := c.Param("short_url")
+	opts, err := parseQrOptions(c)
+	if err != nil {
+		writeError(c, fmt.Errorf("%w: %w", service.ErrInvalidQrOptions, err))
+		return
+	}
+
+	image, err := h.service.GetQrCode(c.Request.Context(), short_url, h.qrContent(c, short_url), opts)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not generate qr code")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting qr code")
+	// Without a public URL the code points at the Host of the request,
+	// which is up to the client and must not end up in shared caches.
+	if h.publicUrl != nil {
+		c.Header("Cache-Control", "public, max-age=86400")
+	} else {
+		c.Header("Cache-Control", "private, max-age=86400")
+	}
+	c.Data(http.StatusOK, opts.ContentType(), image)
+}
+
+func parseQrOptions(c *ginext.Context) (qr.Options, error) {
+	opts := qr.DefaultOptions()
+
+	if format := c.Query("format"); format != "" {
+		opts.Format = format
+	}
+	if level := c.Query("level"); level != "" {
+		opts.Level = level
+	}
+	if size := c.Query("size"); size != "" {
+		value, err := strconv.Atoi(size)
+		if err != nil {
+			return opts, qr.ErrInvalidSize
+		}
+		opts.Size = value
+	}
+	if margin := c.Query("margin"); margin != "" {
+		value, err := strconv.Atoi(margin)
+		if err != nil {
+			return opts, qr.ErrInvalidMargin
+		}
+		opts.Margin = value
+	}
+	if fg := c.Query("fg"); fg != "" {
+		value, err := qr.ParseColor(fg)
+		if err != nil {
+			return opts, err
+		}
+		opts.Foreground = value
+	}
+	if bg := c.Query("bg"); bg != "" {
+		value, err := qr.ParseColor(bg)
+		if err != nil {
+			return opts, err
+		}
+		opts.Background = value
+	}
+
+	return opts, opts.Validate()
+}
+
+// qrContent builds the full short URL encoded into the QR code from the
+// public URL or, without one, from the request. Forwarded headers are not
+// trusted. The source marker lets redirects tell QR scans apart from
+// direct clicks.
+func (h *Handler) qrContent(c *ginext.Context, short_url string) string {
+	link := url.URL{Scheme: "http", Host: c.Request.Host}
+	if c.Request.TLS != nil {
+		link.Scheme = "https"
+	}
+	if h.publicUrl != nil {
+		link = url.URL{Scheme: h.publicUrl.Scheme, Host: h.publicUrl.Host, Path: h.publicUrl.Path}
+	}
+
+	link.Path = strings.TrimSuffix(link.Path, "/") + "/s/" + short_url
+	link.RawQuery = url.Values{"source": {model.SourceQr}}.Encode()
+	return link.String()
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// countryHeaders are set by CDNs and load balancers that resolve the
+// visitor country, the first non-empty one is used.
+var countryHeaders = []string{"CF-IPCountry", "X-Country-Code", "X-Geo-Country"}
+
+// SetTargetingRules godoc
+// @Summary Replace targeting rules of a short URL
+// @Description Replaces the ordered list of targeting rules. The first rule matching visitor OS, device, browser, language and country decides the destination
+// @Tags URL
+// @Accept json
+// @Produce json
+// @Param short_url path string true "Short URL"
+// @Param rules body []model.TargetingRule true "Ordered targeting rules"
+// @Security ApiKeyAuth
+// @Success 200 {array} model.TargetingRule
+// @Failure 400 {object} dto.ProblemDTO "Invalid rules"
+// @Failure 401 {object} dto.ProblemDTO "Missing, unknown or revoked API key"
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/rules [put]
+func (h *Handler) SetTargetingRules(c *ginext.Context) {
+	short_url := c.Param("short_url")
+
+	var rules []model.TargetingRule
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not bind json to object")
+		writeError(c, fmt.Errorf("%w: %w", service.ErrInvalidBody, err))
+		return
+	}
+
+	saved, err := h.service.SetTargetingRules(c.Request.Context(), short_url, rules)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not set targeting rules")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled PUT request for setting targeting rules")
+	c.JSON(http.StatusOK, saved)
+}
+
+// GetTargetingRules godoc
+// @Summary Get targeting rules of a short URL
+// @Description Returns the ordered list of targeting rules
+// @Tags URL
+// @Produce json
+// @Param short_url path string true "Short URL"
+// @Success 200 {array} model.TargetingRule
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/rules [get]
+func (h *Handler) GetTargetingRules(c *ginext.Context) {
+	short_url := c.Param("short_url")
+	rules, err := h.service.GetTargetingRules(c.Request.Context(), short_url)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get targeting rules")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting targeting rules")
+	c.JSON(http.StatusOK, rules)
+}
+
+// parseAcceptLanguage returns language tags from an Accept-Language header
+// ordered by their quality value.
+func parseAcceptLanguage(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []language
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			languages = append(languages, language{tag: tag, quality: quality})
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	var tags []string
+	for _, l := range languages {
+		tags = append(tags, l.tag)
+	}
+	return tags
+}
+
+func requestCountry(c *ginext.Context) string {
+	for _, header := range countryHeaders {
+		if country := strings.TrimSpace(c.GetHeader(header)); country != "" {
+			return strings.ToUpper(country)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/wb-go/wbf/ginext"
+)
+
+const variantCookieMaxAge = 30 * 24 * 60 * 60
+
+// SetVariants godoc
+// @Summary Replace weighted destinations of a short URL
+// @Description Replaces destinations between which traffic is split proportionally to their weights. With sticky enabled a visitor keeps the same variant via cookie
+// @Tags URL
+// @Accept json
+// @Produce json
+// @Param short_url path string true "Short URL"
+// @Param variants body dto.VariantsDTO true "Weighted destinations"
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.VariantsDTO
+// @Failure 400 {object} dto.ProblemDTO "Invalid variants"
+// @Failure 401 {object} dto.ProblemDTO "Missing, unknown or revoked API key"
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/variants [put]
+func (h *Handler) SetVariants(c *ginext.Context) {
+	var variants dto.VariantsDTO
+	if err := c.ShouldBindJSON(&variants); err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not bind json to object")
+		writeError(c, fmt.Errorf("%w: %w", service.ErrInvalidBody, err))
+		return
+	}
+	variants.ShortUrl = c.Param("short_url")
+
+	saved, err := h.service.SetVariants(c.Request.Context(), variants)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not set variants")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled PUT request for setting variants")
+	c.JSON(http.StatusOK, saved)
+}
+
+// GetVariants godoc
+// @Summary Get weighted destinations of a short URL
+// @Description Returns destinations used for the A/B split and whether the split is sticky
+// @Tags URL
+// @Produce json
+// @Param short_url path string true "Short URL"
+// @Success 200 {object} dto.VariantsDTO
+// @Failure 404 {object} dto.ProblemDTO "Short URL not found"
+// @Failure 500 {object} dto.ProblemDTO "Internal server error"
+// @Failure 503 {object} dto.ProblemDTO "Request cancelled"
+// @Failure 504 {object} dto.ProblemDTO "Request timed out"
+// @Router /api/v1/links/{short_url}/variants [get]
+func (h *Handler) GetVariants(c *ginext.Context) {
+	variants, err := h.service.GetVariants(c.Request.Context(), c.Param("short_url"))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error().Err(err).Msg("could not get variants")
+		writeError(c, err)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Debug().Msg("succesfully handled GET request for getting variants")
+	c.JSON(http.StatusOK, variants)
+}
+
+func variantCookieName(short_url string) string {
+	return "ab_" + short_url
+}
+
+func setVariantCookie(c *ginext.Context, short_url, variant string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(variantCookieName(short_url), variant, variantCookieMaxAge, "/s/"+short_url, "", false, true)
+}
@@ -0,0 +1,210 @@
+// Package healthcheck periodically probes destinations of short links and
+// records whether they are still reachable.
+package healthcheck
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/safehttp"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	userAgent    = "url-shortener-healthcheck/1.0"
+	maxDrainSize = 64 << 10
+)
+
+type Storage interface {
+	ListUrls(context.Context) ([]model.Url, error)
+	SaveUrlHealth(context.Context, model.UrlHealth) error
+}
+
+type Options struct {
+	Interval     time.Duration
+	Timeout      time.Duration
+	Concurrency  int
+	PerHostDelay time.Duration
+	MaxRedirects int
+	AllowPrivate bool
+	// FailureThreshold is how many checks in a row must fail before a
+	// link is marked unhealthy and its traffic goes to the fallback.
+	FailureThreshold int
+}
+
+type Checker struct {
+	storage          Storage
+	client           *http.Client
+	interval         time.Duration
+	concurrency      int
+	perHostDelay     time.Duration
+	failureThreshold int
+}
+
+func New(storage Storage, opts Options) *Checker {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.FailureThreshold < 1 {
+		opts.FailureThreshold = 1
+	}
+
+	return &Checker{
+		storage: storage,
+		client: safehttp.NewClient(safehttp.Options{
+			Timeout:      opts.Timeout,
+			MaxRedirects: opts.MaxRedirects,
+			AllowPrivate: opts.AllowPrivate,
+		}),
+		interval:         opts.Interval,
+		concurrency:      opts.Concurrency,
+		perHostDelay:     opts.PerHostDelay,
+		failureThreshold: opts.FailureThreshold,
+	}
+}
+
+// Run checks all destinations every interval until ctx is cancelled.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll runs a single round of checks. Destinations on different hosts
+// are checked concurrently, while requests to the same host are sent one by
+// one with PerHostDelay between them.
+func (c *Checker) CheckAll(ctx context.Context) {
+	urls, err := c.storage.ListUrls(ctx)
+	if err != nil {
+		zlog.Logger.Error().Msg("could not list urls for health check: " + err.Error())
+		return
+	}
+
+	byHost := make(map[string][]model.Url)
+	for _, u := range urls {
+		host := ""
+		if parsed, err := url.Parse(u.Url); err == nil {
+			host = parsed.Hostname()
+		}
+		byHost[host] = append(byHost[host], u)
+	}
+
+	semaphore := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	for _, hostUrls := range byHost {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case semaphore <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(hostUrls []model.Url) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			c.checkHost(ctx, hostUrls)
+		}(hostUrls)
+	}
+	wg.Wait()
+}
+
+func (c *Checker) checkHost(ctx context.Context, urls []model.Url) {
+	for i, u := range urls {
+		if i > 0 && c.perHostDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.perHostDelay):
+			}
+		}
+
+		health := c.Check(ctx, u)
+		if ctx.Err() != nil {
+			return
+		}
+		if err := c.storage.SaveUrlHealth(ctx, health); err != nil {
+			zlog.Logger.Error().Msg("could not save url health: " + err.Error())
+		}
+	}
+}
+
+// Check probes a single destination with HEAD, falling back to GET for
+// servers that do not support or refuse HEAD requests. A single failure
+// does not make a link unhealthy: u.HealthFailures carries the failures in
+// a row so far and the link turns unhealthy once they reach the threshold.
+// An unhealthy link stays so until a check succeeds.
+func (c *Checker) Check(ctx context.Context, u model.Url) model.UrlHealth {
+	health := model.UrlHealth{
+		ShortUrl:  u.ShortUrl,
+		Url:       u.Url,
+		CheckedAt: time.Now(),
+	}
+
+	start := time.Now()
+	statusCode, err := c.probe(ctx, http.MethodHead, u.Url)
+	if err == nil && headRefused(statusCode) {
+		statusCode, err = c.probe(ctx, http.MethodGet, u.Url)
+	}
+	health.LatencyMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		health.Error = err.Error()
+	}
+	health.StatusCode = statusCode
+	if err == nil && statusCode < http.StatusBadRequest {
+		health.Healthy = true
+		return health
+	}
+
+	health.Failures = u.HealthFailures + 1
+	health.Healthy = u.Healthy && health.Failures < c.failureThreshold
+	return health
+}
+
+// headRefused reports whether the status may only mean that the server
+// does not answer HEAD, some answer 403 to it while serving GET.
+func headRefused(statusCode int) bool {
+	return statusCode == http.StatusMethodNotAllowed ||
+		statusCode == http.StatusNotImplemented ||
+		statusCode == http.StatusForbidden
+}
+
+func (c *Checker) probe(ctx context.Context, method, rawUrl string) (int, error) {
+	target, err := url.Parse(rawUrl)
+	if err != nil {
+		return 0, err
+	}
+	if err := safehttp.CheckUrl(target); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+
+	return resp.StatusCode, nil
+}
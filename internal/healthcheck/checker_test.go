@@ -0,0 +1,166 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStorage struct {
+	mu     sync.Mutex
+	urls   []model.Url
+	health map[string]model.UrlHealth
+}
+
+func (s *memoryStorage) ListUrls(ctx context.Context) ([]model.Url, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urls := make([]model.Url, 0, len(s.urls))
+	for _, u := range s.urls {
+		u.Healthy = true
+		if health, ok := s.health[u.ShortUrl]; ok {
+			u.Healthy = health.Healthy
+			u.HealthFailures = health.Failures
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+func (s *memoryStorage) SaveUrlHealth(ctx context.Context, health model.UrlHealth) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health[health.ShortUrl] = health
+	return nil
+}
+
+func testOptions() Options {
+	return Options{
+		Interval:     time.Minute,
+		Timeout:      time.Second,
+		Concurrency:  2,
+		MaxRedirects: 3,
+		AllowPrivate: true,
+	}
+}
+
+func TestChecker_CheckAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/head-forbidden":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	storage := &memoryStorage{
+		urls: []model.Url{
+			{ShortUrl: "ok", Url: server.URL + "/ok"},
+			{ShortUrl: "nohead", Url: server.URL + "/no-head"},
+			{ShortUrl: "headforbidden", Url: server.URL + "/head-forbidden"},
+			{ShortUrl: "missing", Url: server.URL + "/missing"},
+		},
+		health: make(map[string]model.UrlHealth),
+	}
+
+	checker := New(storage, testOptions())
+	checker.CheckAll(context.Background())
+
+	require.Len(t, storage.health, 4)
+	assert.True(t, storage.health["ok"].Healthy)
+	assert.True(t, storage.health["nohead"].Healthy)
+	assert.Equal(t, http.StatusOK, storage.health["nohead"].StatusCode)
+	assert.True(t, storage.health["headforbidden"].Healthy)
+	assert.False(t, storage.health["missing"].Healthy)
+	assert.Equal(t, http.StatusNotFound, storage.health["missing"].StatusCode)
+}
+
+func TestChecker_PerHostDelay(t *testing.T) {
+	var mu sync.Mutex
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	storage := &memoryStorage{
+		urls: []model.Url{
+			{ShortUrl: "a", Url: server.URL + "/a"},
+			{ShortUrl: "b", Url: server.URL + "/b"},
+		},
+		health: make(map[string]model.UrlHealth),
+	}
+
+	opts := testOptions()
+	opts.PerHostDelay = 50 * time.Millisecond
+	checker := New(storage, opts)
+	checker.CheckAll(context.Background())
+
+	require.Len(t, requests, 2)
+	assert.GreaterOrEqual(t, requests[1].Sub(requests[0]), opts.PerHostDelay)
+}
+
+func TestChecker_Check_Unreachable(t *testing.T) {
+	checker := New(&memoryStorage{}, Options{Timeout: time.Second, MaxRedirects: 3})
+
+	health := checker.Check(context.Background(), model.Url{ShortUrl: "local", Url: "http://127.0.0.1:1/"})
+
+	assert.False(t, health.Healthy)
+	assert.NotEmpty(t, health.Error)
+}
+
+func TestChecker_FailureThreshold(t *testing.T) {
+	var mu sync.Mutex
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	storage := &memoryStorage{
+		urls:   []model.Url{{ShortUrl: "flaky", Url: server.URL}},
+		health: make(map[string]model.UrlHealth),
+	}
+	opts := testOptions()
+	opts.FailureThreshold = 3
+	checker := New(storage, opts)
+
+	for failures := 1; failures <= 4; failures++ {
+		checker.CheckAll(context.Background())
+		assert.Equal(t, failures, storage.health["flaky"].Failures)
+		assert.Equal(t, failures < opts.FailureThreshold, storage.health["flaky"].Healthy, "after %d failures", failures)
+	}
+
+	mu.Lock()
+	status = http.StatusOK
+	mu.Unlock()
+	checker.CheckAll(context.Background())
+	assert.True(t, storage.health["flaky"].Healthy)
+	assert.Zero(t, storage.health["flaky"].Failures)
+}
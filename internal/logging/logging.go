@@ -0,0 +1,134 @@
+// Package logging gives every request an id and a logger carrying it. The
+// logger travels in the request context, so service and repository code
+// log with the same request_id as the access log line of the request.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength bounds ids taken from clients, longer ones are
+	// replaced so that they cannot bloat every log line of the request.
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// FromContext returns the request-scoped logger stored by Middleware, or
+// the global logger outside of a request.
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return &zlog.Logger
+}
+
+// RequestID returns the id of the request ctx belongs to, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewContext stores the request id and a logger carrying it in ctx. An id
+// that is missing or unsafe to log is replaced with a generated one, the id
+// actually used is returned. Trace ids are logged when ctx has a span.
+func NewContext(ctx context.Context, id string) (context.Context, string) {
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+
+	loggerCtx := zlog.Logger.With().Str("request_id", id)
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		loggerCtx = loggerCtx.Str("trace_id", span.TraceID().String()).Str("span_id", span.SpanID().String())
+	}
+	logger := loggerCtx.Logger()
+
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return logger.WithContext(ctx), id
+}
+
+// Middleware takes the request id from X-Request-ID or generates one,
+// echoes it in the response and writes one access log line per request:
+// info for success, warn for client errors and error for server errors.
+// Successful requests to quietPaths, e.g. probes, are logged at debug. It
+// must run after the tracing middleware for trace ids to be logged.
+func Middleware(quietPaths ...string) ginext.HandlerFunc {
+	quiet := make(map[string]bool, len(quietPaths))
+	for _, path := range quietPaths {
+		quiet[path] = true
+	}
+
+	return func(c *ginext.Context) {
+		start := time.Now()
+
+		ctx, id := NewContext(c.Request.Context(), c.GetHeader(RequestIDHeader))
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(ctx)
+		logger := FromContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		var event *zerolog.Event
+		switch {
+		case status >= http.StatusInternalServerError:
+			event = logger.Error()
+		case status >= http.StatusBadRequest:
+			event = logger.Warn()
+		case quiet[c.Request.URL.Path]:
+			event = logger.Debug()
+		default:
+			event = logger.Info()
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		event = event.
+			Str("method", c.Request.Method).
+			Str("route", route).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Float64("latency_ms", float64(time.Since(start).Microseconds())/1000).
+			Int("bytes", max(c.Writer.Size(), 0)).
+			Str("client_ip", c.ClientIP()).
+			Str("user_agent", c.Request.UserAgent())
+		if len(c.Errors) > 0 {
+			event = event.Str("errors", c.Errors.String())
+		}
+		event.Msg("request")
+	}
+}
+
+// validRequestID accepts printable ASCII ids of a sane length, anything
+// else could forge log lines or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
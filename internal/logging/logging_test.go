@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// capture sends the global logger to a buffer for the duration of the test
+// and returns the decoded log lines.
+func capture(t *testing.T) func() []map[string]any {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := zlog.Logger
+	zlog.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { zlog.Logger = previous })
+
+	return func() []map[string]any {
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var fields map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &fields))
+			lines = append(lines, fields)
+		}
+		return lines
+	}
+}
+
+func newRouter() *ginext.Engine {
+	router := ginext.New()
+	router.Use(Middleware("/healthz"))
+	router.GET("/s/:short_url", func(c *ginext.Context) {
+		FromContext(c.Request.Context()).Error().Str("short_url", c.Param("short_url")).Msg("could not get short url")
+		c.JSON(http.StatusBadRequest, map[string]string{"error": "not found"})
+	})
+	router.GET("/healthz", func(c *ginext.Context) {
+		c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return router
+}
+
+func TestMiddleware_PropagatesRequestID(t *testing.T) {
+	lines := capture(t)
+	router := newRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/s/abc123?utm_source=mail", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	req.Header.Set("User-Agent", "curl/8.0")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-42", w.Header().Get(RequestIDHeader))
+
+	logged := lines()
+	require.Len(t, logged, 2)
+	assert.Equal(t, "could not get short url", logged[0]["message"])
+	assert.Equal(t, "req-42", logged[0]["request_id"])
+
+	access := logged[1]
+	assert.Equal(t, "request", access["message"])
+	assert.Equal(t, "warn", access["level"])
+	assert.Equal(t, "req-42", access["request_id"])
+	assert.Equal(t, "GET", access["method"])
+	assert.Equal(t, "/s/:short_url", access["route"])
+	assert.Equal(t, "/s/abc123", access["path"])
+	assert.Equal(t, float64(http.StatusBadRequest), access["status"])
+	assert.Equal(t, "curl/8.0", access["user_agent"])
+	assert.Contains(t, access, "latency_ms")
+	assert.Contains(t, access, "client_ip")
+	assert.Greater(t, access["bytes"], float64(0))
+}
+
+func TestMiddleware_GeneratesRequestID(t *testing.T) {
+	for name, header := range map[string]string{
+		"Missing":  "",
+		"TooLong":  strings.Repeat("a", maxRequestIDLength+1),
+		"Injected": "id\nlevel=error",
+	} {
+		t.Run(name, func(t *testing.T) {
+			lines := capture(t)
+			router := newRouter()
+
+			req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+			if header != "" {
+				req.Header[RequestIDHeader] = []string{header}
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			assert.Len(t, id, 32)
+			assert.NotEqual(t, header, id)
+			logged := lines()
+			require.Len(t, logged, 1)
+			assert.Equal(t, id, logged[0]["request_id"])
+			assert.Equal(t, "debug", logged[0]["level"])
+		})
+	}
+}
+
+func TestMiddleware_UnmatchedRoute(t *testing.T) {
+	lines := capture(t)
+
+	newRouter().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	logged := lines()
+	require.Len(t, logged, 1)
+	assert.Equal(t, "unmatched", logged[0]["route"])
+	assert.Equal(t, float64(http.StatusNotFound), logged[0]["status"])
+}
+
+func TestFromContext_OutsideRequest(t *testing.T) {
+	lines := capture(t)
+
+	FromContext(context.Background()).Info().Msg("background")
+
+	logged := lines()
+	require.Len(t, logged, 1)
+	assert.NotContains(t, logged[0], "request_id")
+	assert.Equal(t, "", RequestID(context.Background()))
+}
@@ -0,0 +1,232 @@
+// Package metadata fetches destination pages of short links and extracts
+// the data needed for link previews.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/safehttp"
+	"golang.org/x/net/html"
+)
+
+const (
+	maxFieldLength = 1024
+	userAgent      = "url-shortener-preview/1.0"
+)
+
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response status: %d", e.StatusCode)
+}
+
+type Options struct {
+	Workers      int
+	QueueSize    int
+	Timeout      time.Duration
+	MaxBodyBytes int64
+	MaxRedirects int
+	MaxAttempts  int
+	RetryDelay   time.Duration
+	AllowPrivate bool
+}
+
+type Fetcher struct {
+	client       *http.Client
+	maxBodyBytes int64
+}
+
+func NewFetcher(opts Options) *Fetcher {
+	return &Fetcher{
+		client: safehttp.NewClient(safehttp.Options{
+			Timeout:      opts.Timeout,
+			MaxRedirects: opts.MaxRedirects,
+			AllowPrivate: opts.AllowPrivate,
+		}),
+		maxBodyBytes: opts.MaxBodyBytes,
+	}
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, rawUrl string) (*model.UrlMetadata, error) {
+	target, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse url: %w", err)
+	}
+	if err := safehttp.CheckUrl(target); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch url: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	var metadata model.UrlMetadata
+	if !isHTML(resp.Header.Get("Content-Type")) {
+		return &metadata, nil
+	}
+
+	body := io.LimitReader(resp.Body, f.maxBodyBytes)
+	if err := parse(body, resp.Request.URL, &metadata); err != nil {
+		return nil, fmt.Errorf("could not parse page: %w", err)
+	}
+
+	return &metadata, nil
+}
+
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// parse reads tokens until the end of the document head and fills
+// metadata with the title, description, preview image and canonical url.
+func parse(body io.Reader, base *url.URL, metadata *model.UrlMetadata) error {
+	var ogTitle, ogDescription string
+
+	tokenizer := html.NewTokenizer(body)
+	inTitle := false
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				finish(metadata, ogTitle, ogDescription)
+				return nil
+			}
+			return tokenizer.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				inTitle = true
+			case "meta":
+				name := strings.ToLower(attr(token, "name"))
+				property := strings.ToLower(attr(token, "property"))
+				content := attr(token, "content")
+				switch {
+				case name == "description" && metadata.Description == "":
+					metadata.Description = content
+				case property == "og:description":
+					ogDescription = content
+				case property == "og:title":
+					ogTitle = content
+				case property == "og:image" && metadata.Image == "":
+					metadata.Image = resolve(base, content)
+				}
+			case "link":
+				if hasRel(attr(token, "rel"), "canonical") && metadata.CanonicalUrl == "" {
+					metadata.CanonicalUrl = resolve(base, attr(token, "href"))
+				}
+			case "body":
+				finish(metadata, ogTitle, ogDescription)
+				return nil
+			}
+		case html.TextToken:
+			if inTitle && metadata.Title == "" {
+				metadata.Title = string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				finish(metadata, ogTitle, ogDescription)
+				return nil
+			}
+		}
+	}
+}
+
+func finish(metadata *model.UrlMetadata, ogTitle, ogDescription string) {
+	if metadata.Title == "" {
+		metadata.Title = ogTitle
+	}
+	if metadata.Description == "" {
+		metadata.Description = ogDescription
+	}
+
+	metadata.Title = clean(metadata.Title)
+	metadata.Description = clean(metadata.Description)
+	metadata.Image = truncate(metadata.Image)
+	metadata.CanonicalUrl = truncate(metadata.CanonicalUrl)
+}
+
+func attr(token html.Token, key string) string {
+	for _, a := range token.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func hasRel(rel, value string) bool {
+	for _, r := range strings.Fields(rel) {
+		if strings.EqualFold(r, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	return u.String()
+}
+
+func clean(s string) string {
+	return truncate(strings.Join(strings.Fields(s), " "))
+}
+
+func truncate(s string) string {
+	if len(s) <= maxFieldLength {
+		return s
+	}
+
+	s = s[:maxFieldLength]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
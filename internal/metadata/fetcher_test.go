@@ -0,0 +1,217 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/safehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>  Example
+	Page </title>
+	<meta name="description" content="Example description">
+	<meta property="og:image" content="/images/preview.png">
+	<link rel="canonical" href="https://example.com/page">
+</head>
+<body><h1>Hello</h1></body>
+</html>`
+
+func testOptions() Options {
+	return Options{
+		Workers:      1,
+		QueueSize:    1,
+		Timeout:      time.Second,
+		MaxBodyBytes: 1 << 20,
+		MaxRedirects: 3,
+		MaxAttempts:  3,
+		RetryDelay:   time.Millisecond,
+		AllowPrivate: true,
+	}
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(testOptions())
+	metadata, err := fetcher.Fetch(context.Background(), server.URL)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Example Page", metadata.Title)
+	assert.Equal(t, "Example description", metadata.Description)
+	assert.Equal(t, server.URL+"/images/preview.png", metadata.Image)
+	assert.Equal(t, "https://example.com/page", metadata.CanonicalUrl)
+}
+
+func TestFetcher_Fetch_OpenGraphFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+			<meta property="og:title" content="OG title">
+			<meta property="og:description" content="OG description">
+		</head></html>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(testOptions())
+	metadata, err := fetcher.Fetch(context.Background(), server.URL)
+
+	require.NoError(t, err)
+	assert.Equal(t, "OG title", metadata.Title)
+	assert.Equal(t, "OG description", metadata.Description)
+}
+
+func TestFetcher_Fetch_SizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><!--" + strings.Repeat("x", 4096) + "--><title>Too far</title></head></html>"))
+	}))
+	defer server.Close()
+
+	opts := testOptions()
+	opts.MaxBodyBytes = 1024
+	fetcher := NewFetcher(opts)
+	metadata, err := fetcher.Fetch(context.Background(), server.URL)
+
+	require.NoError(t, err)
+	assert.Empty(t, metadata.Title)
+}
+
+func TestFetcher_Fetch_PrivateAddressRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	opts := testOptions()
+	opts.AllowPrivate = false
+	fetcher := NewFetcher(opts)
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+
+	assert.True(t, errors.Is(err, safehttp.ErrForbiddenAddress))
+}
+
+func TestFetcher_Fetch_SchemeRejected(t *testing.T) {
+	fetcher := NewFetcher(testOptions())
+	_, err := fetcher.Fetch(context.Background(), "file:///etc/passwd")
+
+	assert.True(t, errors.Is(err, safehttp.ErrForbiddenScheme))
+}
+
+type memoryStorage struct {
+	saved chan model.UrlMetadata
+}
+
+func (s *memoryStorage) SaveUrlMetadata(ctx context.Context, metadata model.UrlMetadata) error {
+	s.saved <- metadata
+	return nil
+}
+
+func TestWorker_RetriesServerErrors(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := &memoryStorage{saved: make(chan model.UrlMetadata, 1)}
+	opts := testOptions()
+	worker := NewWorker(NewFetcher(opts), storage, opts)
+	worker.Start(ctx)
+	worker.Enqueue(model.Url{ShortUrl: "abc123", Url: server.URL})
+
+	select {
+	case metadata := <-storage.saved:
+		assert.Equal(t, "abc123", metadata.ShortUrl)
+		assert.Equal(t, model.MetadataStatusOk, metadata.Status)
+		assert.Equal(t, 3, metadata.Attempts)
+		assert.Equal(t, "Example Page", metadata.Title)
+	case <-time.After(5 * time.Second):
+		t.Fatal("metadata was not saved")
+	}
+}
+
+func TestWorker_DoesNotRetryClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := &memoryStorage{saved: make(chan model.UrlMetadata, 1)}
+	opts := testOptions()
+	worker := NewWorker(NewFetcher(opts), storage, opts)
+	worker.Start(ctx)
+	worker.Enqueue(model.Url{ShortUrl: "abc123", Url: server.URL})
+
+	select {
+	case metadata := <-storage.saved:
+		assert.Equal(t, model.MetadataStatusFailed, metadata.Status)
+		assert.Equal(t, 1, metadata.Attempts)
+		assert.NotEmpty(t, metadata.Error)
+	case <-time.After(5 * time.Second):
+		t.Fatal("metadata was not saved")
+	}
+}
+
+func TestWorker_CloseDrainsQueue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	storage := &memoryStorage{saved: make(chan model.UrlMetadata, 2)}
+	opts := testOptions()
+	opts.QueueSize = 2
+	worker := NewWorker(NewFetcher(opts), storage, opts)
+	worker.Enqueue(model.Url{ShortUrl: "abc123", Url: server.URL})
+	worker.Enqueue(model.Url{ShortUrl: "xyz789", Url: server.URL})
+
+	worker.Start(context.Background())
+	worker.Close()
+	worker.Close()
+	worker.Enqueue(model.Url{ShortUrl: "late", Url: server.URL})
+
+	done := make(chan struct{})
+	go func() {
+		worker.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("workers did not stop after Close")
+	}
+
+	require.Len(t, storage.saved, 2)
+	assert.Equal(t, "abc123", (<-storage.saved).ShortUrl)
+	assert.Equal(t, "xyz789", (<-storage.saved).ShortUrl)
+}
@@ -0,0 +1,185 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/safehttp"
+	"github.com/wb-go/wbf/zlog"
+)
+
+type Storage interface {
+	SaveUrlMetadata(context.Context, model.UrlMetadata) error
+}
+
+// Metrics counts the jobs the worker had to drop, by reason: queue_full or
+// closed. A dropped job leaves its link without a preview for good.
+type Metrics interface {
+	MetadataJobDropped(reason string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) MetadataJobDropped(string) {}
+
+// Worker fetches metadata in the background so that link creation never
+// waits for the destination server.
+type Worker struct {
+	fetcher     *Fetcher
+	storage     Storage
+	jobs        chan model.Url
+	workers     int
+	maxAttempts int
+	retryDelay  time.Duration
+	metrics     Metrics
+	wg          sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewWorker(fetcher *Fetcher, storage Storage, opts Options) *Worker {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+
+	return &Worker{
+		fetcher:     fetcher,
+		storage:     storage,
+		jobs:        make(chan model.Url, opts.QueueSize),
+		workers:     opts.Workers,
+		maxAttempts: opts.MaxAttempts,
+		retryDelay:  opts.RetryDelay,
+		metrics:     nopMetrics{},
+	}
+}
+
+// SetMetrics makes the worker report dropped jobs to metrics instead of
+// nowhere. It must be called before the worker is used.
+func (w *Worker) SetMetrics(metrics Metrics) {
+	w.metrics = metrics
+}
+
+// Enqueue schedules metadata fetching for url. It never blocks: when the
+// queue is full or the worker is closed the job is dropped, logged and
+// counted, nothing retries it later.
+func (w *Worker) Enqueue(url model.Url) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		w.drop(url, "closed")
+		return
+	}
+
+	select {
+	case w.jobs <- url:
+	default:
+		w.drop(url, "queue_full")
+	}
+}
+
+func (w *Worker) drop(url model.Url, reason string) {
+	w.metrics.MetadataJobDropped(reason)
+	zlog.Logger.Warn().Str("short_url", url.ShortUrl).Str("reason", reason).Msg("metadata job dropped")
+}
+
+// Start runs the workers until ctx is cancelled or, after Close, until the
+// queue is drained.
+func (w *Worker) Start(ctx context.Context) {
+	for i := 0; i < w.workers; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case url, ok := <-w.jobs:
+					if !ok {
+						return
+					}
+					w.process(ctx, url)
+				}
+			}
+		}()
+	}
+}
+
+// Close stops accepting new jobs. Queued jobs are still processed, Wait
+// returns once they are done.
+func (w *Worker) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.closed {
+		w.closed = true
+		close(w.jobs)
+	}
+}
+
+// Wait blocks until all workers have stopped.
+func (w *Worker) Wait() {
+	w.wg.Wait()
+}
+
+func (w *Worker) process(ctx context.Context, url model.Url) {
+	var (
+		metadata *model.UrlMetadata
+		err      error
+		attempts int
+	)
+
+	delay := w.retryDelay
+	for attempts < w.maxAttempts {
+		attempts++
+		metadata, err = w.fetcher.Fetch(ctx, url.Url)
+		if err == nil || !isRetryable(err) || attempts == w.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	if err != nil {
+		metadata = &model.UrlMetadata{
+			Status: model.MetadataStatusFailed,
+			Error:  err.Error(),
+		}
+	} else {
+		metadata.Status = model.MetadataStatusOk
+	}
+	metadata.ShortUrl = url.ShortUrl
+	metadata.Attempts = attempts
+	metadata.FetchedAt = time.Now()
+
+	if err := w.storage.SaveUrlMetadata(ctx, *metadata); err != nil {
+		zlog.Logger.Error().Msg("could not save url metadata: " + err.Error())
+	}
+}
+
+func isRetryable(err error) bool {
+	if errors.Is(err, safehttp.ErrForbiddenAddress) ||
+		errors.Is(err, safehttp.ErrForbiddenScheme) ||
+		errors.Is(err, safehttp.ErrTooManyRedirects) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError ||
+			statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	return true
+}
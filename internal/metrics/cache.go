@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type cache struct {
+	service.Cache
+	lookups *prometheus.CounterVec
+}
+
+// Cache counts the lookups of c. A redis.Nil answer is a miss, any other
+// error is reported separately so that an unavailable cache does not look
+// like a cold one.
+func (m *Metrics) Cache(c service.Cache) service.Cache {
+	return &cache{Cache: c, lookups: m.cacheLookups}
+}
+
+func (c *cache) Get(ctx context.Context, key string) (string, error) {
+	value, err := c.Cache.Get(ctx, key)
+	switch {
+	case err == nil:
+		c.lookups.WithLabelValues("hit").Inc()
+	case err == redis.Nil:
+		c.lookups.WithLabelValues("miss").Inc()
+	default:
+		c.lookups.WithLabelValues("error").Inc()
+	}
+	return value, err
+}
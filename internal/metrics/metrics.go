@@ -0,0 +1,122 @@
+// Package metrics exposes the state of the service in the Prometheus text
+// format: HTTP requests per route, redirects, cache hits and misses,
+// storage latency per method, short_url collisions, dropped metadata
+// fetches and the Go runtime.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/wb-go/wbf/ginext"
+)
+
+const namespace = "url_shortener"
+
+// Metrics owns its registry, so that several instances, e.g. in tests, do
+// not collide on the global one.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	redirects       *prometheus.CounterVec
+	cacheLookups    *prometheus.CounterVec
+	queryDuration   *prometheus.HistogramVec
+	collisions      prometheus.Counter
+	metadataDropped *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Successful redirects by click source and by what chose the destination.",
+		}, []string{"source", "target"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Cache lookups by result: hit, miss or error.",
+		}, []string{"result"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Storage call latency by repository method and result: ok or error.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method", "result"}),
+		collisions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "short_url_collisions_total",
+			Help:      "Generated short_urls that were already taken and had to be generated again.",
+		}),
+		metadataDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "metadata_jobs_dropped_total",
+			Help:      "Metadata fetches that were dropped by reason: queue_full or closed.",
+		}, []string{"reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.redirects,
+		m.cacheLookups,
+		m.queryDuration,
+		m.collisions,
+		m.metadataDropped,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware counts requests and their latency. Routes are labelled by
+// their pattern, e.g. /s/:short_url, so that every short link does not get
+// a series of its own; requests that match no route are "unmatched".
+func (m *Metrics) Middleware() ginext.HandlerFunc {
+	return func(c *ginext.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.requestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *Metrics) Redirect(source, target string) {
+	m.redirects.WithLabelValues(source, target).Inc()
+}
+
+func (m *Metrics) ShortUrlCollision() {
+	m.collisions.Inc()
+}
+
+func (m *Metrics) MetadataJobDropped(reason string) {
+	m.metadataDropped.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	memorycache "github.com/Komilov31/url-shortener/internal/cache/memory"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+	memoryrepo "github.com/Komilov31/url-shortener/internal/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/ginext"
+)
+
+// scrape returns the metrics in the text format.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	m := New()
+	router := ginext.New()
+	router.Use(m.Middleware())
+	router.GET("/s/:short_url", func(c *ginext.Context) {
+		c.Redirect(http.StatusMovedPermanently, "https://example.com")
+	})
+
+	for _, path := range []string{"/s/abc123", "/s/xyz789", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `url_shortener_http_requests_total{method="GET",route="/s/:short_url",status="301"} 2`)
+	assert.Contains(t, body, `url_shortener_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `url_shortener_http_request_duration_seconds_count{method="GET",route="/s/:short_url",status="301"} 2`)
+	assert.NotContains(t, body, "abc123")
+}
+
+func TestMetrics_Service(t *testing.T) {
+	m := New()
+
+	m.Redirect(model.SourceQr, "cache")
+	m.Redirect(model.SourceDirect, "variant")
+	m.ShortUrlCollision()
+	m.MetadataJobDropped("queue_full")
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `url_shortener_redirects_total{source="qr",target="cache"} 1`)
+	assert.Contains(t, body, `url_shortener_redirects_total{source="direct",target="variant"} 1`)
+	assert.Contains(t, body, "url_shortener_short_url_collisions_total 1")
+	assert.Contains(t, body, `url_shortener_metadata_jobs_dropped_total{reason="queue_full"} 1`)
+}
+
+func TestMetrics_Cache(t *testing.T) {
+	m := New()
+	cache := m.Cache(memorycache.New())
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "abc123", "https://example.com"))
+	_, err := cache.Get(ctx, "abc123")
+	require.NoError(t, err)
+	_, _ = cache.Get(ctx, "missing")
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _ = cache.Get(cancelled, "abc123")
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `url_shortener_cache_lookups_total{result="hit"} 1`)
+	assert.Contains(t, body, `url_shortener_cache_lookups_total{result="miss"} 1`)
+	assert.Contains(t, body, `url_shortener_cache_lookups_total{result="error"} 1`)
+}
+
+func TestMetrics_Storage(t *testing.T) {
+	m := New()
+	storage := m.Storage(memoryrepo.New())
+	ctx := context.Background()
+
+	_, err := storage.CreateShortUrl(ctx, model.Url{Url: "https://example.com", ShortUrl: "abc123"})
+	require.NoError(t, err)
+	_, err = storage.DeleteLink(ctx, "missing")
+	require.ErrorIs(t, err, repository.ErrAliasNotFound)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `url_shortener_db_query_duration_seconds_count{method="CreateShortUrl",result="ok"} 1`)
+	assert.Contains(t, body, `url_shortener_db_query_duration_seconds_count{method="DeleteLink",result="error"} 1`)
+}
+
+func TestMetrics_Runtime(t *testing.T) {
+	body := scrape(t, New())
+
+	assert.Contains(t, body, "go_goroutines")
+	assert.Contains(t, body, "go_memstats_alloc_bytes")
+}
@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/healthcheck"
+	"github.com/Komilov31/url-shortener/internal/metadata"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Storage is every storage call made while serving requests and by the
+// background workers.
+type Storage interface {
+	service.Storage
+	metadata.Storage
+	healthcheck.Storage
+}
+
+type storage struct {
+	storage  Storage
+	duration *prometheus.HistogramVec
+}
+
+// Storage times every call to s by method name. Any error, including a
+// missing link, is counted with the "error" result.
+func (m *Metrics) Storage(s Storage) Storage {
+	return &storage{storage: s, duration: m.queryDuration}
+}
+
+func (s *storage) observe(method string, start time.Time, err *error) {
+	result := "ok"
+	if *err != nil {
+		result = "error"
+	}
+	s.duration.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+}
+
+func (s *storage) CreateShortUrl(ctx context.Context, url model.Url) (_ *model.Url, err error) {
+	defer s.observe("CreateShortUrl", time.Now(), &err)
+	return s.storage.CreateShortUrl(ctx, url)
+}
+
+func (s *storage) CreateRedirectInfo(ctx context.Context, info model.RedirectInfo) (err error) {
+	defer s.observe("CreateRedirectInfo", time.Now(), &err)
+	return s.storage.CreateRedirectInfo(ctx, info)
+}
+
+func (s *storage) GetUrlByShort(ctx context.Context, shortUrl string, info model.RedirectInfo) (_ *model.Url, err error) {
+	defer s.observe("GetUrlByShort", time.Now(), &err)
+	return s.storage.GetUrlByShort(ctx, shortUrl, info)
+}
+
+func (s *storage) GetAnalytics(ctx context.Context, shortUrl string) (_ []dto.RedirectInfo, err error) {
+	defer s.observe("GetAnalytics", time.Now(), &err)
+	return s.storage.GetAnalytics(ctx, shortUrl)
+}
+
+func (s *storage) AggregateByUserAgent(ctx context.Context, filter dto.LinkFilter) (_ []dto.UserAgentDTO, err error) {
+	defer s.observe("AggregateByUserAgent", time.Now(), &err)
+	return s.storage.AggregateByUserAgent(ctx, filter)
+}
+
+func (s *storage) AggregateByDate(ctx context.Context, filter dto.LinkFilter) (_ []dto.DateDTO, err error) {
+	defer s.observe("AggregateByDate", time.Now(), &err)
+	return s.storage.AggregateByDate(ctx, filter)
+}
+
+func (s *storage) AggregateByMonth(ctx context.Context, filter dto.LinkFilter) (_ []dto.MonthDTO, err error) {
+	defer s.observe("AggregateByMonth", time.Now(), &err)
+	return s.storage.AggregateByMonth(ctx, filter)
+}
+
+func (s *storage) AggregateByCampaign(ctx context.Context, period string, filter dto.LinkFilter) (_ []dto.CampaignDTO, err error) {
+	defer s.observe("AggregateByCampaign", time.Now(), &err)
+	return s.storage.AggregateByCampaign(ctx, period, filter)
+}
+
+func (s *storage) AggregateByReferrer(ctx context.Context, filter dto.LinkFilter) (_ []dto.ReferrerDTO, err error) {
+	defer s.observe("AggregateByReferrer", time.Now(), &err)
+	return s.storage.AggregateByReferrer(ctx, filter)
+}
+
+func (s *storage) AggregateByTag(ctx context.Context, filter dto.LinkFilter) (_ []dto.TagDTO, err error) {
+	defer s.observe("AggregateByTag", time.Now(), &err)
+	return s.storage.AggregateByTag(ctx, filter)
+}
+
+func (s *storage) GetUrlMetadata(ctx context.Context, shortUrl string) (_ *model.UrlMetadata, err error) {
+	defer s.observe("GetUrlMetadata", time.Now(), &err)
+	return s.storage.GetUrlMetadata(ctx, shortUrl)
+}
+
+func (s *storage) GetUnhealthyUrls(ctx context.Context) (_ []model.UrlHealth, err error) {
+	defer s.observe("GetUnhealthyUrls", time.Now(), &err)
+	return s.storage.GetUnhealthyUrls(ctx)
+}
+
+func (s *storage) SetTargetingRules(ctx context.Context, shortUrl string, rules []model.TargetingRule) (_ []model.TargetingRule, err error) {
+	defer s.observe("SetTargetingRules", time.Now(), &err)
+	return s.storage.SetTargetingRules(ctx, shortUrl, rules)
+}
+
+func (s *storage) GetTargetingRules(ctx context.Context, shortUrl string) (_ []model.TargetingRule, err error) {
+	defer s.observe("GetTargetingRules", time.Now(), &err)
+	return s.storage.GetTargetingRules(ctx, shortUrl)
+}
+
+func (s *storage) SetVariants(ctx context.Context, variants dto.VariantsDTO) (_ *dto.VariantsDTO, err error) {
+	defer s.observe("SetVariants", time.Now(), &err)
+	return s.storage.SetVariants(ctx, variants)
+}
+
+func (s *storage) GetVariants(ctx context.Context, shortUrl string) (_ *dto.VariantsDTO, err error) {
+	defer s.observe("GetVariants", time.Now(), &err)
+	return s.storage.GetVariants(ctx, shortUrl)
+}
+
+func (s *storage) SearchLinks(ctx context.Context, query dto.LinkQuery) (_ []dto.LinkDTO, err error) {
+	defer s.observe("SearchLinks", time.Now(), &err)
+	return s.storage.SearchLinks(ctx, query)
+}
+
+func (s *storage) GetLink(ctx context.Context, shortUrl string) (_ *dto.LinkDTO, err error) {
+	defer s.observe("GetLink", time.Now(), &err)
+	return s.storage.GetLink(ctx, shortUrl)
+}
+
+func (s *storage) SetDisabled(ctx context.Context, shortUrl string, disabled bool) (_ *dto.LinkDTO, err error) {
+	defer s.observe("SetDisabled", time.Now(), &err)
+	return s.storage.SetDisabled(ctx, shortUrl, disabled)
+}
+
+func (s *storage) SetTags(ctx context.Context, shortUrl string, tags []string) (_ *dto.LinkDTO, err error) {
+	defer s.observe("SetTags", time.Now(), &err)
+	return s.storage.SetTags(ctx, shortUrl, tags)
+}
+
+func (s *storage) SetFolder(ctx context.Context, shortUrl string, folder string) (_ *dto.LinkDTO, err error) {
+	defer s.observe("SetFolder", time.Now(), &err)
+	return s.storage.SetFolder(ctx, shortUrl, folder)
+}
+
+func (s *storage) DeleteLink(ctx context.Context, shortUrl string) (_ *model.Url, err error) {
+	defer s.observe("DeleteLink", time.Now(), &err)
+	return s.storage.DeleteLink(ctx, shortUrl)
+}
+
+func (s *storage) PurgeAnalytics(ctx context.Context, shortUrl string, before time.Time) (_ int, err error) {
+	defer s.observe("PurgeAnalytics", time.Now(), &err)
+	return s.storage.PurgeAnalytics(ctx, shortUrl, before)
+}
+
+func (s *storage) CreateApiKey(ctx context.Context, key model.ApiKey) (_ *model.ApiKey, err error) {
+	defer s.observe("CreateApiKey", time.Now(), &err)
+	return s.storage.CreateApiKey(ctx, key)
+}
+
+func (s *storage) ListApiKeys(ctx context.Context) (_ []model.ApiKey, err error) {
+	defer s.observe("ListApiKeys", time.Now(), &err)
+	return s.storage.ListApiKeys(ctx)
+}
+
+func (s *storage) GetApiKeyByHash(ctx context.Context, keyHash string) (_ *model.ApiKey, err error) {
+	defer s.observe("GetApiKeyByHash", time.Now(), &err)
+	return s.storage.GetApiKeyByHash(ctx, keyHash)
+}
+
+func (s *storage) RevokeApiKey(ctx context.Context, id int) (_ *model.ApiKey, err error) {
+	defer s.observe("RevokeApiKey", time.Now(), &err)
+	return s.storage.RevokeApiKey(ctx, id)
+}
+
+func (s *storage) ClaimIdempotencyKey(ctx context.Context, key model.IdempotencyKey, ttl time.Duration) (_ *model.IdempotencyKey, _ bool, err error) {
+	defer s.observe("ClaimIdempotencyKey", time.Now(), &err)
+	return s.storage.ClaimIdempotencyKey(ctx, key, ttl)
+}
+
+func (s *storage) CompleteIdempotencyKey(ctx context.Context, key, token, response string, ttl time.Duration) (err error) {
+	defer s.observe("CompleteIdempotencyKey", time.Now(), &err)
+	return s.storage.CompleteIdempotencyKey(ctx, key, token, response, ttl)
+}
+
+func (s *storage) ReleaseIdempotencyKey(ctx context.Context, key, token string) (err error) {
+	defer s.observe("ReleaseIdempotencyKey", time.Now(), &err)
+	return s.storage.ReleaseIdempotencyKey(ctx, key, token)
+}
+
+func (s *storage) PurgeIdempotencyKeys(ctx context.Context) (_ int, err error) {
+	defer s.observe("PurgeIdempotencyKeys", time.Now(), &err)
+	return s.storage.PurgeIdempotencyKeys(ctx)
+}
+
+func (s *storage) SaveUrlMetadata(ctx context.Context, metadata model.UrlMetadata) (err error) {
+	defer s.observe("SaveUrlMetadata", time.Now(), &err)
+	return s.storage.SaveUrlMetadata(ctx, metadata)
+}
+
+func (s *storage) ListUrls(ctx context.Context) (_ []model.Url, err error) {
+	defer s.observe("ListUrls", time.Now(), &err)
+	return s.storage.ListUrls(ctx)
+}
+
+func (s *storage) SaveUrlHealth(ctx context.Context, health model.UrlHealth) (err error) {
+	defer s.observe("SaveUrlHealth", time.Now(), &err)
+	return s.storage.SaveUrlHealth(ctx, health)
+}
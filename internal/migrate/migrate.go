@@ -0,0 +1,94 @@
+// Package migrate applies the embedded schema migrations and checks that
+// the database schema is what the code expects.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/Komilov31/url-shortener/migrations"
+	"github.com/pressly/goose/v3"
+)
+
+var ErrSchemaOutdated = errors.New("database schema is older than the application expects")
+
+type Migrator struct {
+	provider *goose.Provider
+}
+
+func New(dialect goose.Dialect, db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	provider, err := goose.NewProvider(dialect, db, fsys)
+	if err != nil {
+		return nil, fmt.Errorf("could not load migrations: %w", err)
+	}
+
+	return &Migrator{
+		provider: provider,
+	}, nil
+}
+
+// Postgres returns a migrator for the migrations in migrations/.
+func Postgres(db *sql.DB) (*Migrator, error) {
+	return New(goose.DialectPostgres, db, migrations.Postgres())
+}
+
+// Sqlite returns a migrator for the migrations in migrations/sqlite.
+func Sqlite(db *sql.DB) (*Migrator, error) {
+	return New(goose.DialectSQLite3, db, migrations.Sqlite())
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	results, err := m.provider.Up(ctx)
+	if err != nil {
+		return results, fmt.Errorf("could not apply migrations: %w", err)
+	}
+	return results, nil
+}
+
+// Down rolls back the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	result, err := m.provider.Down(ctx)
+	if err != nil {
+		return result, fmt.Errorf("could not roll back migration: %w", err)
+	}
+	return result, nil
+}
+
+// Redo rolls back the latest applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	up, err := m.provider.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down, up}, fmt.Errorf("could not apply migration: %w", err)
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	status, err := m.provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get migrations status: %w", err)
+	}
+	return status, nil
+}
+
+// Check returns ErrSchemaOutdated if the database has not been migrated
+// to the latest embedded migration.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, target, err := m.provider.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get schema version: %w", err)
+	}
+	if current < target {
+		return fmt.Errorf("%w: schema version is %d, expected %d", ErrSchemaOutdated, current, target)
+	}
+	return nil
+}
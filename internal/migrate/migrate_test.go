@@ -0,0 +1,104 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/Komilov31/url-shortener/internal/repository/sqlite"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMigrator(t *testing.T) (*Migrator, *sql.DB) {
+	t.Helper()
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "url_shortener.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := Sqlite(db)
+	require.NoError(t, err)
+
+	return migrator, db
+}
+
+func TestMigrator_Check(t *testing.T) {
+	migrator, _ := newMigrator(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, migrator.Check(ctx), ErrSchemaOutdated)
+
+	results, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, results)
+	assert.NoError(t, migrator.Check(ctx))
+
+	results, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestMigrator_DownAndRedo(t *testing.T) {
+	migrator, db := newMigrator(t)
+	ctx := context.Background()
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+
+	results, err := migrator.Redo(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, results[0].Source.Version, results[1].Source.Version)
+	assert.NoError(t, migrator.Check(ctx))
+
+	_, err = migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.ErrorIs(t, migrator.Check(ctx), ErrSchemaOutdated)
+
+	// Down rolls back only the latest migration.
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	last := len(status) - 1
+	assert.Equal(t, goose.StatePending, status[last].State)
+	for _, migration := range status[:last] {
+		assert.Equal(t, goose.StateApplied, migration.State)
+	}
+
+	var tables int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'idempotency_keys'").Scan(&tables))
+	assert.Zero(t, tables)
+}
+
+func TestMigrator_Status(t *testing.T) {
+	migrator, _ := newMigrator(t)
+	ctx := context.Background()
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, status)
+	assert.Equal(t, goose.StatePending, status[0].State)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	status, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, migration := range status {
+		assert.Equal(t, goose.StateApplied, migration.State)
+		assert.False(t, migration.AppliedAt.IsZero())
+	}
+}
+
+func TestPostgres_LoadsMigrations(t *testing.T) {
+	// sql.Open does not connect, the provider only parses the files.
+	db, err := sql.Open("postgres", "host=localhost")
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrator, err := Postgres(db)
+	require.NoError(t, err)
+	assert.NotEmpty(t, migrator.provider.ListSources())
+}
@@ -1,16 +1,138 @@
 package model
 
-import "time"
+import (
+	"net/url"
+	"time"
+)
+
+const (
+	MetadataStatusOk     = "ok"
+	MetadataStatusFailed = "failed"
+
+	SourceDirect = "direct"
+	SourceQr     = "qr"
+
+	LinkStatusActive   = "active"
+	LinkStatusExpired  = "expired"
+	LinkStatusDisabled = "disabled"
+
+	// QueryPolicyNone drops the query string of the short link request,
+	// QueryPolicyKeep merges it keeping destination values on conflict and
+	// QueryPolicyOverride merges it replacing destination values.
+	QueryPolicyNone     = "none"
+	QueryPolicyKeep     = "keep"
+	QueryPolicyOverride = "override"
+)
 
 type Url struct {
-	Id       int    `json:"-"`
-	Url      string `json:"url,omitempty"`
-	ShortUrl string `json:"short_url"`
+	Id             int             `json:"-"`
+	Url            string          `json:"url,omitempty"`
+	ShortUrl       string          `json:"short_url"`
+	FallbackUrl    string          `json:"fallback_url,omitempty"`
+	Rules          []TargetingRule `json:"rules,omitempty"`
+	Variants       []Variant       `json:"variants,omitempty"`
+	StickyVariants bool            `json:"sticky_variants,omitempty"`
+	QueryPolicy    string          `json:"query_policy,omitempty"`
+	Folder         string          `json:"folder,omitempty"`
+	Tags           []string        `json:"tags,omitempty"`
+	Owner          string          `json:"owner,omitempty"`
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	Disabled       bool            `json:"disabled,omitempty"`
+	Utm            Utm             `json:"utm"`
+	Variant        string          `json:"-"`
+	Healthy        bool            `json:"-"`
+	// HealthFailures counts the failed health checks in a row.
+	HealthFailures int `json:"-"`
+}
+
+type Utm struct {
+	Source   string `json:"utm_source,omitempty"`
+	Medium   string `json:"utm_medium,omitempty"`
+	Campaign string `json:"utm_campaign,omitempty"`
+	Term     string `json:"utm_term,omitempty"`
+	Content  string `json:"utm_content,omitempty"`
+}
+
+type Variant struct {
+	Id     int    `json:"id,omitempty"`
+	Name   string `json:"name"`
+	Url    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+type TargetingRule struct {
+	Id          int    `json:"id,omitempty"`
+	Position    int    `json:"position"`
+	Os          string `json:"os,omitempty"`
+	Device      string `json:"device,omitempty"`
+	Browser     string `json:"browser,omitempty"`
+	Language    string `json:"language,omitempty"`
+	Country     string `json:"country,omitempty"`
+	Destination string `json:"destination"`
 }
 
 type RedirectInfo struct {
-	Id          int       `json:"-"`
-	ShortUrl    string    `json:"short_url"`
-	RequestTime time.Time `json:"request_time"`
-	UserAgent   string    `json:"user_agent"`
+	Id             int        `json:"-"`
+	ShortUrl       string     `json:"short_url"`
+	RequestTime    time.Time  `json:"request_time"`
+	UserAgent      string     `json:"user_agent"`
+	Source         string     `json:"source"`
+	Referrer       string     `json:"referrer,omitempty"`
+	ReferrerDomain string     `json:"referrer_domain,omitempty"`
+	Languages      []string   `json:"-"`
+	Country        string     `json:"-"`
+	MatchedRule    int        `json:"matched_rule,omitempty"`
+	Variant        string     `json:"variant,omitempty"`
+	ClientIp       string     `json:"-"`
+	VisitorId      string     `json:"-"`
+	Query          url.Values `json:"-"`
+	Utm            Utm        `json:"utm"`
+}
+
+type UrlMetadata struct {
+	ShortUrl     string    `json:"short_url"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Image        string    `json:"image"`
+	CanonicalUrl string    `json:"canonical_url"`
+	Status       string    `json:"status"`
+	Attempts     int       `json:"attempts"`
+	Error        string    `json:"error,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+type UrlHealth struct {
+	ShortUrl   string    `json:"short_url"`
+	Url        string    `json:"url"`
+	StatusCode int       `json:"status_code"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Healthy    bool      `json:"healthy"`
+	Failures   int       `json:"failures"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// ApiKey is an issued API key. Only the SHA-256 hash of the key is stored,
+// Prefix is the beginning of the key to tell keys apart.
+type ApiKey struct {
+	Id        int        `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	KeyHash   string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IdempotencyKey remembers the result of a request sent with an
+// Idempotency-Key header. Response is empty while the request is being
+// processed. RequestHash tells a retry from another request reusing the
+// key. Token is picked by the request that claimed the key, only that
+// request can complete or release it.
+type IdempotencyKey struct {
+	Key         string
+	RequestHash string
+	Token       string
+	Response    string
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
 }
@@ -0,0 +1,132 @@
+// Package probe answers liveness and readiness checks. Liveness only says
+// that the process serves HTTP, readiness pings every dependency and turns
+// negative as soon as graceful shutdown starts.
+package probe
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	StatusReady        = "ready"
+	StatusDegraded     = "degraded"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+
+	DependencyUp   = "up"
+	DependencyDown = "down"
+)
+
+// Dependency is something the service talks to. When a critical dependency
+// is down the service is not ready, when any other one is down it still
+// serves requests, only worse, and is reported as degraded.
+type Dependency struct {
+	Name     string
+	Critical bool
+	Ping     func(context.Context) error
+}
+
+type Probe struct {
+	dependencies []Dependency
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// New returns a probe that gives every dependency up to timeout to answer.
+func New(timeout time.Duration, dependencies ...Dependency) *Probe {
+	return &Probe{
+		dependencies: dependencies,
+		timeout:      timeout,
+	}
+}
+
+// Shutdown makes every following readiness check fail.
+func (p *Probe) Shutdown() {
+	p.shuttingDown.Store(true)
+}
+
+// Check pings all dependencies concurrently. Dependencies are still
+// reported during shutdown so that the cause of a slow stop is visible.
+func (p *Probe) Check(ctx context.Context) dto.ReadinessDTO {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	statuses := make([]dto.DependencyDTO, len(p.dependencies))
+	var wg sync.WaitGroup
+	for i, dependency := range p.dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = dto.DependencyDTO{
+				Name:     dependency.Name,
+				Status:   DependencyUp,
+				Critical: dependency.Critical,
+			}
+			if err := dependency.Ping(ctx); err != nil {
+				statuses[i].Status = DependencyDown
+				statuses[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := dto.ReadinessDTO{Status: StatusReady, Dependencies: statuses}
+	for _, status := range statuses {
+		if status.Status == DependencyUp {
+			continue
+		}
+		if status.Critical {
+			report.Status = StatusNotReady
+			break
+		}
+		report.Status = StatusDegraded
+	}
+	if p.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// Healthz godoc
+// @Summary Liveness probe
+// @Description Answers 200 while the process serves HTTP, dependencies are not checked
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (p *Probe) Healthz(c *ginext.Context) {
+	c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Description Pings the storage and the cache. A failing cache only degrades the service and still answers 200, a failing storage or a graceful shutdown answers 503
+// @Tags Health
+// @Produce json
+// @Success 200 {object} dto.ReadinessDTO "Ready or degraded"
+// @Failure 503 {object} dto.ReadinessDTO "Not ready or shutting down"
+// @Router /readyz [get]
+func (p *Probe) Readyz(c *ginext.Context) {
+	report := p.Check(c.Request.Context())
+
+	code := http.StatusOK
+	switch report.Status {
+	case StatusNotReady, StatusShuttingDown:
+		code = http.StatusServiceUnavailable
+	}
+	if report.Status != StatusReady {
+		zlog.Logger.Warn().Msg("readiness check reported " + report.Status)
+	}
+	c.JSON(code, report)
+}
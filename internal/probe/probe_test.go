@@ -0,0 +1,126 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/ginext"
+)
+
+func up(context.Context) error {
+	return nil
+}
+
+func down(context.Context) error {
+	return errors.New("connection refused")
+}
+
+func readyz(t *testing.T, p *Probe) (int, dto.ReadinessDTO) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	p.Readyz((*ginext.Context)(c))
+
+	var report dto.ReadinessDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func TestProbe_Healthz(t *testing.T) {
+	p := New(time.Second, Dependency{Name: "postgres", Critical: true, Ping: down})
+	p.Shutdown()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	p.Healthz((*ginext.Context)(c))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestProbe_Readyz(t *testing.T) {
+	tests := []struct {
+		name     string
+		postgres func(context.Context) error
+		redis    func(context.Context) error
+		code     int
+		status   string
+	}{
+		{"Ready", up, up, http.StatusOK, StatusReady},
+		{"CacheDown", up, down, http.StatusOK, StatusDegraded},
+		{"StorageDown", down, up, http.StatusServiceUnavailable, StatusNotReady},
+		{"AllDown", down, down, http.StatusServiceUnavailable, StatusNotReady},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(time.Second,
+				Dependency{Name: "postgres", Critical: true, Ping: tt.postgres},
+				Dependency{Name: "redis", Ping: tt.redis},
+			)
+
+			code, report := readyz(t, p)
+
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.status, report.Status)
+			require.Len(t, report.Dependencies, 2)
+			assert.Equal(t, "postgres", report.Dependencies[0].Name)
+			assert.True(t, report.Dependencies[0].Critical)
+			assert.Equal(t, "redis", report.Dependencies[1].Name)
+			assert.False(t, report.Dependencies[1].Critical)
+		})
+	}
+}
+
+func TestProbe_ReportsErrors(t *testing.T) {
+	p := New(time.Second,
+		Dependency{Name: "postgres", Critical: true, Ping: up},
+		Dependency{Name: "redis", Ping: down},
+	)
+
+	report := p.Check(context.Background())
+
+	assert.Equal(t, dto.DependencyDTO{Name: "postgres", Status: DependencyUp, Critical: true}, report.Dependencies[0])
+	assert.Equal(t, dto.DependencyDTO{Name: "redis", Status: DependencyDown, Error: "connection refused"}, report.Dependencies[1])
+}
+
+func TestProbe_Timeout(t *testing.T) {
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	p := New(50*time.Millisecond, Dependency{Name: "postgres", Critical: true, Ping: hang})
+
+	start := time.Now()
+	report := p.Check(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusNotReady, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Dependencies[0].Error)
+}
+
+func TestProbe_Shutdown(t *testing.T) {
+	p := New(time.Second, Dependency{Name: "postgres", Critical: true, Ping: up})
+
+	code, report := readyz(t, p)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusReady, report.Status)
+
+	p.Shutdown()
+
+	code, report = readyz(t, p)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusShuttingDown, report.Status)
+	assert.Equal(t, DependencyUp, report.Dependencies[0].Status)
+}
@@ -0,0 +1,74 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode_PNG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Size = 300
+	opts.Foreground = color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}
+
+	data, err := Encode("http://localhost:8080/s/abc123?source=qr", opts)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 300, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	r, g, b, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, [3]uint32{0xffff, 0xffff, 0xffff}, [3]uint32{r, g, b}, "margin uses background color")
+}
+
+func TestEncode_SVG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Format = FormatSVG
+	opts.Background, _ = ParseColor("#00ff00")
+
+	data, err := Encode("http://localhost:8080/s/abc123?source=qr", opts)
+	require.NoError(t, err)
+
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, `fill="#00ff00"`)
+	assert.Contains(t, svg, `fill="#000000"`)
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Options)
+		err    error
+	}{
+		{name: "defaults", modify: func(o *Options) {}},
+		{name: "format", modify: func(o *Options) { o.Format = "gif" }, err: ErrInvalidFormat},
+		{name: "size", modify: func(o *Options) { o.Size = MaxSize + 1 }, err: ErrInvalidSize},
+		{name: "margin", modify: func(o *Options) { o.Margin = -1 }, err: ErrInvalidMargin},
+		{name: "level", modify: func(o *Options) { o.Level = "X" }, err: ErrInvalidLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.modify(&opts)
+			assert.Equal(t, tt.err, opts.Validate())
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("1a2B3c")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}, c)
+
+	_, err = ParseColor("red")
+	assert.ErrorIs(t, err, ErrInvalidColor)
+}
@@ -0,0 +1,81 @@
+// Package referrer normalises Referer headers into referring domains so
+// that clicks can be grouped by where they came from.
+package referrer
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Direct is reported for clicks without a usable Referer header.
+const Direct = "direct"
+
+// known maps redirect and app domains of popular services to friendly
+// names, so that e.g. t.co and twitter.com end up in the same bucket.
+var known = map[string]string{
+	"t.co":                  "twitter",
+	"twitter.com":           "twitter",
+	"x.com":                 "twitter",
+	"facebook.com":          "facebook",
+	"m.facebook.com":        "facebook",
+	"l.facebook.com":        "facebook",
+	"lm.facebook.com":       "facebook",
+	"instagram.com":         "instagram",
+	"l.instagram.com":       "instagram",
+	"linkedin.com":          "linkedin",
+	"lnkd.in":               "linkedin",
+	"reddit.com":            "reddit",
+	"old.reddit.com":        "reddit",
+	"out.reddit.com":        "reddit",
+	"youtube.com":           "youtube",
+	"m.youtube.com":         "youtube",
+	"t.me":                  "telegram",
+	"web.telegram.org":      "telegram",
+	"vk.com":                "vk",
+	"m.vk.com":              "vk",
+	"away.vk.com":           "vk",
+	"google.com":            "google",
+	"news.google.com":       "google",
+	"com.google.android.gm": "gmail",
+	"mail.google.com":       "gmail",
+	"android-app":           "android app",
+	"yandex.ru":             "yandex",
+	"bing.com":              "bing",
+	"duckduckgo.com":        "duckduckgo",
+}
+
+// Normalize returns the referring domain without "www.", a friendly name
+// for known services, or Direct when the header is empty or unparsable.
+func Normalize(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Direct
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return Direct
+	}
+
+	// Android apps send referrers like android-app://com.google.android.gm/
+	if parsed.Scheme == "android-app" {
+		if name, ok := known[parsed.Host]; ok {
+			return name
+		}
+		return known["android-app"]
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if host == "" {
+		return Direct
+	}
+	if ip := net.ParseIP(host); ip == nil {
+		host = strings.TrimPrefix(host, "www.")
+	}
+
+	if name, ok := known[host]; ok {
+		return name
+	}
+	return host
+}
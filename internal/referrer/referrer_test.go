@@ -0,0 +1,30 @@
+package referrer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		referrer string
+		expected string
+	}{
+		{referrer: "", expected: Direct},
+		{referrer: "   ", expected: Direct},
+		{referrer: "not a url", expected: Direct},
+		{referrer: "https://www.Example.com/blog/post?id=1", expected: "example.com"},
+		{referrer: "https://news.example.com/", expected: "news.example.com"},
+		{referrer: "https://t.co/abc", expected: "twitter"},
+		{referrer: "https://l.facebook.com/l.php?u=x", expected: "facebook"},
+		{referrer: "https://www.facebook.com/", expected: "facebook"},
+		{referrer: "android-app://com.google.android.gm/", expected: "gmail"},
+		{referrer: "android-app://org.telegram.messenger/", expected: "android app"},
+		{referrer: "http://192.168.1.10:8080/page", expected: "192.168.1.10"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Normalize(tt.referrer), tt.referrer)
+	}
+}
@@ -3,24 +3,29 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Komilov31/url-shortener/internal/dto"
 	"github.com/lib/pq"
 )
 
-func (r *Repository) AggregateByUserAgent() ([]dto.UserAgentDTO, error) {
+func (r *Repository) AggregateByUserAgent(ctx context.Context, filter dto.LinkFilter) ([]dto.UserAgentDTO, error) {
 	query := `SELECT short_url, COUNT(short_url) AS count,
 	ARRAY_AGG(DISTINCT user_agent) AS user_agent
-	FROM redirect_analytics 
+	FROM redirect_analytics
+	WHERE ` + filterCondition("short_url", 1) + `
 	GROUP BY short_url;`
 
 	rows, err := r.db.QueryContext(
-		context.Background(),
+		ctx,
 		query,
+		filter.Folder,
+		filter.Tag,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
 	}
+	defer rows.Close()
 
 	var analytics []dto.UserAgentDTO
 	for rows.Next() {
@@ -30,11 +35,14 @@ func (r *Repository) AggregateByUserAgent() ([]dto.UserAgentDTO, error) {
 		}
 		analytics = append(analytics, next)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
 
 	return analytics, nil
 }
 
-func (r *Repository) AggregateByDate() ([]dto.DateDTO, error) {
+func (r *Repository) AggregateByDate(ctx context.Context, filter dto.LinkFilter) ([]dto.DateDTO, error) {
 	query := `SELECT COUNT(short_url),
     EXTRACT(DAY FROM request_time) AS day,
     EXTRACT(MONTH FROM request_time) AS month,
@@ -42,14 +50,18 @@ func (r *Repository) AggregateByDate() ([]dto.DateDTO, error) {
     ARRAY_AGG(short_url) AS short_urls,
     ARRAY_AGG(request_time ORDER BY request_time) AS request_times
 	FROM redirect_analytics
+	WHERE ` + filterCondition("short_url", 1) + `
 	GROUP BY day, month, year;`
 	rows, err := r.db.QueryContext(
-		context.Background(),
+		ctx,
 		query,
+		filter.Folder,
+		filter.Tag,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
 	}
+	defer rows.Close()
 
 	var analytics []dto.DateDTO
 	for rows.Next() {
@@ -80,25 +92,32 @@ func (r *Repository) AggregateByDate() ([]dto.DateDTO, error) {
 		}
 		analytics = append(analytics, next)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
 
 	return analytics, nil
 }
 
-func (r *Repository) AggregateByMonth() ([]dto.MonthDTO, error) {
+func (r *Repository) AggregateByMonth(ctx context.Context, filter dto.LinkFilter) ([]dto.MonthDTO, error) {
 	query := `SELECT COUNT(short_url),
     EXTRACT(MONTH FROM request_time) AS month,
     EXTRACT(YEAR FROM request_time) AS year,
     ARRAY_AGG(short_url) AS short_urls,
     ARRAY_AGG(request_time ORDER BY request_time) AS request_times
 	FROM redirect_analytics
+	WHERE ` + filterCondition("short_url", 1) + `
 	GROUP BY month, year;`
 	rows, err := r.db.QueryContext(
-		context.Background(),
+		ctx,
 		query,
+		filter.Folder,
+		filter.Tag,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
 	}
+	defer rows.Close()
 
 	var analytics []dto.MonthDTO
 	for rows.Next() {
@@ -128,6 +147,118 @@ func (r *Repository) AggregateByMonth() ([]dto.MonthDTO, error) {
 		}
 		analytics = append(analytics, next)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return analytics, nil
+}
+
+// AggregateByCampaign counts clicks and unique visitors per utm source,
+// medium and campaign. period is a date_trunc unit such as day or month.
+func (r *Repository) AggregateByCampaign(ctx context.Context, period string, filter dto.LinkFilter) ([]dto.CampaignDTO, error) {
+	query := `SELECT DATE_TRUNC($1, request_time) AS period,
+	utm_source, utm_medium, utm_campaign,
+	COUNT(*) AS clicks,
+	COUNT(DISTINCT NULLIF(visitor_id, '')) AS uniques
+	FROM redirect_analytics
+	WHERE (utm_source <> '' OR utm_medium <> '' OR utm_campaign <> '')
+	AND ` + filterCondition("short_url", 2) + `
+	GROUP BY period, utm_source, utm_medium, utm_campaign
+	ORDER BY period, clicks DESC;`
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		period,
+		filter.Folder,
+		filter.Tag,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
+	}
+	defer rows.Close()
+
+	var analytics []dto.CampaignDTO
+	for rows.Next() {
+		var next dto.CampaignDTO
+		err := rows.Scan(
+			&next.Period,
+			&next.Source,
+			&next.Medium,
+			&next.Campaign,
+			&next.Clicks,
+			&next.Uniques,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan aggregated data from db: %w", err)
+		}
+		analytics = append(analytics, next)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return analytics, nil
+}
+
+func (r *Repository) AggregateByReferrer(ctx context.Context, filter dto.LinkFilter) ([]dto.ReferrerDTO, error) {
+	query := `SELECT short_url, referrer_domain, COUNT(*) AS count
+	FROM redirect_analytics
+	WHERE ` + filterCondition("short_url", 1) + `
+	GROUP BY short_url, referrer_domain
+	ORDER BY short_url, count DESC, referrer_domain;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		filter.Folder,
+		filter.Tag,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
+	}
+	defer rows.Close()
+
+	var analytics []dto.ReferrerDTO
+	for rows.Next() {
+		var short_url string
+		var referrer dto.ReferrerCount
+		if err := rows.Scan(&short_url, &referrer.Referrer, &referrer.RedirectCount); err != nil {
+			return nil, fmt.Errorf("could not scan aggregated data from db: %w", err)
+		}
+
+		if len(analytics) == 0 || analytics[len(analytics)-1].ShortUrl != short_url {
+			analytics = append(analytics, dto.ReferrerDTO{ShortUrl: short_url})
+		}
+		last := &analytics[len(analytics)-1]
+		last.Referrers = append(last.Referrers, referrer)
+		last.RedirectCount += referrer.RedirectCount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
 
 	return analytics, nil
 }
+
+// PurgeAnalytics deletes clicks recorded before the given time, of one link
+// or of all links when short_url is empty. It returns the number of
+// deleted clicks.
+func (r *Repository) PurgeAnalytics(ctx context.Context, short_url string, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM redirect_analytics WHERE ($1::text = '' OR short_url = $1) AND request_time < $2",
+		short_url,
+		before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete redirect info from db: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get deleted rows count: %w", err)
+	}
+
+	return int(deleted), nil
+}
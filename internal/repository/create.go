@@ -9,10 +9,10 @@ import (
 	"github.com/lib/pq"
 )
 
-func (r *Repository) CreateShortUrl(urlInfo model.Url) (*model.Url, error) {
-	query := "SELECT * FROM urls WHERE url=$1"
+func (r *Repository) CreateShortUrl(ctx context.Context, urlInfo model.Url) (*model.Url, error) {
+	query := "SELECT id, short_url, url, fallback_url, disabled, expires_at FROM urls WHERE url=$1"
 	rows, err := r.db.QueryContext(
-		context.Background(),
+		ctx,
 		query,
 		urlInfo.Url,
 	)
@@ -22,19 +22,42 @@ func (r *Repository) CreateShortUrl(urlInfo model.Url) (*model.Url, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		err = rows.Scan(&urlInfo.Id, &urlInfo.ShortUrl, &urlInfo.Url)
+		var existing model.Url
+		err = rows.Scan(&existing.Id, &existing.ShortUrl, &existing.Url, &existing.FallbackUrl, &existing.Disabled, &existing.ExpiresAt)
 		if err == nil {
-			return &urlInfo, nil
+			return &existing, nil
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	tx, err := r.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
 
-	query = "INSERT INTO urls(url, short_url) VALUES($1, $2)"
-	_, err = r.db.ExecContext(
-		context.Background(),
+	query = `INSERT INTO urls(url, short_url, fallback_url, sticky_variants, query_policy,
+	utm_source, utm_medium, utm_campaign, utm_term, utm_content, folder, owner, expires_at)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`
+	err = tx.QueryRowContext(
+		ctx,
 		query,
 		urlInfo.Url,
 		urlInfo.ShortUrl,
-	)
+		urlInfo.FallbackUrl,
+		urlInfo.StickyVariants,
+		urlInfo.QueryPolicy,
+		urlInfo.Utm.Source,
+		urlInfo.Utm.Medium,
+		urlInfo.Utm.Campaign,
+		urlInfo.Utm.Term,
+		urlInfo.Utm.Content,
+		urlInfo.Folder,
+		urlInfo.Owner,
+		urlInfo.ExpiresAt,
+	).Scan(&urlInfo.Id)
 	if err != nil {
 		var pgErr *pq.Error
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -43,16 +66,45 @@ func (r *Repository) CreateShortUrl(urlInfo model.Url) (*model.Url, error) {
 		return nil, fmt.Errorf("could not save url info in db: %w", err)
 	}
 
+	if err := insertRules(ctx, tx, urlInfo.ShortUrl, urlInfo.Rules); err != nil {
+		return nil, err
+	}
+
+	if err := insertVariants(ctx, tx, urlInfo.ShortUrl, urlInfo.Variants); err != nil {
+		return nil, err
+	}
+
+	if err := insertTags(ctx, tx, urlInfo.ShortUrl, urlInfo.Tags); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
 	return &urlInfo, nil
 }
 
-func (r *Repository) CreateRedirectInfo(redirectInfo model.RedirectInfo) error {
-	query := `INSERT INTO redirect_analytics (short_url, user_agent) VALUES ($1, $2);`
+func (r *Repository) CreateRedirectInfo(ctx context.Context, redirectInfo model.RedirectInfo) error {
+	query := `INSERT INTO redirect_analytics (short_url, user_agent, source, matched_rule, variant,
+	utm_source, utm_medium, utm_campaign, utm_term, utm_content, visitor_id, referrer, referrer_domain)
+	VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8, $9, $10, $11, $12, $13);`
 	_, err := r.db.ExecContext(
-		context.Background(),
+		ctx,
 		query,
 		redirectInfo.ShortUrl,
 		redirectInfo.UserAgent,
+		redirectInfo.Source,
+		redirectInfo.MatchedRule,
+		redirectInfo.Variant,
+		redirectInfo.Utm.Source,
+		redirectInfo.Utm.Medium,
+		redirectInfo.Utm.Campaign,
+		redirectInfo.Utm.Term,
+		redirectInfo.Utm.Content,
+		redirectInfo.VisitorId,
+		redirectInfo.Referrer,
+		redirectInfo.ReferrerDomain,
 	)
 	if err != nil {
 		return fmt.Errorf("could not insert redirect info to db: %w", err)
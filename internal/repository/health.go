@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+func (r *Repository) ListUrls(ctx context.Context) ([]model.Url, error) {
+	query := `SELECT u.id, u.short_url, u.url, u.fallback_url,
+	COALESCE(h.healthy, TRUE), COALESCE(h.failures, 0)
+	FROM urls u
+	LEFT JOIN url_health h ON h.short_url = u.short_url
+	ORDER BY u.id`
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get urls from db: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []model.Url
+	for rows.Next() {
+		var url model.Url
+		if err := rows.Scan(&url.Id, &url.ShortUrl, &url.Url, &url.FallbackUrl, &url.Healthy, &url.HealthFailures); err != nil {
+			return nil, fmt.Errorf("could not scan url from db: %w", err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return urls, nil
+}
+
+func (r *Repository) SaveUrlHealth(ctx context.Context, health model.UrlHealth) error {
+	query := `INSERT INTO url_health
+	(short_url, status_code, latency_ms, healthy, failures, error, checked_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (short_url) DO UPDATE SET
+	status_code = EXCLUDED.status_code,
+	latency_ms = EXCLUDED.latency_ms,
+	healthy = EXCLUDED.healthy,
+	failures = EXCLUDED.failures,
+	error = EXCLUDED.error,
+	checked_at = EXCLUDED.checked_at;`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		health.ShortUrl,
+		health.StatusCode,
+		health.LatencyMs,
+		health.Healthy,
+		health.Failures,
+		health.Error,
+		health.CheckedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("could not save url health in db: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetUnhealthyUrls(ctx context.Context) ([]model.UrlHealth, error) {
+	query := `SELECT h.short_url, u.url, h.status_code, h.latency_ms,
+	h.healthy, h.failures, h.error, h.checked_at
+	FROM url_health h
+	JOIN urls u ON u.short_url = h.short_url
+	WHERE NOT h.healthy
+	ORDER BY h.checked_at DESC;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get unhealthy urls from db: %w", err)
+	}
+	defer rows.Close()
+
+	var unhealthy []model.UrlHealth
+	for rows.Next() {
+		var health model.UrlHealth
+		err := rows.Scan(
+			&health.ShortUrl,
+			&health.Url,
+			&health.StatusCode,
+			&health.LatencyMs,
+			&health.Healthy,
+			&health.Failures,
+			&health.Error,
+			&health.CheckedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan url health: %w", err)
+		}
+		unhealthy = append(unhealthy, health)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return unhealthy, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+const selectApiKeysQuery = `SELECT id, name, prefix, key_hash, created_at, revoked_at
+	FROM api_keys`
+
+func (r *Repository) CreateApiKey(ctx context.Context, key model.ApiKey) (*model.ApiKey, error) {
+	query := `INSERT INTO api_keys (name, prefix, key_hash)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at;`
+
+	err := r.db.Master.QueryRowContext(
+		ctx,
+		query,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+	).Scan(&key.Id, &key.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("could not save api key in db: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (r *Repository) ListApiKeys(ctx context.Context) ([]model.ApiKey, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		selectApiKeysQuery+`
+	ORDER BY id;`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get api keys from db: %w", err)
+	}
+	defer rows.Close()
+
+	return scanApiKeys(rows)
+}
+
+// GetApiKeyByHash finds a key by the hash of its secret, revoked keys
+// are returned as well.
+func (r *Repository) GetApiKeyByHash(ctx context.Context, keyHash string) (*model.ApiKey, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		selectApiKeysQuery+`
+	WHERE key_hash = $1;`,
+		keyHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get api key from db: %w", err)
+	}
+	defer rows.Close()
+
+	keys, err := scanApiKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrApiKeyNotFound
+	}
+
+	return &keys[0], nil
+}
+
+// RevokeApiKey marks the key revoked, revoking it again keeps the time of
+// the first revocation.
+func (r *Repository) RevokeApiKey(ctx context.Context, id int) (*model.ApiKey, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+	WHERE id = $1
+	RETURNING id, name, prefix, key_hash, created_at, revoked_at;`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not revoke api key in db: %w", err)
+	}
+	defer rows.Close()
+
+	keys, err := scanApiKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrApiKeyNotFound
+	}
+
+	return &keys[0], nil
+}
+
+func scanApiKeys(rows *sql.Rows) ([]model.ApiKey, error) {
+	var keys []model.ApiKey
+	for rows.Next() {
+		var key model.ApiKey
+		err := rows.Scan(
+			&key.Id,
+			&key.Name,
+			&key.Prefix,
+			&key.KeyHash,
+			&key.CreatedAt,
+			&key.RevokedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return keys, nil
+}
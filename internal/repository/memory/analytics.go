@@ -0,0 +1,279 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+func (r *Repository) AggregateByUserAgent(ctx context.Context, filter dto.LinkFilter) ([]dto.UserAgentDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groups := make(map[string]*dto.UserAgentDTO)
+	for _, redirect := range r.filteredRedirects(filter) {
+		group, ok := groups[redirect.ShortUrl]
+		if !ok {
+			group = &dto.UserAgentDTO{ShortUrl: redirect.ShortUrl}
+			groups[redirect.ShortUrl] = group
+		}
+		group.RedirectCount++
+		group.UserAgent = append(group.UserAgent, redirect.UserAgent)
+	}
+
+	var analytics []dto.UserAgentDTO
+	for _, group := range groups {
+		slices.Sort(group.UserAgent)
+		group.UserAgent = slices.Compact(group.UserAgent)
+		analytics = append(analytics, *group)
+	}
+	slices.SortFunc(analytics, func(a, b dto.UserAgentDTO) int {
+		return strings.Compare(a.ShortUrl, b.ShortUrl)
+	})
+
+	return analytics, nil
+}
+
+func (r *Repository) AggregateByDate(ctx context.Context, filter dto.LinkFilter) ([]dto.DateDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var analytics []dto.DateDTO
+	for _, redirect := range r.filteredRedirects(filter) {
+		year, month, day := redirect.RequestTime.Date()
+		i := slices.IndexFunc(analytics, func(d dto.DateDTO) bool {
+			return d.Year == year && d.Month == int(month) && d.Day == day
+		})
+		if i < 0 {
+			analytics = append(analytics, dto.DateDTO{Day: day, Month: int(month), Year: year})
+			i = len(analytics) - 1
+		}
+		analytics[i].RedirectCount++
+		analytics[i].UrlInfo = append(analytics[i].UrlInfo, dto.UrlInfo{
+			ShortUrl: redirect.ShortUrl,
+			Time:     redirect.RequestTime.Format(timeLayout),
+		})
+	}
+
+	return analytics, nil
+}
+
+func (r *Repository) AggregateByMonth(ctx context.Context, filter dto.LinkFilter) ([]dto.MonthDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var analytics []dto.MonthDTO
+	for _, redirect := range r.filteredRedirects(filter) {
+		year, month, _ := redirect.RequestTime.Date()
+		i := slices.IndexFunc(analytics, func(m dto.MonthDTO) bool {
+			return m.Year == year && m.Month == int(month)
+		})
+		if i < 0 {
+			analytics = append(analytics, dto.MonthDTO{Month: int(month), Year: year})
+			i = len(analytics) - 1
+		}
+		analytics[i].RedirectCount++
+		analytics[i].UrlInfo = append(analytics[i].UrlInfo, dto.UrlInfo{
+			ShortUrl: redirect.ShortUrl,
+			Time:     redirect.RequestTime.Format(timeLayout),
+		})
+	}
+
+	return analytics, nil
+}
+
+// AggregateByCampaign counts clicks and unique visitors per utm source,
+// medium and campaign. period is day, week or month.
+func (r *Repository) AggregateByCampaign(ctx context.Context, period string, filter dto.LinkFilter) ([]dto.CampaignDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := truncateTime(time.Time{}, period); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type campaign struct {
+		period                   time.Time
+		source, medium, campaign string
+	}
+	var (
+		keys     []campaign
+		clicks   = make(map[campaign]int)
+		visitors = make(map[campaign]map[string]struct{})
+	)
+	for _, redirect := range r.filteredRedirects(filter) {
+		if redirect.Utm.Source == "" && redirect.Utm.Medium == "" && redirect.Utm.Campaign == "" {
+			continue
+		}
+
+		start, _ := truncateTime(redirect.RequestTime, period)
+		key := campaign{start, redirect.Utm.Source, redirect.Utm.Medium, redirect.Utm.Campaign}
+		if _, ok := clicks[key]; !ok {
+			keys = append(keys, key)
+			visitors[key] = make(map[string]struct{})
+		}
+		clicks[key]++
+		if redirect.VisitorId != "" {
+			visitors[key][redirect.VisitorId] = struct{}{}
+		}
+	}
+
+	var analytics []dto.CampaignDTO
+	for _, key := range keys {
+		analytics = append(analytics, dto.CampaignDTO{
+			Period:   key.period,
+			Source:   key.source,
+			Medium:   key.medium,
+			Campaign: key.campaign,
+			Clicks:   clicks[key],
+			Uniques:  len(visitors[key]),
+		})
+	}
+	slices.SortStableFunc(analytics, func(a, b dto.CampaignDTO) int {
+		if c := a.Period.Compare(b.Period); c != 0 {
+			return c
+		}
+		return b.Clicks - a.Clicks
+	})
+
+	return analytics, nil
+}
+
+// truncateTime works like DATE_TRUNC for the periods supported by the
+// campaign analytics, weeks start on Monday.
+func truncateTime(t time.Time, period string) (time.Time, error) {
+	year, month, day := t.Date()
+	switch period {
+	case "day":
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+	case "week":
+		weekday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-weekday, 0, 0, 0, 0, time.UTC), nil
+	case "month":
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, fmt.Errorf("could not aggregate campaigns: unknown period %q", period)
+	}
+}
+
+func (r *Repository) AggregateByReferrer(ctx context.Context, filter dto.LinkFilter) ([]dto.ReferrerDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grouped := make(map[string][]model.RedirectInfo)
+	for _, redirect := range r.filteredRedirects(filter) {
+		grouped[redirect.ShortUrl] = append(grouped[redirect.ShortUrl], redirect)
+	}
+
+	var analytics []dto.ReferrerDTO
+	for short_url, redirects := range grouped {
+		analytics = append(analytics, dto.ReferrerDTO{
+			ShortUrl:      short_url,
+			Referrers:     countReferrers(redirects),
+			RedirectCount: len(redirects),
+		})
+	}
+	slices.SortFunc(analytics, func(a, b dto.ReferrerDTO) int {
+		return strings.Compare(a.ShortUrl, b.ShortUrl)
+	})
+
+	return analytics, nil
+}
+
+// AggregateByTag rolls clicks up to tags, a click of a link with several
+// tags is counted once for every tag.
+func (r *Repository) AggregateByTag(ctx context.Context, filter dto.LinkFilter) ([]dto.TagDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	grouped := make(map[string][]model.RedirectInfo)
+	for _, redirect := range r.redirects {
+		grouped[redirect.ShortUrl] = append(grouped[redirect.ShortUrl], redirect)
+	}
+
+	tags := make(map[string]*dto.TagDTO)
+	visitors := make(map[string]map[string]struct{})
+	for short_url, l := range r.links {
+		if !r.matchesFilter(short_url, filter) {
+			continue
+		}
+
+		for _, tag := range l.Tags {
+			group, ok := tags[tag]
+			if !ok {
+				group = &dto.TagDTO{Tag: tag}
+				tags[tag] = group
+				visitors[tag] = make(map[string]struct{})
+			}
+			group.Links++
+			group.RedirectCount += len(grouped[short_url])
+			for _, redirect := range grouped[short_url] {
+				if redirect.VisitorId != "" {
+					visitors[tag][redirect.VisitorId] = struct{}{}
+				}
+			}
+		}
+	}
+
+	var analytics []dto.TagDTO
+	for tag, group := range tags {
+		group.Uniques = len(visitors[tag])
+		analytics = append(analytics, *group)
+	}
+	slices.SortFunc(analytics, func(a, b dto.TagDTO) int {
+		if a.RedirectCount != b.RedirectCount {
+			return b.RedirectCount - a.RedirectCount
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+
+	return analytics, nil
+}
+
+// PurgeAnalytics deletes clicks recorded before the given time, of one link
+// or of all links when short_url is empty. It returns the number of
+// deleted clicks.
+func (r *Repository) PurgeAnalytics(ctx context.Context, short_url string, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before = timestamp(before.UTC())
+	count := len(r.redirects)
+	r.redirects = slices.DeleteFunc(r.redirects, func(redirect model.RedirectInfo) bool {
+		return (short_url == "" || redirect.ShortUrl == short_url) && redirect.RequestTime.Before(before)
+	})
+
+	return count - len(r.redirects), nil
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) CreateShortUrl(ctx context.Context, urlInfo model.Url) (*model.Url, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.links {
+		if existing.Url.Url == urlInfo.Url {
+			return &model.Url{
+				Id:          existing.Id,
+				ShortUrl:    existing.ShortUrl,
+				Url:         existing.Url.Url,
+				FallbackUrl: existing.FallbackUrl,
+				Disabled:    existing.Disabled,
+				ExpiresAt:   timestampPtr(existing.ExpiresAt),
+			}, nil
+		}
+	}
+
+	if _, ok := r.links[urlInfo.ShortUrl]; ok {
+		return nil, repository.ErrUniqueConstraint
+	}
+
+	r.lastUrlId++
+	urlInfo.Id = r.lastUrlId
+
+	r.links[urlInfo.ShortUrl] = &link{
+		Url: model.Url{
+			Id:             urlInfo.Id,
+			Url:            urlInfo.Url,
+			ShortUrl:       urlInfo.ShortUrl,
+			FallbackUrl:    urlInfo.FallbackUrl,
+			Rules:          r.newRules(urlInfo.Rules),
+			Variants:       r.newVariants(urlInfo.Variants),
+			StickyVariants: urlInfo.StickyVariants,
+			QueryPolicy:    urlInfo.QueryPolicy,
+			Folder:         urlInfo.Folder,
+			Tags:           uniqueTags(urlInfo.Tags),
+			Owner:          urlInfo.Owner,
+			ExpiresAt:      timestampPtr(urlInfo.ExpiresAt),
+			Utm:            urlInfo.Utm,
+		},
+		createdAt: r.timestamp(),
+	}
+
+	return &urlInfo, nil
+}
+
+func (r *Repository) CreateRedirectInfo(ctx context.Context, redirectInfo model.RedirectInfo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.links[redirectInfo.ShortUrl]; !ok {
+		return fmt.Errorf("could not insert redirect info: %w", repository.ErrAliasNotFound)
+	}
+
+	r.lastRedirectId++
+	r.redirects = append(r.redirects, model.RedirectInfo{
+		Id:             r.lastRedirectId,
+		ShortUrl:       redirectInfo.ShortUrl,
+		RequestTime:    r.timestamp(),
+		UserAgent:      redirectInfo.UserAgent,
+		Source:         redirectInfo.Source,
+		Referrer:       redirectInfo.Referrer,
+		ReferrerDomain: redirectInfo.ReferrerDomain,
+		MatchedRule:    redirectInfo.MatchedRule,
+		Variant:        redirectInfo.Variant,
+		VisitorId:      redirectInfo.VisitorId,
+		Utm:            redirectInfo.Utm,
+	})
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+// timeLayout is how Postgres prints timestamps inside arrays.
+const timeLayout = "2006-01-02 15:04:05.999999"
+
+func (r *Repository) GetUrlByShort(ctx context.Context, short_url string, redirectInfo model.RedirectInfo) (*model.Url, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	l, ok := r.links[short_url]
+	if !ok {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	urlInfo := model.Url{
+		Id:             l.Id,
+		ShortUrl:       l.ShortUrl,
+		Url:            l.Url.Url,
+		FallbackUrl:    l.FallbackUrl,
+		Rules:          sortedRules(l.Rules),
+		Variants:       slices.Clone(l.Variants),
+		StickyVariants: l.StickyVariants,
+		QueryPolicy:    l.QueryPolicy,
+		ExpiresAt:      timestampPtr(l.ExpiresAt),
+		Disabled:       l.Disabled,
+		Utm:            l.Utm,
+		Healthy:        true,
+	}
+	if health, ok := r.health[short_url]; ok {
+		urlInfo.Healthy = health.Healthy
+	}
+
+	return &urlInfo, nil
+}
+
+func (r *Repository) GetAnalytics(ctx context.Context, short_url string) ([]dto.RedirectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var redirects []model.RedirectInfo
+	for _, redirect := range r.redirects {
+		if redirect.ShortUrl == short_url {
+			redirects = append(redirects, redirect)
+		}
+	}
+	if len(redirects) == 0 {
+		return nil, nil
+	}
+
+	info := dto.RedirectInfo{
+		ShortUrl:      short_url,
+		Url:           r.links[short_url].Url.Url,
+		RedirectCount: len(redirects),
+		UserAgent:     []string{},
+		RequestTime:   []string{},
+		Variants:      countVariants(redirects),
+		Referrers:     countReferrers(redirects),
+	}
+	for _, redirect := range redirects {
+		if redirect.Source == model.SourceQr {
+			info.QrScans++
+		}
+		info.UserAgent = append(info.UserAgent, redirect.UserAgent)
+		info.RequestTime = append(info.RequestTime, redirect.RequestTime.Format(timeLayout))
+	}
+	slices.Sort(info.UserAgent)
+	info.UserAgent = slices.Compact(info.UserAgent)
+	info.RequestTime = slices.Compact(info.RequestTime)
+
+	return []dto.RedirectInfo{info}, nil
+}
+
+func countVariants(redirects []model.RedirectInfo) []dto.VariantCount {
+	counts := make(map[string]int)
+	for _, redirect := range redirects {
+		if redirect.Variant != "" {
+			counts[redirect.Variant]++
+		}
+	}
+
+	var variants []dto.VariantCount
+	for variant, count := range counts {
+		variants = append(variants, dto.VariantCount{Variant: variant, RedirectCount: count})
+	}
+	slices.SortFunc(variants, func(a, b dto.VariantCount) int {
+		return strings.Compare(a.Variant, b.Variant)
+	})
+
+	return variants
+}
+
+func countReferrers(redirects []model.RedirectInfo) []dto.ReferrerCount {
+	counts := make(map[string]int)
+	for _, redirect := range redirects {
+		counts[redirect.ReferrerDomain]++
+	}
+
+	var referrers []dto.ReferrerCount
+	for referrer, count := range counts {
+		referrers = append(referrers, dto.ReferrerCount{Referrer: referrer, RedirectCount: count})
+	}
+	sortReferrers(referrers)
+
+	return referrers
+}
+
+func sortReferrers(referrers []dto.ReferrerCount) {
+	slices.SortFunc(referrers, func(a, b dto.ReferrerCount) int {
+		if a.RedirectCount != b.RedirectCount {
+			return b.RedirectCount - a.RedirectCount
+		}
+		return strings.Compare(a.Referrer, b.Referrer)
+	})
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) ListUrls(ctx context.Context) ([]model.Url, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var urls []model.Url
+	for _, l := range r.links {
+		url := model.Url{
+			Id:          l.Id,
+			ShortUrl:    l.ShortUrl,
+			Url:         l.Url.Url,
+			FallbackUrl: l.FallbackUrl,
+			Healthy:     true,
+		}
+		if health, ok := r.health[l.ShortUrl]; ok {
+			url.Healthy = health.Healthy
+			url.HealthFailures = health.Failures
+		}
+		urls = append(urls, url)
+	}
+	slices.SortFunc(urls, func(a, b model.Url) int {
+		return a.Id - b.Id
+	})
+
+	return urls, nil
+}
+
+func (r *Repository) SaveUrlHealth(ctx context.Context, health model.UrlHealth) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.links[health.ShortUrl]; !ok {
+		return fmt.Errorf("could not save url health: %w", repository.ErrAliasNotFound)
+	}
+	health.CheckedAt = timestamp(health.CheckedAt)
+	r.health[health.ShortUrl] = health
+
+	return nil
+}
+
+func (r *Repository) GetUnhealthyUrls(ctx context.Context) ([]model.UrlHealth, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var unhealthy []model.UrlHealth
+	for short_url, health := range r.health {
+		if health.Healthy {
+			continue
+		}
+		health.Url = r.links[short_url].Url.Url
+		unhealthy = append(unhealthy, health)
+	}
+	slices.SortFunc(unhealthy, func(a, b model.UrlHealth) int {
+		return b.CheckedAt.Compare(a.CheckedAt)
+	})
+
+	return unhealthy, nil
+}
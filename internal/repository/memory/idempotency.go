@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+// ClaimIdempotencyKey stores key as in progress for lease unless a record
+// with the same key exists that has not expired. It reports whether key was
+// claimed, otherwise the existing record is returned.
+func (r *Repository) ClaimIdempotencyKey(ctx context.Context, key model.IdempotencyKey, lease time.Duration) (*model.IdempotencyKey, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.timestamp()
+	if existing, ok := r.idempotencyKeys[key.Key]; ok && existing.ExpiresAt.After(now) {
+		return &existing, false, nil
+	}
+
+	key.Response = ""
+	key.CreatedAt = now
+	key.ExpiresAt = timestamp(now.Add(lease))
+	r.idempotencyKeys[key.Key] = key
+
+	return &key, true, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request that claimed
+// key with token and keeps it for ttl. It fails with
+// ErrIdempotencyKeyNotFound once another request has taken the key over.
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, key, token, response string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.idempotencyKeys[key]
+	if !ok || existing.Token != token || existing.Response != "" {
+		return repository.ErrIdempotencyKeyNotFound
+	}
+	existing.Response = response
+	existing.ExpiresAt = timestamp(r.timestamp().Add(ttl))
+	r.idempotencyKeys[key] = existing
+
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes key while the request that claimed it
+// with token is in progress, so that the request can be retried after it
+// failed. Completed keys and keys taken over by another request are kept.
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, key, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.idempotencyKeys[key]; ok && existing.Token == token && existing.Response == "" {
+		delete(r.idempotencyKeys, key)
+	}
+
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes expired keys and returns how many were
+// deleted.
+func (r *Repository) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.timestamp()
+	deleted := 0
+	for key, existing := range r.idempotencyKeys {
+		if !existing.ExpiresAt.After(now) {
+			delete(r.idempotencyKeys, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) CreateApiKey(ctx context.Context, key model.ApiKey) (*model.ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.apiKeys {
+		if existing.KeyHash == key.KeyHash {
+			return nil, repository.ErrUniqueConstraint
+		}
+	}
+
+	r.lastApiKeyId++
+	key.Id = r.lastApiKeyId
+	key.CreatedAt = r.timestamp()
+	key.RevokedAt = nil
+	r.apiKeys = append(r.apiKeys, key)
+
+	return &key, nil
+}
+
+func (r *Repository) ListApiKeys(ctx context.Context) ([]model.ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []model.ApiKey
+	for _, key := range r.apiKeys {
+		keys = append(keys, copyApiKey(key))
+	}
+
+	return keys, nil
+}
+
+// GetApiKeyByHash finds a key by the hash of its secret, revoked keys
+// are returned as well.
+func (r *Repository) GetApiKeyByHash(ctx context.Context, keyHash string) (*model.ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.apiKeys {
+		if key.KeyHash == keyHash {
+			found := copyApiKey(key)
+			return &found, nil
+		}
+	}
+
+	return nil, repository.ErrApiKeyNotFound
+}
+
+// RevokeApiKey marks the key revoked, revoking it again keeps the time of
+// the first revocation.
+func (r *Repository) RevokeApiKey(ctx context.Context, id int) (*model.ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.apiKeys {
+		key := &r.apiKeys[i]
+		if key.Id != id {
+			continue
+		}
+		if key.RevokedAt == nil {
+			revokedAt := r.timestamp()
+			key.RevokedAt = &revokedAt
+		}
+		revoked := copyApiKey(*key)
+		return &revoked, nil
+	}
+
+	return nil, repository.ErrApiKeyNotFound
+}
+
+func copyApiKey(key model.ApiKey) model.ApiKey {
+	if key.RevokedAt != nil {
+		revokedAt := *key.RevokedAt
+		key.RevokedAt = &revokedAt
+	}
+	return key
+}
@@ -0,0 +1,233 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+type clickStats struct {
+	clicks    int
+	lastClick *time.Time
+}
+
+// SearchLinks matches Search as a case insensitive substring of the
+// destination, alias, page title and tags.
+func (r *Repository) SearchLinks(ctx context.Context, search dto.LinkQuery) ([]dto.LinkDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type result struct {
+		id   int
+		link dto.LinkDTO
+	}
+
+	stats := r.clickStats()
+	now := r.now()
+	pattern := strings.ToLower(search.Search)
+
+	var results []result
+	for short_url, l := range r.links {
+		if !r.matchesFilter(short_url, search.LinkFilter) {
+			continue
+		}
+
+		link := r.linkDTO(l, stats[short_url], now)
+		switch {
+		case pattern != "" && !r.containsPattern(link, pattern):
+			continue
+		case search.Owner != "" && link.Owner != search.Owner:
+			continue
+		case search.Status != "" && link.Status != search.Status:
+			continue
+		case search.CreatedFrom != nil && link.CreatedAt.Before(timestamp(*search.CreatedFrom)):
+			continue
+		case search.CreatedTo != nil && !link.CreatedAt.Before(timestamp(*search.CreatedTo)):
+			continue
+		case search.MinClicks != nil && link.Clicks < *search.MinClicks:
+			continue
+		case search.MaxClicks != nil && link.Clicks > *search.MaxClicks:
+			continue
+		}
+
+		results = append(results, result{id: l.Id, link: link})
+	}
+
+	desc := search.Order != dto.LinkOrderAsc
+	slices.SortFunc(results, func(a, b result) int {
+		c := compareLinks(a.link, b.link, search.Sort, desc)
+		if c == 0 {
+			c = a.id - b.id
+			if desc {
+				c = -c
+			}
+		}
+		return c
+	})
+
+	var links []dto.LinkDTO
+	for i := search.Offset; i < len(results) && len(links) < search.Limit; i++ {
+		links = append(links, results[i].link)
+	}
+
+	return links, nil
+}
+
+// compareLinks orders links by the sort key, links without a last click
+// come last in both directions like NULLS LAST does.
+func compareLinks(a, b dto.LinkDTO, sort string, desc bool) int {
+	var c int
+	switch sort {
+	case dto.LinkSortLastClick:
+		switch {
+		case a.LastClick == nil && b.LastClick == nil:
+			return 0
+		case a.LastClick == nil:
+			return 1
+		case b.LastClick == nil:
+			return -1
+		}
+		c = a.LastClick.Compare(*b.LastClick)
+	case dto.LinkSortClicks:
+		c = a.Clicks - b.Clicks
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+
+	if desc {
+		return -c
+	}
+	return c
+}
+
+// containsPattern must be called with r.mu held.
+func (r *Repository) containsPattern(link dto.LinkDTO, pattern string) bool {
+	if strings.Contains(strings.ToLower(link.Url), pattern) ||
+		strings.Contains(strings.ToLower(link.ShortUrl), pattern) {
+		return true
+	}
+	if metadata, ok := r.metadata[link.ShortUrl]; ok && strings.Contains(strings.ToLower(metadata.Title), pattern) {
+		return true
+	}
+	return slices.ContainsFunc(link.Tags, func(tag string) bool {
+		return strings.Contains(strings.ToLower(tag), pattern)
+	})
+}
+
+func (r *Repository) GetLink(ctx context.Context, short_url string) (*dto.LinkDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	l, ok := r.links[short_url]
+	if !ok {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	return r.queryLink(l), nil
+}
+
+func (r *Repository) SetDisabled(ctx context.Context, short_url string, disabled bool) (*dto.LinkDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.links[short_url]
+	if !ok {
+		return nil, repository.ErrAliasNotFound
+	}
+	l.Disabled = disabled
+
+	return r.queryLink(l), nil
+}
+
+// DeleteLink deletes the link with its analytics, metadata and health
+// check, rules, variants and tags are stored on the link itself.
+func (r *Repository) DeleteLink(ctx context.Context, short_url string) (*model.Url, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.links[short_url]
+	if !ok {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	delete(r.links, short_url)
+	delete(r.metadata, short_url)
+	delete(r.health, short_url)
+	r.redirects = slices.DeleteFunc(r.redirects, func(redirect model.RedirectInfo) bool {
+		return redirect.ShortUrl == short_url
+	})
+
+	return &model.Url{
+		Id:          l.Id,
+		ShortUrl:    l.ShortUrl,
+		Url:         l.Url.Url,
+		FallbackUrl: l.FallbackUrl,
+	}, nil
+}
+
+// queryLink must be called with r.mu held.
+func (r *Repository) queryLink(l *link) *dto.LinkDTO {
+	link := r.linkDTO(l, r.clickStats()[l.ShortUrl], r.now())
+	return &link
+}
+
+// clickStats must be called with r.mu held.
+func (r *Repository) clickStats() map[string]clickStats {
+	stats := make(map[string]clickStats)
+	for _, redirect := range r.redirects {
+		stat := stats[redirect.ShortUrl]
+		stat.clicks++
+		if stat.lastClick == nil || redirect.RequestTime.After(*stat.lastClick) {
+			requestTime := redirect.RequestTime
+			stat.lastClick = &requestTime
+		}
+		stats[redirect.ShortUrl] = stat
+	}
+	return stats
+}
+
+// linkDTO must be called with r.mu held.
+func (r *Repository) linkDTO(l *link, stats clickStats, now time.Time) dto.LinkDTO {
+	link := dto.LinkDTO{
+		ShortUrl:  l.ShortUrl,
+		Url:       l.Url.Url,
+		Title:     r.metadata[l.ShortUrl].Title,
+		Folder:    l.Folder,
+		Tags:      append([]string{}, l.Tags...),
+		Owner:     l.Owner,
+		Status:    model.LinkStatusActive,
+		CreatedAt: l.createdAt,
+		ExpiresAt: timestampPtr(l.ExpiresAt),
+		Clicks:    stats.clicks,
+		LastClick: stats.lastClick,
+	}
+	switch {
+	case l.Disabled:
+		link.Status = model.LinkStatusDisabled
+	case l.ExpiresAt != nil && !l.ExpiresAt.After(now):
+		link.Status = model.LinkStatusExpired
+	}
+
+	return link
+}
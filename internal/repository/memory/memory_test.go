@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_Contract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Storage {
+		return New()
+	})
+}
+
+func TestRepository_AggregateByCampaign_Periods(t *testing.T) {
+	repo := New()
+	ctx := context.Background()
+	_, err := repo.CreateShortUrl(ctx, model.Url{Url: "https://example.com", ShortUrl: "abc123"})
+	require.NoError(t, err)
+
+	// Wednesday and Sunday of the same week, Monday of the next one.
+	for _, day := range []int{15, 19, 20} {
+		repo.now = func() time.Time { return time.Date(2026, time.April, day, 12, 30, 0, 0, time.UTC) }
+		err := repo.CreateRedirectInfo(ctx, model.RedirectInfo{ShortUrl: "abc123", Utm: model.Utm{Source: "ads"}})
+		require.NoError(t, err)
+	}
+
+	weeks, err := repo.AggregateByCampaign(ctx, "week", dto.LinkFilter{})
+	require.NoError(t, err)
+	require.Len(t, weeks, 2)
+	assert.Equal(t, time.Date(2026, time.April, 13, 0, 0, 0, 0, time.UTC), weeks[0].Period)
+	assert.Equal(t, 2, weeks[0].Clicks)
+	assert.Equal(t, time.Date(2026, time.April, 20, 0, 0, 0, 0, time.UTC), weeks[1].Period)
+
+	months, err := repo.AggregateByCampaign(ctx, "month", dto.LinkFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []dto.CampaignDTO{
+		{Period: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), Source: "ads", Clicks: 3},
+	}, months)
+
+	_, err = repo.AggregateByCampaign(ctx, "hour", dto.LinkFilter{})
+	assert.Error(t, err)
+}
+
+func TestRepository_CancelledContext(t *testing.T) {
+	repo := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.SearchLinks(ctx, dto.LinkQuery{Limit: 10})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
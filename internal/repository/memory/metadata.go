@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) SaveUrlMetadata(ctx context.Context, metadata model.UrlMetadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.links[metadata.ShortUrl]; !ok {
+		return fmt.Errorf("could not save url metadata: %w", repository.ErrAliasNotFound)
+	}
+	metadata.FetchedAt = timestamp(metadata.FetchedAt)
+	r.metadata[metadata.ShortUrl] = metadata
+
+	return nil
+}
+
+func (r *Repository) GetUrlMetadata(ctx context.Context, short_url string) (*model.UrlMetadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	metadata, ok := r.metadata[short_url]
+	if !ok {
+		return nil, repository.ErrMetadataNotFound
+	}
+
+	return &metadata, nil
+}
@@ -0,0 +1,102 @@
+// Package memory is an in-memory implementation of the link storage. It
+// follows the semantics of the Postgres repository and is meant for local
+// development and tests: nothing survives a restart.
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+type link struct {
+	model.Url
+	createdAt time.Time
+}
+
+type Repository struct {
+	mu              sync.RWMutex
+	links           map[string]*link
+	redirects       []model.RedirectInfo
+	metadata        map[string]model.UrlMetadata
+	health          map[string]model.UrlHealth
+	apiKeys         []model.ApiKey
+	idempotencyKeys map[string]model.IdempotencyKey
+
+	lastUrlId      int
+	lastRuleId     int
+	lastVariantId  int
+	lastRedirectId int
+	lastApiKeyId   int
+
+	now func() time.Time
+}
+
+func New() *Repository {
+	return &Repository{
+		links:           make(map[string]*link),
+		metadata:        make(map[string]model.UrlMetadata),
+		health:          make(map[string]model.UrlHealth),
+		idempotencyKeys: make(map[string]model.IdempotencyKey),
+		now:             time.Now,
+	}
+}
+
+// Ping and Close exist for parity with the database backends, the memory
+// backend is always available and there is nothing to release.
+func (r *Repository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (r *Repository) Close() error {
+	return nil
+}
+
+// timestamp converts t the way a Postgres TIMESTAMP column stores it: the
+// wall clock is kept, the zone is dropped and precision is microseconds.
+func timestamp(t time.Time) time.Time {
+	t = t.Round(time.Microsecond)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+func timestampPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	converted := timestamp(*t)
+	return &converted
+}
+
+func (r *Repository) timestamp() time.Time {
+	return timestamp(r.now().UTC())
+}
+
+// matchesFilter must be called with r.mu held.
+func (r *Repository) matchesFilter(short_url string, filter dto.LinkFilter) bool {
+	l, ok := r.links[short_url]
+	if !ok {
+		return false
+	}
+	if filter.Folder != "" && l.Folder != filter.Folder {
+		return false
+	}
+	if filter.Tag != "" && !slices.Contains(l.Tags, filter.Tag) {
+		return false
+	}
+	return true
+}
+
+// filteredRedirects must be called with r.mu held.
+func (r *Repository) filteredRedirects(filter dto.LinkFilter) []model.RedirectInfo {
+	var redirects []model.RedirectInfo
+	for _, redirect := range r.redirects {
+		if r.matchesFilter(redirect.ShortUrl, filter) {
+			redirects = append(redirects, redirect)
+		}
+	}
+	return redirects
+}
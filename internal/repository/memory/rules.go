@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) SetTargetingRules(ctx context.Context, short_url string, rules []model.TargetingRule) ([]model.TargetingRule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.links[short_url]
+	if !ok {
+		return nil, repository.ErrAliasNotFound
+	}
+	l.Rules = r.newRules(rules)
+
+	return sortedRules(l.Rules), nil
+}
+
+func (r *Repository) GetTargetingRules(ctx context.Context, short_url string) ([]model.TargetingRule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	l, ok := r.links[short_url]
+	if !ok {
+		return nil, nil
+	}
+
+	return sortedRules(l.Rules), nil
+}
+
+// newRules assigns ids to rules, it must be called with r.mu held.
+func (r *Repository) newRules(rules []model.TargetingRule) []model.TargetingRule {
+	var saved []model.TargetingRule
+	for _, rule := range rules {
+		r.lastRuleId++
+		rule.Id = r.lastRuleId
+		saved = append(saved, rule)
+	}
+	return saved
+}
+
+func sortedRules(rules []model.TargetingRule) []model.TargetingRule {
+	sorted := slices.Clone(rules)
+	slices.SortStableFunc(sorted, func(a, b model.TargetingRule) int {
+		return a.Position - b.Position
+	})
+	return sorted
+}
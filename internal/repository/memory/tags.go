@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) SetTags(ctx context.Context, short_url string, tags []string) (*dto.LinkDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.links[short_url]
+	if !ok {
+		return nil, repository.ErrAliasNotFound
+	}
+	l.Tags = uniqueTags(tags)
+
+	return r.queryLink(l), nil
+}
+
+func (r *Repository) SetFolder(ctx context.Context, short_url string, folder string) (*dto.LinkDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.links[short_url]
+	if !ok {
+		return nil, repository.ErrAliasNotFound
+	}
+	l.Folder = folder
+
+	return r.queryLink(l), nil
+}
+
+// uniqueTags returns sorted tags without duplicates.
+func uniqueTags(tags []string) []string {
+	unique := slices.Clone(tags)
+	slices.Sort(unique)
+	return slices.Compact(unique)
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) SetVariants(ctx context.Context, variants dto.VariantsDTO) (*dto.VariantsDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.links[variants.ShortUrl]
+	if !ok {
+		return nil, repository.ErrAliasNotFound
+	}
+	l.StickyVariants = variants.Sticky
+	l.Variants = r.newVariants(variants.Variants)
+
+	variants.Variants = slices.Clone(l.Variants)
+	return &variants, nil
+}
+
+func (r *Repository) GetVariants(ctx context.Context, short_url string) (*dto.VariantsDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	l, ok := r.links[short_url]
+	if !ok {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	return &dto.VariantsDTO{
+		ShortUrl: short_url,
+		Sticky:   l.StickyVariants,
+		Variants: slices.Clone(l.Variants),
+	}, nil
+}
+
+// newVariants assigns ids to variants, it must be called with r.mu held.
+func (r *Repository) newVariants(variants []model.Variant) []model.Variant {
+	var saved []model.Variant
+	for _, variant := range variants {
+		r.lastVariantId++
+		variant.Id = r.lastVariantId
+		saved = append(saved, variant)
+	}
+	return saved
+}
@@ -1,14 +1,20 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/wb-go/wbf/dbpg"
 )
 
 var (
-	ErrAliasNotFound    = errors.New("need to create short_url first")
-	ErrUniqueConstraint = errors.New("short_url already exists in db")
+	ErrAliasNotFound          = errors.New("short_url does not exist")
+	ErrUniqueConstraint       = errors.New("short_url already exists in db")
+	ErrMetadataNotFound       = errors.New("metadata for short_url is not fetched yet")
+	ErrApiKeyNotFound         = errors.New("api key does not exist")
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key does not exist")
 )
 
 type Repository struct {
@@ -20,3 +26,39 @@ func New(db *dbpg.DB) *Repository {
 		db: db,
 	}
 }
+
+// Ping checks that the master accepts connections.
+func (r *Repository) Ping(ctx context.Context) error {
+	if err := r.db.Master.PingContext(ctx); err != nil {
+		return fmt.Errorf("could not ping master: %w", err)
+	}
+	return nil
+}
+
+// Replicas is the number of read replicas, see PingReplica.
+func (r *Repository) Replicas() int {
+	return len(r.db.Slaves)
+}
+
+// PingReplica checks that the i-th replica, counting from zero, accepts
+// connections.
+func (r *Repository) PingReplica(ctx context.Context, i int) error {
+	if err := r.db.Slaves[i].PingContext(ctx); err != nil {
+		return fmt.Errorf("could not ping replica %d: %w", i+1, err)
+	}
+	return nil
+}
+
+// Close closes the connection pools of the master and the replicas.
+func (r *Repository) Close() error {
+	var errs []error
+	for _, db := range append([]*sql.DB{r.db.Master}, r.db.Slaves...) {
+		if err := db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("could not close db: %w", err)
+	}
+	return nil
+}
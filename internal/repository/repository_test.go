@@ -0,0 +1,39 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Komilov31/url-shortener/internal/migrate"
+	"github.com/Komilov31/url-shortener/internal/repository"
+	"github.com/Komilov31/url-shortener/internal/repository/repotest"
+	"github.com/stretchr/testify/require"
+	"github.com/wb-go/wbf/dbpg"
+)
+
+// TestRepository_Contract runs against a real Postgres when
+// TEST_POSTGRES_DSN is set. Migrations are applied first, every test
+// truncates the tables.
+func TestRepository_Contract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := dbpg.New(dsn, []string{}, &dbpg.Options{MaxOpenConns: 10, MaxIdleConns: 5})
+	require.NoError(t, err)
+
+	migrator, err := migrate.Postgres(db.Master)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	repotest.Run(t, func(t *testing.T) repotest.Storage {
+		_, err := db.Master.Exec(`TRUNCATE urls, redirect_analytics, url_metadata, url_health,
+		targeting_rules, url_variants, url_tags, api_keys RESTART IDENTITY CASCADE`)
+		require.NoError(t, err)
+
+		return repository.New(db)
+	})
+}
@@ -0,0 +1,929 @@
+// Package repotest is a contract test suite for link storage backends.
+// Every storage must pass it so that the service behaves the same no
+// matter which backend is configured.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/healthcheck"
+	"github.com/Komilov31/url-shortener/internal/metadata"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Storage is everything the application needs from a storage backend.
+type Storage interface {
+	service.Storage
+	metadata.Storage
+	healthcheck.Storage
+}
+
+// Run runs the storage contract. newStorage must return an empty storage
+// for every call.
+func Run(t *testing.T, newStorage func(t *testing.T) Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, storage Storage)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"CreateExistingUrl", testCreateExistingUrl},
+		{"CreateExistingUrlWithOptions", testCreateExistingUrlWithOptions},
+		{"CreateExistingDisabledUrl", testCreateExistingDisabledUrl},
+		{"CreateDuplicateShortUrl", testCreateDuplicateShortUrl},
+		{"GetUnknownShortUrl", testGetUnknownShortUrl},
+		{"Analytics", testAnalytics},
+		{"AnalyticsWithoutRedirects", testAnalyticsWithoutRedirects},
+		{"RedirectForUnknownShortUrl", testRedirectForUnknownShortUrl},
+		{"AggregateByUserAgent", testAggregateByUserAgent},
+		{"AggregateByDateAndMonth", testAggregateByDateAndMonth},
+		{"AggregateByCampaign", testAggregateByCampaign},
+		{"AggregateByReferrer", testAggregateByReferrer},
+		{"AggregateByTag", testAggregateByTag},
+		{"Metadata", testMetadata},
+		{"Health", testHealth},
+		{"TargetingRules", testTargetingRules},
+		{"Variants", testVariants},
+		{"SearchLinks", testSearchLinks},
+		{"GetLink", testGetLink},
+		{"UpdateLinks", testUpdateLinks},
+		{"DeleteLink", testDeleteLink},
+		{"PurgeAnalytics", testPurgeAnalytics},
+		{"ApiKeys", testApiKeys},
+		{"IdempotencyKeys", testIdempotencyKeys},
+		{"ConcurrentIdempotencyKeys", testConcurrentIdempotencyKeys},
+		{"ConcurrentRedirects", testConcurrentRedirects},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+func createUrl(t *testing.T, storage Storage, urlInfo model.Url) *model.Url {
+	t.Helper()
+
+	if urlInfo.QueryPolicy == "" {
+		urlInfo.QueryPolicy = model.QueryPolicyNone
+	}
+	created, err := storage.CreateShortUrl(context.Background(), urlInfo)
+	require.NoError(t, err)
+	return created
+}
+
+func redirect(t *testing.T, storage Storage, redirectInfo model.RedirectInfo) {
+	t.Helper()
+
+	if redirectInfo.Source == "" {
+		redirectInfo.Source = model.SourceDirect
+	}
+	require.NoError(t, storage.CreateRedirectInfo(context.Background(), redirectInfo))
+}
+
+func testCreateAndGet(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	expires := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	created := createUrl(t, storage, model.Url{
+		Url:            "https://example.com/page",
+		ShortUrl:       "abc123",
+		FallbackUrl:    "https://example.com/fallback",
+		StickyVariants: true,
+		QueryPolicy:    model.QueryPolicyKeep,
+		Folder:         "marketing",
+		Tags:           []string{"email", "promo"},
+		Owner:          "alice",
+		ExpiresAt:      &expires,
+		Utm:            model.Utm{Source: "newsletter", Campaign: "spring"},
+		Rules: []model.TargetingRule{
+			{Position: 2, Os: "ios", Destination: "https://example.com/ios"},
+			{Position: 1, Device: "mobile", Destination: "https://example.com/mobile"},
+		},
+		Variants: []model.Variant{
+			{Name: "a", Url: "https://example.com/a", Weight: 1},
+			{Name: "b", Url: "https://example.com/b", Weight: 3},
+		},
+	})
+	assert.NotZero(t, created.Id)
+	assert.Equal(t, "abc123", created.ShortUrl)
+
+	urlInfo, err := storage.GetUrlByShort(ctx, "abc123", model.RedirectInfo{ShortUrl: "abc123"})
+	require.NoError(t, err)
+
+	assert.Equal(t, created.Id, urlInfo.Id)
+	assert.Equal(t, "https://example.com/page", urlInfo.Url)
+	assert.Equal(t, "https://example.com/fallback", urlInfo.FallbackUrl)
+	assert.True(t, urlInfo.StickyVariants)
+	assert.Equal(t, model.QueryPolicyKeep, urlInfo.QueryPolicy)
+	assert.Equal(t, model.Utm{Source: "newsletter", Campaign: "spring"}, urlInfo.Utm)
+	assert.False(t, urlInfo.Disabled)
+	assert.True(t, urlInfo.Healthy)
+	require.NotNil(t, urlInfo.ExpiresAt)
+	assert.True(t, expires.Equal(*urlInfo.ExpiresAt))
+
+	require.Len(t, urlInfo.Rules, 2)
+	assert.Equal(t, 1, urlInfo.Rules[0].Position)
+	assert.Equal(t, "https://example.com/mobile", urlInfo.Rules[0].Destination)
+	assert.Equal(t, "ios", urlInfo.Rules[1].Os)
+	assert.NotZero(t, urlInfo.Rules[0].Id)
+
+	require.Len(t, urlInfo.Variants, 2)
+	assert.Equal(t, "a", urlInfo.Variants[0].Name)
+	assert.Equal(t, 3, urlInfo.Variants[1].Weight)
+	assert.NotZero(t, urlInfo.Variants[0].Id)
+}
+
+func testCreateExistingUrl(t *testing.T, storage Storage) {
+	first := createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123"})
+
+	second := createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "xyz789"})
+
+	assert.Equal(t, first.Id, second.Id)
+	assert.Equal(t, "abc123", second.ShortUrl)
+}
+
+// testCreateExistingDisabledUrl: the existing link comes back with its
+// state, so that the service does not hand out a link that stopped
+// redirecting.
+func testCreateExistingDisabledUrl(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123", ExpiresAt: &expiresAt})
+	_, err := storage.SetDisabled(ctx, "abc123", true)
+	require.NoError(t, err)
+
+	second := createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "xyz789"})
+
+	assert.Equal(t, "abc123", second.ShortUrl)
+	assert.True(t, second.Disabled)
+	require.NotNil(t, second.ExpiresAt)
+	assert.WithinDuration(t, expiresAt, *second.ExpiresAt, time.Millisecond)
+}
+
+// testCreateExistingUrlWithOptions: the existing link comes back unchanged,
+// the options of the second request are not stored anywhere. The service
+// tells this apart by the short_url.
+func testCreateExistingUrlWithOptions(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123", Tags: []string{"promo"}})
+
+	second := createUrl(t, storage, model.Url{
+		Url:         "https://example.com",
+		ShortUrl:    "xyz789",
+		FallbackUrl: "https://example.org",
+		Rules:       []model.TargetingRule{{Os: "ios", Destination: "https://apps.apple.com"}},
+		Folder:      "marketing",
+		Tags:        []string{"email"},
+		Owner:       "alice",
+		QueryPolicy: model.QueryPolicyKeep,
+	})
+	assert.Equal(t, "abc123", second.ShortUrl)
+
+	link, err := storage.GetLink(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"promo"}, link.Tags)
+	assert.Empty(t, link.Folder)
+	assert.Empty(t, link.Owner)
+
+	rules, err := storage.GetTargetingRules(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = storage.GetLink(ctx, "xyz789")
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+}
+
+func testCreateDuplicateShortUrl(t *testing.T, storage Storage) {
+	createUrl(t, storage, model.Url{Url: "https://example.com/1", ShortUrl: "abc123"})
+
+	_, err := storage.CreateShortUrl(context.Background(), model.Url{
+		Url:         "https://example.com/2",
+		ShortUrl:    "abc123",
+		QueryPolicy: model.QueryPolicyNone,
+	})
+
+	assert.ErrorIs(t, err, repository.ErrUniqueConstraint)
+}
+
+func testGetUnknownShortUrl(t *testing.T, storage Storage) {
+	_, err := storage.GetUrlByShort(context.Background(), "missing", model.RedirectInfo{ShortUrl: "missing"})
+
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+}
+
+func testAnalytics(t *testing.T, storage Storage) {
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123"})
+	createUrl(t, storage, model.Url{Url: "https://example.org", ShortUrl: "other"})
+
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", UserAgent: "firefox", ReferrerDomain: "google.com", Variant: "a"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", UserAgent: "chrome", ReferrerDomain: "google.com", Variant: "b"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", UserAgent: "firefox", ReferrerDomain: "direct", Variant: "a", Source: model.SourceQr})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "other", UserAgent: "safari", ReferrerDomain: "direct"})
+
+	analytics, err := storage.GetAnalytics(context.Background(), "abc123")
+	require.NoError(t, err)
+	require.Len(t, analytics, 1)
+
+	info := analytics[0]
+	assert.Equal(t, "abc123", info.ShortUrl)
+	assert.Equal(t, "https://example.com", info.Url)
+	assert.Equal(t, 3, info.RedirectCount)
+	assert.Equal(t, 1, info.QrScans)
+	assert.Equal(t, []string{"chrome", "firefox"}, info.UserAgent)
+	assert.NotEmpty(t, info.RequestTime)
+	assert.Equal(t, []dto.VariantCount{
+		{Variant: "a", RedirectCount: 2},
+		{Variant: "b", RedirectCount: 1},
+	}, info.Variants)
+	assert.Equal(t, []dto.ReferrerCount{
+		{Referrer: "google.com", RedirectCount: 2},
+		{Referrer: "direct", RedirectCount: 1},
+	}, info.Referrers)
+}
+
+func testAnalyticsWithoutRedirects(t *testing.T, storage Storage) {
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123"})
+
+	analytics, err := storage.GetAnalytics(context.Background(), "abc123")
+
+	assert.NoError(t, err)
+	assert.Empty(t, analytics)
+}
+
+func testRedirectForUnknownShortUrl(t *testing.T, storage Storage) {
+	err := storage.CreateRedirectInfo(context.Background(), model.RedirectInfo{
+		ShortUrl: "missing",
+		Source:   model.SourceDirect,
+	})
+
+	assert.Error(t, err)
+}
+
+// createTaggedLinks creates two links in different folders with tags and
+// clicks, used by the filtered analytics tests.
+func createTaggedLinks(t *testing.T, storage Storage) {
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123", Folder: "marketing", Tags: []string{"email", "promo"}})
+	createUrl(t, storage, model.Url{Url: "https://example.org", ShortUrl: "xyz789", Folder: "sales", Tags: []string{"promo"}})
+
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", UserAgent: "firefox", ReferrerDomain: "google.com", VisitorId: "v1"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", UserAgent: "chrome", ReferrerDomain: "direct", VisitorId: "v1"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "xyz789", UserAgent: "safari", ReferrerDomain: "direct", VisitorId: "v2"})
+}
+
+func testAggregateByUserAgent(t *testing.T, storage Storage) {
+	createTaggedLinks(t, storage)
+	ctx := context.Background()
+
+	analytics, err := storage.AggregateByUserAgent(ctx, dto.LinkFilter{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []dto.UserAgentDTO{
+		{ShortUrl: "abc123", UserAgent: []string{"chrome", "firefox"}, RedirectCount: 2},
+		{ShortUrl: "xyz789", UserAgent: []string{"safari"}, RedirectCount: 1},
+	}, analytics)
+
+	analytics, err = storage.AggregateByUserAgent(ctx, dto.LinkFilter{Folder: "sales"})
+	require.NoError(t, err)
+	assert.Equal(t, []dto.UserAgentDTO{
+		{ShortUrl: "xyz789", UserAgent: []string{"safari"}, RedirectCount: 1},
+	}, analytics)
+
+	analytics, err = storage.AggregateByUserAgent(ctx, dto.LinkFilter{Tag: "missing"})
+	require.NoError(t, err)
+	assert.Empty(t, analytics)
+}
+
+func testAggregateByDateAndMonth(t *testing.T, storage Storage) {
+	createTaggedLinks(t, storage)
+	ctx := context.Background()
+
+	dates, err := storage.AggregateByDate(ctx, dto.LinkFilter{})
+	require.NoError(t, err)
+	total := 0
+	for _, date := range dates {
+		assert.Len(t, date.UrlInfo, date.RedirectCount)
+		total += date.RedirectCount
+	}
+	assert.Equal(t, 3, total)
+
+	months, err := storage.AggregateByMonth(ctx, dto.LinkFilter{Tag: "email"})
+	require.NoError(t, err)
+	total = 0
+	for _, month := range months {
+		for _, info := range month.UrlInfo {
+			assert.Equal(t, "abc123", info.ShortUrl)
+			assert.NotEmpty(t, info.Time)
+		}
+		total += month.RedirectCount
+	}
+	assert.Equal(t, 2, total)
+}
+
+func testAggregateByCampaign(t *testing.T, storage Storage) {
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123", Folder: "marketing"})
+	createUrl(t, storage, model.Url{Url: "https://example.org", ShortUrl: "xyz789"})
+
+	spring := model.Utm{Source: "newsletter", Medium: "email", Campaign: "spring"}
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", Utm: spring, VisitorId: "v1"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", Utm: spring, VisitorId: "v1"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", Utm: spring, VisitorId: "v2"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", Utm: spring})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "xyz789", Utm: model.Utm{Source: "ads"}, VisitorId: "v3"})
+
+	// Clicks happened seconds ago, so they fall into the current or, right
+	// at midnight, the previous month.
+	analytics, err := storage.AggregateByCampaign(context.Background(), "month", dto.LinkFilter{Folder: "marketing"})
+	require.NoError(t, err)
+	require.NotEmpty(t, analytics)
+
+	clicks, uniques := 0, 0
+	for _, campaign := range analytics {
+		assert.Equal(t, "newsletter", campaign.Source)
+		assert.Equal(t, "email", campaign.Medium)
+		assert.Equal(t, "spring", campaign.Campaign)
+		assert.Equal(t, 1, campaign.Period.Day())
+		assert.WithinDuration(t, time.Now().UTC(), campaign.Period, 32*24*time.Hour)
+		clicks += campaign.Clicks
+		uniques += campaign.Uniques
+	}
+	assert.Equal(t, 4, clicks)
+	if len(analytics) == 1 {
+		assert.Equal(t, 2, uniques)
+	}
+
+	analytics, err = storage.AggregateByCampaign(context.Background(), "day", dto.LinkFilter{})
+	require.NoError(t, err)
+	clicks = 0
+	for _, campaign := range analytics {
+		assert.Zero(t, campaign.Period.Hour())
+		clicks += campaign.Clicks
+	}
+	assert.Equal(t, 5, clicks)
+}
+
+func testAggregateByReferrer(t *testing.T, storage Storage) {
+	createTaggedLinks(t, storage)
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123", ReferrerDomain: "google.com"})
+
+	analytics, err := storage.AggregateByReferrer(context.Background(), dto.LinkFilter{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []dto.ReferrerDTO{
+		{
+			ShortUrl: "abc123",
+			Referrers: []dto.ReferrerCount{
+				{Referrer: "google.com", RedirectCount: 2},
+				{Referrer: "direct", RedirectCount: 1},
+			},
+			RedirectCount: 3,
+		},
+		{
+			ShortUrl:      "xyz789",
+			Referrers:     []dto.ReferrerCount{{Referrer: "direct", RedirectCount: 1}},
+			RedirectCount: 1,
+		},
+	}, analytics)
+}
+
+func testAggregateByTag(t *testing.T, storage Storage) {
+	createTaggedLinks(t, storage)
+	createUrl(t, storage, model.Url{Url: "https://example.net", ShortUrl: "quiet", Tags: []string{"archive"}})
+	ctx := context.Background()
+
+	analytics, err := storage.AggregateByTag(ctx, dto.LinkFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []dto.TagDTO{
+		{Tag: "promo", Links: 2, RedirectCount: 3, Uniques: 2},
+		{Tag: "email", Links: 1, RedirectCount: 2, Uniques: 1},
+		{Tag: "archive", Links: 1, RedirectCount: 0, Uniques: 0},
+	}, analytics)
+
+	analytics, err = storage.AggregateByTag(ctx, dto.LinkFilter{Folder: "sales"})
+	require.NoError(t, err)
+	assert.Equal(t, []dto.TagDTO{
+		{Tag: "promo", Links: 1, RedirectCount: 1, Uniques: 1},
+	}, analytics)
+}
+
+func testMetadata(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123"})
+
+	_, err := storage.GetUrlMetadata(ctx, "abc123")
+	assert.ErrorIs(t, err, repository.ErrMetadataNotFound)
+
+	fetchedAt := time.Now().UTC().Truncate(time.Second)
+	metadata := model.UrlMetadata{
+		ShortUrl:  "abc123",
+		Title:     "Example",
+		Status:    model.MetadataStatusOk,
+		Attempts:  1,
+		FetchedAt: fetchedAt,
+	}
+	require.NoError(t, storage.SaveUrlMetadata(ctx, metadata))
+
+	metadata.Title = "Example Domain"
+	metadata.Attempts = 2
+	require.NoError(t, storage.SaveUrlMetadata(ctx, metadata))
+
+	saved, err := storage.GetUrlMetadata(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "Example Domain", saved.Title)
+	assert.Equal(t, 2, saved.Attempts)
+	assert.True(t, fetchedAt.Equal(saved.FetchedAt))
+
+	err = storage.SaveUrlMetadata(ctx, model.UrlMetadata{ShortUrl: "missing", Status: model.MetadataStatusOk})
+	assert.Error(t, err)
+}
+
+func testHealth(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	first := createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123", FallbackUrl: "https://example.com/fallback"})
+	second := createUrl(t, storage, model.Url{Url: "https://example.org", ShortUrl: "xyz789"})
+
+	urls, err := storage.ListUrls(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Url{
+		{Id: first.Id, ShortUrl: "abc123", Url: "https://example.com", FallbackUrl: "https://example.com/fallback", Healthy: true},
+		{Id: second.Id, ShortUrl: "xyz789", Url: "https://example.org", Healthy: true},
+	}, urls)
+
+	checkedAt := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, storage.SaveUrlHealth(ctx, model.UrlHealth{ShortUrl: "abc123", StatusCode: 500, Healthy: false, Failures: 3, CheckedAt: checkedAt}))
+	require.NoError(t, storage.SaveUrlHealth(ctx, model.UrlHealth{ShortUrl: "xyz789", StatusCode: 404, Healthy: false, CheckedAt: checkedAt.Add(time.Minute)}))
+	require.NoError(t, storage.SaveUrlHealth(ctx, model.UrlHealth{ShortUrl: "xyz789", StatusCode: 200, Healthy: true, CheckedAt: checkedAt.Add(time.Minute)}))
+
+	unhealthy, err := storage.GetUnhealthyUrls(ctx)
+	require.NoError(t, err)
+	require.Len(t, unhealthy, 1)
+	assert.Equal(t, "abc123", unhealthy[0].ShortUrl)
+	assert.Equal(t, "https://example.com", unhealthy[0].Url)
+	assert.Equal(t, 500, unhealthy[0].StatusCode)
+	assert.Equal(t, 3, unhealthy[0].Failures)
+	assert.True(t, checkedAt.Equal(unhealthy[0].CheckedAt))
+
+	urls, err = storage.ListUrls(ctx)
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	assert.False(t, urls[0].Healthy)
+	assert.Equal(t, 3, urls[0].HealthFailures)
+	assert.True(t, urls[1].Healthy)
+	assert.Zero(t, urls[1].HealthFailures)
+
+	urlInfo, err := storage.GetUrlByShort(ctx, "abc123", model.RedirectInfo{ShortUrl: "abc123"})
+	require.NoError(t, err)
+	assert.False(t, urlInfo.Healthy)
+}
+
+func testTargetingRules(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	createUrl(t, storage, model.Url{
+		Url:      "https://example.com",
+		ShortUrl: "abc123",
+		Rules:    []model.TargetingRule{{Position: 1, Os: "ios", Destination: "https://example.com/ios"}},
+	})
+
+	saved, err := storage.SetTargetingRules(ctx, "abc123", []model.TargetingRule{
+		{Position: 1, Country: "DE", Destination: "https://example.de"},
+		{Position: 2, Language: "fr", Destination: "https://example.fr"},
+	})
+	require.NoError(t, err)
+	require.Len(t, saved, 2)
+	assert.NotZero(t, saved[0].Id)
+
+	rules, err := storage.GetTargetingRules(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, saved, rules)
+	assert.Equal(t, "DE", rules[0].Country)
+	assert.Equal(t, "fr", rules[1].Language)
+
+	rules, err = storage.SetTargetingRules(ctx, "abc123", nil)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = storage.SetTargetingRules(ctx, "missing", nil)
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+}
+
+func testVariants(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123"})
+
+	variants, err := storage.GetVariants(ctx, "abc123")
+	require.NoError(t, err)
+	assert.False(t, variants.Sticky)
+	assert.Empty(t, variants.Variants)
+
+	saved, err := storage.SetVariants(ctx, dto.VariantsDTO{
+		ShortUrl: "abc123",
+		Sticky:   true,
+		Variants: []model.Variant{
+			{Name: "a", Url: "https://example.com/a", Weight: 1},
+			{Name: "b", Url: "https://example.com/b", Weight: 2},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, saved.Variants, 2)
+	assert.NotZero(t, saved.Variants[0].Id)
+
+	variants, err = storage.GetVariants(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, saved, variants)
+
+	_, err = storage.GetVariants(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+	_, err = storage.SetVariants(ctx, dto.VariantsDTO{ShortUrl: "missing"})
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+}
+
+func shortUrls(links []dto.LinkDTO) []string {
+	short := []string{}
+	for _, link := range links {
+		short = append(short, link.ShortUrl)
+	}
+	return short
+}
+
+func testSearchLinks(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	expired := time.Now().UTC().Add(-time.Hour)
+
+	createUrl(t, storage, model.Url{Url: "https://example.com/spring-sale", ShortUrl: "first", Owner: "alice", Tags: []string{"promo"}})
+	createUrl(t, storage, model.Url{Url: "https://example.org/docs", ShortUrl: "second", Owner: "bob"})
+	createUrl(t, storage, model.Url{Url: "https://example.net/old", ShortUrl: "third", Owner: "alice", ExpiresAt: &expired})
+	require.NoError(t, storage.SaveUrlMetadata(ctx, model.UrlMetadata{ShortUrl: "second", Title: "API Reference", Status: model.MetadataStatusOk, FetchedAt: time.Now()}))
+
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "second"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "second"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "first"})
+
+	search := func(query dto.LinkQuery) []dto.LinkDTO {
+		t.Helper()
+		if query.Sort == "" {
+			query.Sort = dto.LinkSortCreated
+		}
+		if query.Order == "" {
+			query.Order = dto.LinkOrderAsc
+		}
+		if query.Limit == 0 {
+			query.Limit = 50
+		}
+		links, err := storage.SearchLinks(ctx, query)
+		require.NoError(t, err)
+		return links
+	}
+
+	assert.Equal(t, []string{"first", "second", "third"}, shortUrls(search(dto.LinkQuery{})))
+	assert.Equal(t, []string{"third", "second", "first"}, shortUrls(search(dto.LinkQuery{Order: dto.LinkOrderDesc})))
+	assert.Equal(t, []string{"first"}, shortUrls(search(dto.LinkQuery{Search: "SPRING"})))
+	assert.Equal(t, []string{"second"}, shortUrls(search(dto.LinkQuery{Search: "reference"})))
+	assert.Equal(t, []string{"first"}, shortUrls(search(dto.LinkQuery{Search: "prom"})))
+	assert.Equal(t, []string{}, shortUrls(search(dto.LinkQuery{Search: "%"})))
+	assert.Equal(t, []string{"first", "third"}, shortUrls(search(dto.LinkQuery{Owner: "alice"})))
+	assert.Equal(t, []string{"third"}, shortUrls(search(dto.LinkQuery{Status: model.LinkStatusExpired})))
+	assert.Equal(t, []string{"first", "second"}, shortUrls(search(dto.LinkQuery{Status: model.LinkStatusActive})))
+	assert.Equal(t, []string{"first"}, shortUrls(search(dto.LinkQuery{LinkFilter: dto.LinkFilter{Tag: "promo"}})))
+	assert.Equal(t, []string{"second"}, shortUrls(search(dto.LinkQuery{Offset: 1, Limit: 1})))
+
+	minClicks, maxClicks := 1, 1
+	assert.Equal(t, []string{"first"}, shortUrls(search(dto.LinkQuery{MinClicks: &minClicks, MaxClicks: &maxClicks})))
+
+	byClicks := search(dto.LinkQuery{Sort: dto.LinkSortClicks, Order: dto.LinkOrderDesc})
+	assert.Equal(t, []string{"second", "first", "third"}, shortUrls(byClicks))
+	assert.Equal(t, 2, byClicks[0].Clicks)
+	assert.Equal(t, "API Reference", byClicks[0].Title)
+	assert.NotNil(t, byClicks[0].LastClick)
+	assert.Nil(t, byClicks[2].LastClick)
+	assert.Equal(t, model.LinkStatusExpired, byClicks[2].Status)
+	assert.Equal(t, []string{}, byClicks[2].Tags)
+
+	byLastClick := search(dto.LinkQuery{Sort: dto.LinkSortLastClick, Order: dto.LinkOrderAsc})
+	assert.Equal(t, "third", byLastClick[2].ShortUrl)
+
+	createdFrom := byClicks[0].CreatedAt
+	assert.Equal(t, []string{"second", "third"}, shortUrls(search(dto.LinkQuery{CreatedFrom: &createdFrom})))
+}
+
+func testGetLink(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123", Folder: "marketing", Tags: []string{"promo"}, Owner: "alice"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123"})
+
+	link, err := storage.GetLink(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", link.ShortUrl)
+	assert.Equal(t, "https://example.com", link.Url)
+	assert.Equal(t, "marketing", link.Folder)
+	assert.Equal(t, []string{"promo"}, link.Tags)
+	assert.Equal(t, "alice", link.Owner)
+	assert.Equal(t, model.LinkStatusActive, link.Status)
+	assert.Equal(t, 2, link.Clicks)
+	assert.NotNil(t, link.LastClick)
+
+	_, err = storage.GetLink(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+}
+
+func testUpdateLinks(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123", Tags: []string{"old"}})
+
+	link, err := storage.SetTags(ctx, "abc123", []string{"promo", "email", "promo"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"email", "promo"}, link.Tags)
+
+	link, err = storage.SetFolder(ctx, "abc123", "marketing")
+	require.NoError(t, err)
+	assert.Equal(t, "marketing", link.Folder)
+	assert.Equal(t, []string{"email", "promo"}, link.Tags)
+
+	link, err = storage.SetDisabled(ctx, "abc123", true)
+	require.NoError(t, err)
+	assert.Equal(t, model.LinkStatusDisabled, link.Status)
+
+	urlInfo, err := storage.GetUrlByShort(ctx, "abc123", model.RedirectInfo{ShortUrl: "abc123"})
+	require.NoError(t, err)
+	assert.True(t, urlInfo.Disabled)
+
+	link, err = storage.SetDisabled(ctx, "abc123", false)
+	require.NoError(t, err)
+	assert.Equal(t, model.LinkStatusActive, link.Status)
+
+	_, err = storage.SetTags(ctx, "missing", nil)
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+	_, err = storage.SetFolder(ctx, "missing", "")
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+	_, err = storage.SetDisabled(ctx, "missing", true)
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+}
+
+func testDeleteLink(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	created := createUrl(t, storage, model.Url{
+		Url:         "https://example.com",
+		ShortUrl:    "abc123",
+		FallbackUrl: "https://example.com/fallback",
+		Tags:        []string{"promo"},
+		Rules:       []model.TargetingRule{{Position: 1, Os: "ios", Destination: "https://example.com/ios"}},
+		Variants:    []model.Variant{{Name: "a", Url: "https://example.com/a", Weight: 1}},
+	})
+	createUrl(t, storage, model.Url{Url: "https://example.org", ShortUrl: "xyz789"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "xyz789"})
+	require.NoError(t, storage.SaveUrlMetadata(ctx, model.UrlMetadata{ShortUrl: "abc123", Status: model.MetadataStatusOk}))
+	require.NoError(t, storage.SaveUrlHealth(ctx, model.UrlHealth{ShortUrl: "abc123", StatusCode: 200, Healthy: true, CheckedAt: time.Now().UTC()}))
+
+	deleted, err := storage.DeleteLink(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, &model.Url{
+		Id:          created.Id,
+		ShortUrl:    "abc123",
+		Url:         "https://example.com",
+		FallbackUrl: "https://example.com/fallback",
+	}, deleted)
+
+	_, err = storage.GetUrlByShort(ctx, "abc123", model.RedirectInfo{ShortUrl: "abc123"})
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+	_, err = storage.GetUrlMetadata(ctx, "abc123")
+	assert.ErrorIs(t, err, repository.ErrMetadataNotFound)
+
+	links, err := storage.SearchLinks(ctx, dto.LinkQuery{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"xyz789"}, shortUrls(links))
+
+	analytics, err := storage.AggregateByUserAgent(ctx, dto.LinkFilter{})
+	require.NoError(t, err)
+	require.Len(t, analytics, 1)
+	assert.Equal(t, "xyz789", analytics[0].ShortUrl)
+
+	// The alias can be reused once the link is gone.
+	createUrl(t, storage, model.Url{Url: "https://example.net", ShortUrl: "abc123"})
+
+	_, err = storage.DeleteLink(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+}
+
+func testPurgeAnalytics(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123"})
+	createUrl(t, storage, model.Url{Url: "https://example.org", ShortUrl: "xyz789"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "abc123"})
+	redirect(t, storage, model.RedirectInfo{ShortUrl: "xyz789"})
+
+	purged, err := storage.PurgeAnalytics(ctx, "", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	purged, err = storage.PurgeAnalytics(ctx, "abc123", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+
+	analytics, err := storage.GetAnalytics(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Empty(t, analytics)
+	analytics, err = storage.GetAnalytics(ctx, "xyz789")
+	require.NoError(t, err)
+	require.Len(t, analytics, 1)
+	assert.Equal(t, 1, analytics[0].RedirectCount)
+
+	purged, err = storage.PurgeAnalytics(ctx, "", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+}
+
+func testApiKeys(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	keys, err := storage.ListApiKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	first, err := storage.CreateApiKey(ctx, model.ApiKey{Name: "ci", Prefix: "us_first", KeyHash: "hash-1"})
+	require.NoError(t, err)
+	assert.NotZero(t, first.Id)
+	assert.False(t, first.CreatedAt.IsZero())
+	assert.Nil(t, first.RevokedAt)
+
+	second, err := storage.CreateApiKey(ctx, model.ApiKey{Name: "backup", Prefix: "us_second", KeyHash: "hash-2"})
+	require.NoError(t, err)
+
+	_, err = storage.CreateApiKey(ctx, model.ApiKey{Name: "copy", Prefix: "us_copy", KeyHash: "hash-1"})
+	assert.Error(t, err)
+
+	revoked, err := storage.RevokeApiKey(ctx, first.Id)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+	assert.Equal(t, "ci", revoked.Name)
+
+	again, err := storage.RevokeApiKey(ctx, first.Id)
+	require.NoError(t, err)
+	require.NotNil(t, again.RevokedAt)
+	assert.True(t, revoked.RevokedAt.Equal(*again.RevokedAt))
+
+	keys, err = storage.ListApiKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, first.Id, keys[0].Id)
+	assert.Equal(t, "hash-1", keys[0].KeyHash)
+	assert.NotNil(t, keys[0].RevokedAt)
+	assert.Equal(t, second.Id, keys[1].Id)
+	assert.Nil(t, keys[1].RevokedAt)
+
+	found, err := storage.GetApiKeyByHash(ctx, "hash-2")
+	require.NoError(t, err)
+	assert.Equal(t, second.Id, found.Id)
+	assert.Equal(t, "backup", found.Name)
+	found, err = storage.GetApiKeyByHash(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.NotNil(t, found.RevokedAt)
+	_, err = storage.GetApiKeyByHash(ctx, "hash-3")
+	assert.ErrorIs(t, err, repository.ErrApiKeyNotFound)
+
+	_, err = storage.RevokeApiKey(ctx, 1000)
+	assert.ErrorIs(t, err, repository.ErrApiKeyNotFound)
+}
+
+func testIdempotencyKeys(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	claimed, ok, err := storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "retry-1", RequestHash: "hash-1", Token: "token-1"}, time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "retry-1", claimed.Key)
+	assert.Equal(t, "token-1", claimed.Token)
+	assert.Empty(t, claimed.Response)
+	assert.True(t, claimed.ExpiresAt.After(claimed.CreatedAt))
+
+	existing, ok, err := storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "retry-1", RequestHash: "hash-2", Token: "token-2"}, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "hash-1", existing.RequestHash)
+	assert.Empty(t, existing.Response)
+
+	require.NoError(t, storage.CompleteIdempotencyKey(ctx, "retry-1", "token-1", `{"short_url":"abc123"}`, time.Hour))
+	assert.ErrorIs(t, storage.CompleteIdempotencyKey(ctx, "missing", "token-1", "{}", time.Hour), repository.ErrIdempotencyKeyNotFound)
+
+	// A completed key survives a release, only in progress ones go.
+	require.NoError(t, storage.ReleaseIdempotencyKey(ctx, "retry-1", "token-1"))
+	existing, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "retry-1", RequestHash: "hash-1", Token: "token-1"}, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, `{"short_url":"abc123"}`, existing.Response)
+
+	_, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "retry-2", RequestHash: "hash-1", Token: "token-1"}, time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, storage.ReleaseIdempotencyKey(ctx, "retry-2", "token-1"))
+	_, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "retry-2", RequestHash: "hash-2", Token: "token-2"}, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Completing a key keeps the response for the TTL, not for the lease
+	// the key was claimed with.
+	_, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "leased", RequestHash: "hash-1", Token: "token-1"}, -time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, storage.CompleteIdempotencyKey(ctx, "leased", "token-1", "{}", time.Hour))
+	existing, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "leased", RequestHash: "hash-2", Token: "token-2"}, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "{}", existing.Response)
+
+	// A request whose lease ran out and whose key was taken over can
+	// neither complete nor release the key of the request that took it.
+	_, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "taken-over", RequestHash: "hash-1", Token: "token-1"}, -time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "taken-over", RequestHash: "hash-1", Token: "token-2"}, time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.ErrorIs(t, storage.CompleteIdempotencyKey(ctx, "taken-over", "token-1", "{}", time.Hour), repository.ErrIdempotencyKeyNotFound)
+	require.NoError(t, storage.ReleaseIdempotencyKey(ctx, "taken-over", "token-1"))
+	require.NoError(t, storage.CompleteIdempotencyKey(ctx, "taken-over", "token-2", `{"short_url":"def456"}`, time.Hour))
+	assert.ErrorIs(t, storage.CompleteIdempotencyKey(ctx, "taken-over", "token-2", "{}", time.Hour), repository.ErrIdempotencyKeyNotFound)
+	existing, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "taken-over", RequestHash: "hash-1", Token: "token-3"}, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "token-2", existing.Token)
+	assert.Equal(t, `{"short_url":"def456"}`, existing.Response)
+
+	// Expired keys can be claimed again and are purged.
+	_, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "expired", RequestHash: "hash-1", Token: "token-1"}, -time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, storage.CompleteIdempotencyKey(ctx, "expired", "token-1", "{}", -time.Second))
+	claimed, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "expired", RequestHash: "hash-2", Token: "token-2"}, -time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "hash-2", claimed.RequestHash)
+	assert.Empty(t, claimed.Response)
+
+	deleted, err := storage.PurgeIdempotencyKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	_, ok, err = storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "retry-1", RequestHash: "hash-1", Token: "token-1"}, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func testConcurrentIdempotencyKeys(t *testing.T, storage Storage) {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claims := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := storage.ClaimIdempotencyKey(ctx, model.IdempotencyKey{Key: "retry", RequestHash: "hash", Token: "token"}, time.Hour)
+			assert.NoError(t, err)
+			if ok {
+				mu.Lock()
+				claims++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, claims)
+}
+
+func testConcurrentRedirects(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	createUrl(t, storage, model.Url{Url: "https://example.com", ShortUrl: "abc123"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := storage.CreateRedirectInfo(ctx, model.RedirectInfo{
+				ShortUrl:  "abc123",
+				UserAgent: fmt.Sprintf("agent-%d", i%5),
+				Source:    model.SourceDirect,
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	analytics, err := storage.GetAnalytics(ctx, "abc123")
+	require.NoError(t, err)
+	require.Len(t, analytics, 1)
+	assert.Equal(t, 50, analytics[0].RedirectCount)
+	assert.Len(t, analytics[0].UserAgent, 5)
+}
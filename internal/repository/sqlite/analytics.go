@@ -0,0 +1,274 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+)
+
+// periodColumns maps campaign periods to date expressions truncating
+// request_time like DATE_TRUNC does, weeks start on Monday.
+var periodColumns = map[string]string{
+	"day":   "date(request_time)",
+	"week":  "date(request_time, '-6 days', 'weekday 1')",
+	"month": "date(request_time, 'start of month')",
+}
+
+func (r *Repository) AggregateByUserAgent(ctx context.Context, filter dto.LinkFilter) ([]dto.UserAgentDTO, error) {
+	query := `SELECT short_url, COUNT(short_url) AS count,
+	json_group_array(DISTINCT user_agent ORDER BY user_agent) AS user_agent
+	FROM redirect_analytics
+	WHERE ` + filterCondition("short_url", 1) + `
+	GROUP BY short_url;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		filter.Folder,
+		filter.Tag,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
+	}
+	defer rows.Close()
+
+	var analytics []dto.UserAgentDTO
+	for rows.Next() {
+		var next dto.UserAgentDTO
+		if err := rows.Scan(&next.ShortUrl, &next.RedirectCount, jsonArray(&next.UserAgent)); err != nil {
+			return nil, fmt.Errorf("could not scan aggregated data from db: %w", err)
+		}
+		analytics = append(analytics, next)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return analytics, nil
+}
+
+func (r *Repository) AggregateByDate(ctx context.Context, filter dto.LinkFilter) ([]dto.DateDTO, error) {
+	query := `SELECT COUNT(short_url),
+	CAST(strftime('%d', request_time) AS INTEGER) AS day,
+	CAST(strftime('%m', request_time) AS INTEGER) AS month,
+	CAST(strftime('%Y', request_time) AS INTEGER) AS year,
+	json_group_array(short_url ORDER BY request_time) AS short_urls,
+	json_group_array(request_time ORDER BY request_time) AS request_times
+	FROM redirect_analytics
+	WHERE ` + filterCondition("short_url", 1) + `
+	GROUP BY day, month, year;`
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		filter.Folder,
+		filter.Tag,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
+	}
+	defer rows.Close()
+
+	var analytics []dto.DateDTO
+	for rows.Next() {
+		var time, short_url []string
+		var next dto.DateDTO
+		err := rows.Scan(
+			&next.RedirectCount,
+			&next.Day,
+			&next.Month,
+			&next.Year,
+			jsonArray(&short_url),
+			jsonArray(&time),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan aggregated data from db: %w", err)
+		}
+
+		next.UrlInfo = urlInfo(short_url, time)
+		analytics = append(analytics, next)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return analytics, nil
+}
+
+func (r *Repository) AggregateByMonth(ctx context.Context, filter dto.LinkFilter) ([]dto.MonthDTO, error) {
+	query := `SELECT COUNT(short_url),
+	CAST(strftime('%m', request_time) AS INTEGER) AS month,
+	CAST(strftime('%Y', request_time) AS INTEGER) AS year,
+	json_group_array(short_url ORDER BY request_time) AS short_urls,
+	json_group_array(request_time ORDER BY request_time) AS request_times
+	FROM redirect_analytics
+	WHERE ` + filterCondition("short_url", 1) + `
+	GROUP BY month, year;`
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		filter.Folder,
+		filter.Tag,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
+	}
+	defer rows.Close()
+
+	var analytics []dto.MonthDTO
+	for rows.Next() {
+		var time, short_url []string
+		var next dto.MonthDTO
+		err := rows.Scan(
+			&next.RedirectCount,
+			&next.Month,
+			&next.Year,
+			jsonArray(&short_url),
+			jsonArray(&time),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan aggregated data from db: %w", err)
+		}
+
+		for _, info := range urlInfo(short_url, time) {
+			next.UrlInfo = append(next.UrlInfo, info)
+		}
+		analytics = append(analytics, next)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return analytics, nil
+}
+
+func urlInfo(short_url, time []string) []dto.UrlInfo {
+	var info []dto.UrlInfo
+	for i := range short_url {
+		next := dto.UrlInfo{ShortUrl: short_url[i]}
+		if i < len(time) {
+			next.Time = time[i]
+		}
+		info = append(info, next)
+	}
+	return info
+}
+
+// AggregateByCampaign counts clicks and unique visitors per utm source,
+// medium and campaign. period is day, week or month.
+func (r *Repository) AggregateByCampaign(ctx context.Context, period string, filter dto.LinkFilter) ([]dto.CampaignDTO, error) {
+	periodColumn, ok := periodColumns[period]
+	if !ok {
+		return nil, fmt.Errorf("could not aggregate campaigns: unknown period %q", period)
+	}
+
+	query := `SELECT ` + periodColumn + ` AS period,
+	utm_source, utm_medium, utm_campaign,
+	COUNT(*) AS clicks,
+	COUNT(DISTINCT NULLIF(visitor_id, '')) AS uniques
+	FROM redirect_analytics
+	WHERE (utm_source <> '' OR utm_medium <> '' OR utm_campaign <> '')
+	AND ` + filterCondition("short_url", 1) + `
+	GROUP BY period, utm_source, utm_medium, utm_campaign
+	ORDER BY period, clicks DESC;`
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		filter.Folder,
+		filter.Tag,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
+	}
+	defer rows.Close()
+
+	var analytics []dto.CampaignDTO
+	for rows.Next() {
+		var next dto.CampaignDTO
+		var start string
+		err := rows.Scan(
+			&start,
+			&next.Source,
+			&next.Medium,
+			&next.Campaign,
+			&next.Clicks,
+			&next.Uniques,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan aggregated data from db: %w", err)
+		}
+		next.Period, err = time.Parse(time.DateOnly, start)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse campaign period: %w", err)
+		}
+		analytics = append(analytics, next)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return analytics, nil
+}
+
+func (r *Repository) AggregateByReferrer(ctx context.Context, filter dto.LinkFilter) ([]dto.ReferrerDTO, error) {
+	query := `SELECT short_url, referrer_domain, COUNT(*) AS count
+	FROM redirect_analytics
+	WHERE ` + filterCondition("short_url", 1) + `
+	GROUP BY short_url, referrer_domain
+	ORDER BY short_url, count DESC, referrer_domain;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		filter.Folder,
+		filter.Tag,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
+	}
+	defer rows.Close()
+
+	var analytics []dto.ReferrerDTO
+	for rows.Next() {
+		var short_url string
+		var referrer dto.ReferrerCount
+		if err := rows.Scan(&short_url, &referrer.Referrer, &referrer.RedirectCount); err != nil {
+			return nil, fmt.Errorf("could not scan aggregated data from db: %w", err)
+		}
+
+		if len(analytics) == 0 || analytics[len(analytics)-1].ShortUrl != short_url {
+			analytics = append(analytics, dto.ReferrerDTO{ShortUrl: short_url})
+		}
+		last := &analytics[len(analytics)-1]
+		last.Referrers = append(last.Referrers, referrer)
+		last.RedirectCount += referrer.RedirectCount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return analytics, nil
+}
+
+// PurgeAnalytics deletes clicks recorded before the given time, of one link
+// or of all links when short_url is empty. It returns the number of
+// deleted clicks.
+func (r *Repository) PurgeAnalytics(ctx context.Context, short_url string, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM redirect_analytics WHERE ($1 = '' OR short_url = $1) AND request_time < $2",
+		short_url,
+		timestamp(before.UTC()),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete redirect info from db: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get deleted rows count: %w", err)
+	}
+
+	return int(deleted), nil
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) CreateShortUrl(ctx context.Context, urlInfo model.Url) (*model.Url, error) {
+	query := "SELECT id, short_url, url, fallback_url, disabled, expires_at FROM urls WHERE url=$1"
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		urlInfo.Url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get url info from db: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var existing model.Url
+		err = rows.Scan(&existing.Id, &existing.ShortUrl, &existing.Url, &existing.FallbackUrl, &existing.Disabled, &existing.ExpiresAt)
+		if err == nil {
+			return &existing, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+	rows.Close()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
+
+	query = `INSERT INTO urls(url, short_url, fallback_url, sticky_variants, query_policy,
+	utm_source, utm_medium, utm_campaign, utm_term, utm_content, folder, owner, expires_at, created_at)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		urlInfo.Url,
+		urlInfo.ShortUrl,
+		urlInfo.FallbackUrl,
+		urlInfo.StickyVariants,
+		urlInfo.QueryPolicy,
+		urlInfo.Utm.Source,
+		urlInfo.Utm.Medium,
+		urlInfo.Utm.Campaign,
+		urlInfo.Utm.Term,
+		urlInfo.Utm.Content,
+		urlInfo.Folder,
+		urlInfo.Owner,
+		timestampPtr(urlInfo.ExpiresAt),
+		r.timestamp(),
+	).Scan(&urlInfo.Id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, repository.ErrUniqueConstraint
+		}
+		return nil, fmt.Errorf("could not save url info in db: %w", err)
+	}
+
+	if err := insertRules(ctx, tx, urlInfo.ShortUrl, urlInfo.Rules); err != nil {
+		return nil, err
+	}
+
+	if err := insertVariants(ctx, tx, urlInfo.ShortUrl, urlInfo.Variants); err != nil {
+		return nil, err
+	}
+
+	if err := insertTags(ctx, tx, urlInfo.ShortUrl, urlInfo.Tags); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return &urlInfo, nil
+}
+
+func (r *Repository) CreateRedirectInfo(ctx context.Context, redirectInfo model.RedirectInfo) error {
+	query := `INSERT INTO redirect_analytics (short_url, user_agent, source, matched_rule, variant,
+	utm_source, utm_medium, utm_campaign, utm_term, utm_content, visitor_id, referrer, referrer_domain,
+	request_time)
+	VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);`
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		redirectInfo.ShortUrl,
+		redirectInfo.UserAgent,
+		redirectInfo.Source,
+		redirectInfo.MatchedRule,
+		redirectInfo.Variant,
+		redirectInfo.Utm.Source,
+		redirectInfo.Utm.Medium,
+		redirectInfo.Utm.Campaign,
+		redirectInfo.Utm.Term,
+		redirectInfo.Utm.Content,
+		redirectInfo.VisitorId,
+		redirectInfo.Referrer,
+		redirectInfo.ReferrerDomain,
+		r.timestamp(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert redirect info to db: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) GetUrlByShort(ctx context.Context, short_url string, redirectInfo model.RedirectInfo) (*model.Url, error) {
+	// Read only transactions are deferred, they do not take the write lock
+	// and redirects do not wait for each other.
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT u.id, u.short_url, u.url, u.fallback_url, u.sticky_variants, u.query_policy,
+	u.utm_source, u.utm_medium, u.utm_campaign, u.utm_term, u.utm_content,
+	u.expires_at, u.disabled, COALESCE(h.healthy, TRUE)
+	FROM urls u
+	LEFT JOIN url_health h ON h.short_url = u.short_url
+	WHERE u.short_url=$1`
+	rows, err := tx.QueryContext(
+		ctx,
+		query,
+		short_url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get alias from db: %w", err)
+	}
+	defer rows.Close()
+
+	var urlInfo model.Url
+	hasNext := false
+	for rows.Next() {
+		err = rows.Scan(
+			&urlInfo.Id,
+			&urlInfo.ShortUrl,
+			&urlInfo.Url,
+			&urlInfo.FallbackUrl,
+			&urlInfo.StickyVariants,
+			&urlInfo.QueryPolicy,
+			&urlInfo.Utm.Source,
+			&urlInfo.Utm.Medium,
+			&urlInfo.Utm.Campaign,
+			&urlInfo.Utm.Term,
+			&urlInfo.Utm.Content,
+			&urlInfo.ExpiresAt,
+			&urlInfo.Disabled,
+			&urlInfo.Healthy,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan rows result: %w", err)
+		}
+		hasNext = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+	rows.Close()
+
+	if !hasNext {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	urlInfo.Rules, err = queryRules(ctx, tx, short_url)
+	if err != nil {
+		return nil, err
+	}
+
+	urlInfo.Variants, err = queryVariants(ctx, tx, short_url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return &urlInfo, nil
+}
+
+func (r *Repository) GetAnalytics(ctx context.Context, short_url string) ([]dto.RedirectInfo, error) {
+	query := `SELECT r.short_url, u.url, COUNT(r.short_url) as redirect_count,
+	COUNT(r.short_url) FILTER (WHERE r.source = 'qr') AS qr_scans,
+	json_group_array(DISTINCT r.user_agent ORDER BY r.user_agent) AS all_user_agents,
+	json_group_array(DISTINCT r.request_time ORDER BY r.request_time) AS all_request_times
+	FROM redirect_analytics r
+	JOIN urls u ON u.short_url = r.short_url
+	WHERE r.short_url = $1
+	GROUP BY r.short_url, u.url;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		short_url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get analytics results from db: %w", err)
+	}
+	defer rows.Close()
+
+	var redirectInfo []dto.RedirectInfo
+	for rows.Next() {
+		var redirect dto.RedirectInfo
+		err := rows.Scan(
+			&redirect.ShortUrl,
+			&redirect.Url,
+			&redirect.RedirectCount,
+			&redirect.QrScans,
+			jsonArray(&redirect.UserAgent),
+			jsonArray(&redirect.RequestTime),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan redirectInfo result to model: %w", err)
+		}
+
+		redirectInfo = append(redirectInfo, redirect)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+	rows.Close()
+
+	for i := range redirectInfo {
+		redirectInfo[i].Variants, err = r.countVariants(ctx, short_url)
+		if err != nil {
+			return nil, err
+		}
+
+		redirectInfo[i].Referrers, err = r.countReferrers(ctx, short_url)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return redirectInfo, nil
+}
+
+func (r *Repository) countVariants(ctx context.Context, short_url string) ([]dto.VariantCount, error) {
+	query := `SELECT variant, COUNT(*)
+	FROM redirect_analytics
+	WHERE short_url = $1 AND variant <> ''
+	GROUP BY variant
+	ORDER BY variant;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		short_url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get variant analytics from db: %w", err)
+	}
+	defer rows.Close()
+
+	var variants []dto.VariantCount
+	for rows.Next() {
+		var variant dto.VariantCount
+		if err := rows.Scan(&variant.Variant, &variant.RedirectCount); err != nil {
+			return nil, fmt.Errorf("could not scan variant analytics: %w", err)
+		}
+		variants = append(variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return variants, nil
+}
+
+func (r *Repository) countReferrers(ctx context.Context, short_url string) ([]dto.ReferrerCount, error) {
+	query := `SELECT referrer_domain, COUNT(*) AS count
+	FROM redirect_analytics
+	WHERE short_url = $1
+	GROUP BY referrer_domain
+	ORDER BY count DESC, referrer_domain;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		short_url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get referrer analytics from db: %w", err)
+	}
+	defer rows.Close()
+
+	var referrers []dto.ReferrerCount
+	for rows.Next() {
+		var referrer dto.ReferrerCount
+		if err := rows.Scan(&referrer.Referrer, &referrer.RedirectCount); err != nil {
+			return nil, fmt.Errorf("could not scan referrer analytics: %w", err)
+		}
+		referrers = append(referrers, referrer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return referrers, nil
+}
@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+// ClaimIdempotencyKey stores key as in progress for lease unless a record
+// with the same key exists that has not expired. It reports whether key was
+// claimed, otherwise the existing record is returned.
+func (r *Repository) ClaimIdempotencyKey(ctx context.Context, key model.IdempotencyKey, lease time.Duration) (*model.IdempotencyKey, bool, error) {
+	now := r.now().UTC()
+	rows, err := r.db.QueryContext(
+		ctx,
+		`INSERT INTO idempotency_keys (key, request_hash, token, created_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (key) DO UPDATE SET
+		request_hash = excluded.request_hash,
+		token = excluded.token,
+		response = NULL,
+		created_at = excluded.created_at,
+		expires_at = excluded.expires_at
+	WHERE idempotency_keys.expires_at <= $4
+	RETURNING key, request_hash, token, response, created_at, expires_at;`,
+		key.Key,
+		key.RequestHash,
+		key.Token,
+		timestamp(now),
+		timestamp(now.Add(lease)),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not save idempotency key in db: %w", err)
+	}
+	claimed, err := scanIdempotencyKey(rows)
+	if err != nil {
+		return nil, false, err
+	}
+	if claimed != nil {
+		return claimed, true, nil
+	}
+
+	// SQLite serializes writers, but the record may still be released
+	// between the statements.
+	rows, err = r.db.QueryContext(
+		ctx,
+		`SELECT key, request_hash, token, response, created_at, expires_at
+	FROM idempotency_keys
+	WHERE key = $1;`,
+		key.Key,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not get idempotency key from db: %w", err)
+	}
+	existing, err := scanIdempotencyKey(rows)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing == nil {
+		return r.ClaimIdempotencyKey(ctx, key, lease)
+	}
+
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request that claimed
+// key with token and keeps it for ttl. It fails with
+// ErrIdempotencyKeyNotFound once another request has taken the key over.
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, key, token, response string, ttl time.Duration) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		"UPDATE idempotency_keys SET response = $3, expires_at = $4 WHERE key = $1 AND token = $2 AND response IS NULL",
+		key,
+		token,
+		response,
+		timestamp(r.now().UTC().Add(ttl)),
+	)
+	if err != nil {
+		return fmt.Errorf("could not save idempotent response in db: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return repository.ErrIdempotencyKeyNotFound
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes key while the request that claimed it
+// with token is in progress, so that the request can be retried after it
+// failed. Completed keys and keys taken over by another request are kept.
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, key, token string) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM idempotency_keys WHERE key = $1 AND token = $2 AND response IS NULL",
+		key,
+		token,
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete idempotency key from db: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes expired keys and returns how many were
+// deleted.
+func (r *Repository) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", r.timestamp())
+	if err != nil {
+		return 0, fmt.Errorf("could not delete idempotency keys from db: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get deleted rows count: %w", err)
+	}
+
+	return int(deleted), nil
+}
+
+func scanIdempotencyKey(rows *sql.Rows) (*model.IdempotencyKey, error) {
+	defer rows.Close()
+
+	var key *model.IdempotencyKey
+	for rows.Next() {
+		var response sql.NullString
+		key = &model.IdempotencyKey{}
+		err := rows.Scan(&key.Key, &key.RequestHash, &key.Token, &response, &key.CreatedAt, &key.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan idempotency key: %w", err)
+		}
+		key.Response = response.String
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return key, nil
+}
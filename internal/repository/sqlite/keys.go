@@ -0,0 +1,151 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+const selectApiKeysQuery = `SELECT id, name, prefix, key_hash, created_at, revoked_at
+	FROM api_keys`
+
+func (r *Repository) CreateApiKey(ctx context.Context, key model.ApiKey) (*model.ApiKey, error) {
+	query := `INSERT INTO api_keys (name, prefix, key_hash, created_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id;`
+
+	createdAt := r.timestamp()
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		createdAt,
+	).Scan(&key.Id)
+	if err != nil {
+		return nil, fmt.Errorf("could not save api key in db: %w", err)
+	}
+
+	key.CreatedAt, err = parseTimestamp(createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *Repository) ListApiKeys(ctx context.Context) ([]model.ApiKey, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		selectApiKeysQuery+`
+	ORDER BY id;`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get api keys from db: %w", err)
+	}
+	defer rows.Close()
+
+	return scanApiKeys(rows)
+}
+
+// GetApiKeyByHash finds a key by the hash of its secret, revoked keys
+// are returned as well.
+func (r *Repository) GetApiKeyByHash(ctx context.Context, keyHash string) (*model.ApiKey, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		selectApiKeysQuery+`
+	WHERE key_hash = $1;`,
+		keyHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get api key from db: %w", err)
+	}
+	defer rows.Close()
+
+	keys, err := scanApiKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, repository.ErrApiKeyNotFound
+	}
+
+	return &keys[0], nil
+}
+
+// RevokeApiKey marks the key revoked, revoking it again keeps the time of
+// the first revocation.
+func (r *Repository) RevokeApiKey(ctx context.Context, id int) (*model.ApiKey, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		"UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1",
+		id,
+		r.timestamp(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not revoke api key in db: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, repository.ErrApiKeyNotFound
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		selectApiKeysQuery+`
+	WHERE id = $1;`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get api key from db: %w", err)
+	}
+	defer rows.Close()
+
+	keys, err := scanApiKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, repository.ErrApiKeyNotFound
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return &keys[0], nil
+}
+
+func scanApiKeys(rows *sql.Rows) ([]model.ApiKey, error) {
+	var keys []model.ApiKey
+	for rows.Next() {
+		var key model.ApiKey
+		err := rows.Scan(
+			&key.Id,
+			&key.Name,
+			&key.Prefix,
+			&key.KeyHash,
+			&key.CreatedAt,
+			&key.RevokedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return keys, nil
+}
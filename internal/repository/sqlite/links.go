@@ -0,0 +1,264 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+// selectLinksQuery binds the current time to $1, SQLite has no clock in
+// the format timestamps are stored in.
+const selectLinksQuery = `SELECT u.short_url, u.url, COALESCE(m.title, ''), u.folder,
+	(SELECT json_group_array(tag ORDER BY tag) FROM url_tags WHERE short_url = u.short_url) AS tags,
+	u.owner,
+	CASE
+		WHEN u.disabled THEN 'disabled'
+		WHEN u.expires_at <= $1 THEN 'expired'
+		ELSE 'active'
+	END AS status,
+	u.created_at, u.expires_at, COALESCE(c.clicks, 0), c.last_click
+	FROM urls u
+	LEFT JOIN url_metadata m ON m.short_url = u.short_url
+	LEFT JOIN (
+		SELECT short_url, COUNT(*) AS clicks, MAX(request_time) AS last_click
+		FROM redirect_analytics
+		GROUP BY short_url
+	) c ON c.short_url = u.short_url`
+
+// linkSortColumns maps sort keys accepted by SearchLinks to sql expressions.
+var linkSortColumns = map[string]string{
+	dto.LinkSortCreated:   "u.created_at",
+	dto.LinkSortLastClick: "c.last_click",
+	dto.LinkSortClicks:    "COALESCE(c.clicks, 0)",
+}
+
+// SearchLinks matches Search as a case insensitive substring of the
+// destination, alias, page title and tags. There are no trigram indexes in
+// SQLite, the search scans the links.
+func (r *Repository) SearchLinks(ctx context.Context, search dto.LinkQuery) ([]dto.LinkDTO, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	arg(r.timestamp())
+
+	conditions = append(conditions, filterCondition("u.short_url", len(args)+1))
+	args = append(args, search.Folder, search.Tag)
+
+	if search.Search != "" {
+		pattern := arg("%" + escapeLike(strings.ToLower(search.Search)) + "%")
+		conditions = append(conditions, fmt.Sprintf(`(unicode_lower(u.url) LIKE %[1]s ESCAPE '\'
+	OR unicode_lower(u.short_url) LIKE %[1]s ESCAPE '\'
+	OR unicode_lower(m.title) LIKE %[1]s ESCAPE '\'
+	OR EXISTS (SELECT 1 FROM url_tags WHERE short_url = u.short_url AND unicode_lower(tag) LIKE %[1]s ESCAPE '\'))`, pattern))
+	}
+	if search.Owner != "" {
+		conditions = append(conditions, "u.owner = "+arg(search.Owner))
+	}
+	switch search.Status {
+	case model.LinkStatusActive:
+		conditions = append(conditions, "NOT u.disabled AND (u.expires_at IS NULL OR u.expires_at > $1)")
+	case model.LinkStatusExpired:
+		conditions = append(conditions, "NOT u.disabled AND u.expires_at <= $1")
+	case model.LinkStatusDisabled:
+		conditions = append(conditions, "u.disabled")
+	}
+	if search.CreatedFrom != nil {
+		conditions = append(conditions, "u.created_at >= "+arg(timestamp(*search.CreatedFrom)))
+	}
+	if search.CreatedTo != nil {
+		conditions = append(conditions, "u.created_at < "+arg(timestamp(*search.CreatedTo)))
+	}
+	if search.MinClicks != nil {
+		conditions = append(conditions, "COALESCE(c.clicks, 0) >= "+arg(*search.MinClicks))
+	}
+	if search.MaxClicks != nil {
+		conditions = append(conditions, "COALESCE(c.clicks, 0) <= "+arg(*search.MaxClicks))
+	}
+
+	order := "DESC"
+	if search.Order == dto.LinkOrderAsc {
+		order = "ASC"
+	}
+	sortColumn, ok := linkSortColumns[search.Sort]
+	if !ok {
+		sortColumn = linkSortColumns[dto.LinkSortCreated]
+	}
+
+	query := selectLinksQuery + `
+	WHERE ` + strings.Join(conditions, "\n\tAND ") + `
+	ORDER BY ` + sortColumn + ` ` + order + ` NULLS LAST, u.id ` + order + `
+	LIMIT ` + arg(search.Limit) + ` OFFSET ` + arg(search.Offset) + `;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get links from db: %w", err)
+	}
+	defer rows.Close()
+
+	return scanLinks(rows)
+}
+
+func (r *Repository) GetLink(ctx context.Context, short_url string) (*dto.LinkDTO, error) {
+	rows, err := r.db.QueryContext(ctx, selectLinksQuery+`
+	WHERE u.short_url = $2;`, r.timestamp(), short_url)
+	if err != nil {
+		return nil, fmt.Errorf("could not get link from db: %w", err)
+	}
+	defer rows.Close()
+
+	links, err := scanLinks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	return &links[0], nil
+}
+
+func (r *Repository) SetDisabled(ctx context.Context, short_url string, disabled bool) (*dto.LinkDTO, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE urls SET disabled=$2 WHERE short_url=$1", short_url, disabled)
+	if err != nil {
+		return nil, fmt.Errorf("could not update url in db: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	link, err := r.queryLink(ctx, tx, short_url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return link, nil
+}
+
+// DeleteLink deletes the link with its analytics, rules, variants, tags,
+// metadata and health checks.
+func (r *Repository) DeleteLink(ctx context.Context, short_url string) (*model.Url, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM redirect_analytics WHERE short_url=$1", short_url); err != nil {
+		return nil, fmt.Errorf("could not delete redirect info from db: %w", err)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"DELETE FROM urls WHERE short_url=$1 RETURNING id, short_url, url, fallback_url",
+		short_url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not delete url from db: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("could not read rows from db: %w", err)
+		}
+		return nil, repository.ErrAliasNotFound
+	}
+
+	var deleted model.Url
+	if err := rows.Scan(&deleted.Id, &deleted.ShortUrl, &deleted.Url, &deleted.FallbackUrl); err != nil {
+		return nil, fmt.Errorf("could not scan deleted url: %w", err)
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return &deleted, nil
+}
+
+func (r *Repository) queryLink(ctx context.Context, tx *sql.Tx, short_url string) (*dto.LinkDTO, error) {
+	rows, err := tx.QueryContext(ctx, selectLinksQuery+`
+	WHERE u.short_url = $2;`, r.timestamp(), short_url)
+	if err != nil {
+		return nil, fmt.Errorf("could not get link from db: %w", err)
+	}
+	defer rows.Close()
+
+	links, err := scanLinks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	return &links[0], nil
+}
+
+func scanLinks(rows *sql.Rows) ([]dto.LinkDTO, error) {
+	var links []dto.LinkDTO
+	for rows.Next() {
+		var link dto.LinkDTO
+		var lastClick sql.NullString
+		err := rows.Scan(
+			&link.ShortUrl,
+			&link.Url,
+			&link.Title,
+			&link.Folder,
+			jsonArray(&link.Tags),
+			&link.Owner,
+			&link.Status,
+			&link.CreatedAt,
+			&link.ExpiresAt,
+			&link.Clicks,
+			&lastClick,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan link: %w", err)
+		}
+		if lastClick.Valid {
+			t, err := parseTimestamp(lastClick.String)
+			if err != nil {
+				return nil, fmt.Errorf("could not scan link: %w", err)
+			}
+			link.LastClick = &t
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return links, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+func (r *Repository) SaveUrlMetadata(ctx context.Context, metadata model.UrlMetadata) error {
+	query := `INSERT INTO url_metadata
+	(short_url, title, description, image, canonical_url, status, attempts, error, fetched_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (short_url) DO UPDATE SET
+	title = EXCLUDED.title,
+	description = EXCLUDED.description,
+	image = EXCLUDED.image,
+	canonical_url = EXCLUDED.canonical_url,
+	status = EXCLUDED.status,
+	attempts = EXCLUDED.attempts,
+	error = EXCLUDED.error,
+	fetched_at = EXCLUDED.fetched_at;`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		metadata.ShortUrl,
+		metadata.Title,
+		metadata.Description,
+		metadata.Image,
+		metadata.CanonicalUrl,
+		metadata.Status,
+		metadata.Attempts,
+		metadata.Error,
+		timestamp(metadata.FetchedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("could not save url metadata in db: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetUrlMetadata(ctx context.Context, short_url string) (*model.UrlMetadata, error) {
+	query := `SELECT short_url, title, description, image, canonical_url,
+	status, attempts, error, fetched_at
+	FROM url_metadata
+	WHERE short_url = $1;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		short_url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get url metadata from db: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, repository.ErrMetadataNotFound
+	}
+
+	var metadata model.UrlMetadata
+	err = rows.Scan(
+		&metadata.ShortUrl,
+		&metadata.Title,
+		&metadata.Description,
+		&metadata.Image,
+		&metadata.CanonicalUrl,
+		&metadata.Status,
+		&metadata.Attempts,
+		&metadata.Error,
+		&metadata.FetchedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not scan url metadata: %w", err)
+	}
+
+	return &metadata, nil
+}
@@ -0,0 +1,144 @@
+// Package sqlite is a SQLite implementation of the link storage for single
+// node deployments. It keeps the schema and the semantics of the Postgres
+// repository, the migrations live in migrations/sqlite.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/tracing"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// timeLayout is how timestamps are stored. It is the way Postgres prints
+// them, so the text sorts in time order and analytics look the same.
+const timeLayout = "2006-01-02 15:04:05.999999"
+
+func init() {
+	// lower() of SQLite only folds ASCII, search must be case insensitive
+	// for any language like ILIKE is.
+	err := sqlitedriver.RegisterDeterministicScalarFunction("unicode_lower", 1,
+		func(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+			value, ok := args[0].(string)
+			if !ok {
+				return args[0], nil
+			}
+			return strings.ToLower(value), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type Repository struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// Ping checks that the database file is still usable.
+func (r *Repository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("could not ping db: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) Close() error {
+	if err := r.db.Close(); err != nil {
+		return fmt.Errorf("could not close db: %w", err)
+	}
+	return nil
+}
+
+// Open opens the database file at path, creating it if needed. Foreign
+// keys are enforced, writers wait for each other instead of failing with
+// SQLITE_BUSY. Queries are traced.
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+
+	db, err := tracing.OpenDB("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("could not open sqlite db: %w", err)
+	}
+
+	return db, nil
+}
+
+// timestamp formats t the way a Postgres TIMESTAMP column stores it: the
+// wall clock is kept, the zone is dropped and precision is microseconds.
+func timestamp(t time.Time) string {
+	return t.Round(time.Microsecond).Format(timeLayout)
+}
+
+func timestampPtr(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	converted := timestamp(*t)
+	return &converted
+}
+
+func (r *Repository) timestamp() string {
+	return timestamp(r.now().UTC())
+}
+
+func parseTimestamp(value string) (time.Time, error) {
+	t, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse timestamp %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// isUniqueViolation reports whether err is a failed UNIQUE or PRIMARY KEY
+// constraint.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlitedriver.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+		sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// jsonArray scans a json_group_array result into dest, it is the SQLite
+// counterpart of pq.Array.
+func jsonArray(dest *[]string) sql.Scanner {
+	return (*stringArray)(dest)
+}
+
+type stringArray []string
+
+func (a *stringArray) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(src), (*[]string)(a))
+	case []byte:
+		return json.Unmarshal(src, (*[]string)(a))
+	default:
+		return fmt.Errorf("could not scan %T into string array", src)
+	}
+}
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+const selectRulesQuery = `SELECT id, position, os, device, browser, language, country, destination
+	FROM targeting_rules
+	WHERE short_url = $1
+	ORDER BY position;`
+
+func (r *Repository) SetTargetingRules(ctx context.Context, short_url string, rules []model.TargetingRule) ([]model.TargetingRule, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM urls WHERE short_url=$1)", short_url).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("could not get alias from db: %w", err)
+	}
+	if !exists {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM targeting_rules WHERE short_url=$1", short_url); err != nil {
+		return nil, fmt.Errorf("could not delete targeting rules from db: %w", err)
+	}
+
+	if err := insertRules(ctx, tx, short_url, rules); err != nil {
+		return nil, err
+	}
+
+	saved, err := queryRules(ctx, tx, short_url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (r *Repository) GetTargetingRules(ctx context.Context, short_url string) ([]model.TargetingRule, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		selectRulesQuery,
+		short_url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get targeting rules from db: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRules(rows)
+}
+
+func insertRules(ctx context.Context, tx *sql.Tx, short_url string, rules []model.TargetingRule) error {
+	query := `INSERT INTO targeting_rules
+	(short_url, position, os, device, browser, language, country, destination)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+
+	for _, rule := range rules {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			short_url,
+			rule.Position,
+			rule.Os,
+			rule.Device,
+			rule.Browser,
+			rule.Language,
+			rule.Country,
+			rule.Destination,
+		)
+		if err != nil {
+			return fmt.Errorf("could not save targeting rule in db: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func queryRules(ctx context.Context, tx *sql.Tx, short_url string) ([]model.TargetingRule, error) {
+	rows, err := tx.QueryContext(ctx, selectRulesQuery, short_url)
+	if err != nil {
+		return nil, fmt.Errorf("could not get targeting rules from db: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRules(rows)
+}
+
+func scanRules(rows *sql.Rows) ([]model.TargetingRule, error) {
+	var rules []model.TargetingRule
+	for rows.Next() {
+		var rule model.TargetingRule
+		err := rows.Scan(
+			&rule.Id,
+			&rule.Position,
+			&rule.Os,
+			&rule.Device,
+			&rule.Browser,
+			&rule.Language,
+			&rule.Country,
+			&rule.Destination,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan targeting rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+// filterCondition returns a WHERE condition limiting short_url column to
+// links matching filter. Folder and tag are bound to $first and $first+1.
+func filterCondition(column string, first int) string {
+	return fmt.Sprintf(`($%d = '' OR %s IN (SELECT short_url FROM urls WHERE folder = $%d))
+	AND ($%d = '' OR %s IN (SELECT short_url FROM url_tags WHERE tag = $%d))`,
+		first, column, first, first+1, column, first+1)
+}
+
+func (r *Repository) SetTags(ctx context.Context, short_url string, tags []string) (*dto.LinkDTO, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM urls WHERE short_url=$1)", short_url).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("could not get alias from db: %w", err)
+	}
+	if !exists {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM url_tags WHERE short_url=$1", short_url); err != nil {
+		return nil, fmt.Errorf("could not delete tags from db: %w", err)
+	}
+
+	if err := insertTags(ctx, tx, short_url, tags); err != nil {
+		return nil, err
+	}
+
+	link, err := r.queryLink(ctx, tx, short_url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return link, nil
+}
+
+func (r *Repository) SetFolder(ctx context.Context, short_url string, folder string) (*dto.LinkDTO, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE urls SET folder=$2 WHERE short_url=$1", short_url, folder)
+	if err != nil {
+		return nil, fmt.Errorf("could not update url in db: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	link, err := r.queryLink(ctx, tx, short_url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return link, nil
+}
+
+// AggregateByTag rolls clicks up to tags, a click of a link with several
+// tags is counted once for every tag.
+func (r *Repository) AggregateByTag(ctx context.Context, filter dto.LinkFilter) ([]dto.TagDTO, error) {
+	query := `SELECT t.tag,
+	COUNT(DISTINCT t.short_url) AS links,
+	COUNT(r.id) AS redirect_count,
+	COUNT(DISTINCT NULLIF(r.visitor_id, '')) AS uniques
+	FROM url_tags t
+	LEFT JOIN redirect_analytics r ON r.short_url = t.short_url
+	WHERE ` + filterCondition("t.short_url", 1) + `
+	GROUP BY t.tag
+	ORDER BY redirect_count DESC, t.tag;`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		filter.Folder,
+		filter.Tag,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not send request to get aggregated data from db: %w", err)
+	}
+	defer rows.Close()
+
+	var analytics []dto.TagDTO
+	for rows.Next() {
+		var next dto.TagDTO
+		if err := rows.Scan(&next.Tag, &next.Links, &next.RedirectCount, &next.Uniques); err != nil {
+			return nil, fmt.Errorf("could not scan aggregated data from db: %w", err)
+		}
+		analytics = append(analytics, next)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return analytics, nil
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, short_url string, tags []string) error {
+	query := `INSERT INTO url_tags (short_url, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING;`
+
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, query, short_url, tag); err != nil {
+			return fmt.Errorf("could not save tag in db: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+const selectVariantsQuery = `SELECT id, name, url, weight
+	FROM url_variants
+	WHERE short_url = $1
+	ORDER BY id;`
+
+func (r *Repository) SetVariants(ctx context.Context, variants dto.VariantsDTO) (*dto.VariantsDTO, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transcation: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		"UPDATE urls SET sticky_variants=$2 WHERE short_url=$1",
+		variants.ShortUrl,
+		variants.Sticky,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not update url in db: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM url_variants WHERE short_url=$1", variants.ShortUrl); err != nil {
+		return nil, fmt.Errorf("could not delete variants from db: %w", err)
+	}
+
+	if err := insertVariants(ctx, tx, variants.ShortUrl, variants.Variants); err != nil {
+		return nil, err
+	}
+
+	variants.Variants, err = queryVariants(ctx, tx, variants.ShortUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return &variants, nil
+}
+
+func (r *Repository) GetVariants(ctx context.Context, short_url string) (*dto.VariantsDTO, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		"SELECT sticky_variants FROM urls WHERE short_url=$1",
+		short_url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get alias from db: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, repository.ErrAliasNotFound
+	}
+
+	variants := dto.VariantsDTO{ShortUrl: short_url}
+	if err := rows.Scan(&variants.Sticky); err != nil {
+		return nil, fmt.Errorf("could not scan rows result: %w", err)
+	}
+	rows.Close()
+
+	rows, err = r.db.QueryContext(
+		ctx,
+		selectVariantsQuery,
+		short_url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get variants from db: %w", err)
+	}
+	defer rows.Close()
+
+	variants.Variants, err = scanVariants(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &variants, nil
+}
+
+func insertVariants(ctx context.Context, tx *sql.Tx, short_url string, variants []model.Variant) error {
+	query := `INSERT INTO url_variants (short_url, name, url, weight) VALUES ($1, $2, $3, $4);`
+
+	for _, variant := range variants {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			short_url,
+			variant.Name,
+			variant.Url,
+			variant.Weight,
+		)
+		if err != nil {
+			return fmt.Errorf("could not save variant in db: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func queryVariants(ctx context.Context, tx *sql.Tx, short_url string) ([]model.Variant, error) {
+	rows, err := tx.QueryContext(ctx, selectVariantsQuery, short_url)
+	if err != nil {
+		return nil, fmt.Errorf("could not get variants from db: %w", err)
+	}
+	defer rows.Close()
+
+	return scanVariants(rows)
+}
+
+func scanVariants(rows *sql.Rows) ([]model.Variant, error) {
+	var variants []model.Variant
+	for rows.Next() {
+		var variant model.Variant
+		if err := rows.Scan(&variant.Id, &variant.Name, &variant.Url, &variant.Weight); err != nil {
+			return nil, fmt.Errorf("could not scan variant: %w", err)
+		}
+		variants = append(variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read rows from db: %w", err)
+	}
+
+	return variants, nil
+}
@@ -0,0 +1,121 @@
+// Package safehttp builds HTTP clients for requests to user supplied urls.
+// Such clients refuse to connect to loopback, private and link-local
+// addresses so that the service can not be used to reach internal hosts.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrForbiddenAddress = errors.New("destination address is not allowed")
+	ErrForbiddenScheme  = errors.New("only http and https urls are allowed")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+var deniedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+)
+
+type Options struct {
+	Timeout      time.Duration
+	MaxRedirects int
+	// AllowPrivate disables address filtering, it is meant for tests only.
+	AllowPrivate bool
+}
+
+func NewClient(opts Options) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: opts.Timeout,
+	}
+	if !opts.AllowPrivate {
+		dialer.Control = controlAddress
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= opts.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return CheckUrl(req.URL)
+		},
+	}
+}
+
+// CheckUrl validates url before a request is made. Addresses are checked
+// again after name resolution when the connection is dialed.
+func CheckUrl(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrForbiddenScheme
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("url has no host: %s", u.String())
+	}
+
+	return nil
+}
+
+func IsAllowedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range deniedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func controlAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("could not parse dial address %s: %w", address, err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !IsAllowedIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+
+	return nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+}
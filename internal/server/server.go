@@ -0,0 +1,98 @@
+// Package server runs the HTTP server until the process is asked to stop
+// and then drains in-flight requests before returning.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+type Options struct {
+	Address           string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may take to
+	// finish once shutdown starts.
+	ShutdownTimeout time.Duration
+	// ShutdownDelay keeps the server accepting requests for a while after
+	// shutdown is requested, so that readiness checks fail before
+	// connections are refused.
+	ShutdownDelay time.Duration
+}
+
+type Server struct {
+	http            *http.Server
+	shutdownTimeout time.Duration
+	shutdownDelay   time.Duration
+}
+
+func New(handler http.Handler, opts Options) *Server {
+	return &Server{
+		http: &http.Server{
+			Addr:              opts.Address,
+			Handler:           handler,
+			ReadTimeout:       opts.ReadTimeout,
+			ReadHeaderTimeout: opts.ReadHeaderTimeout,
+			WriteTimeout:      opts.WriteTimeout,
+			IdleTimeout:       opts.IdleTimeout,
+		},
+		shutdownTimeout: opts.ShutdownTimeout,
+		shutdownDelay:   opts.ShutdownDelay,
+	}
+}
+
+// Run listens on the configured address, see Serve.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", s.http.Addr, err)
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves requests until ctx is cancelled, keeps serving for the
+// shutdown delay, then stops accepting connections and waits up to the
+// shutdown timeout for in-flight requests. Requests that are still running
+// after that are cut off and Serve returns context.DeadlineExceeded.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	if s.shutdownDelay > 0 {
+		select {
+		case err := <-serveErr:
+			return err
+		case <-time.After(s.shutdownDelay):
+		}
+	}
+
+	shutdownCtx := context.Background()
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.shutdownTimeout)
+		defer cancel()
+	}
+
+	if err := s.http.Shutdown(shutdownCtx); err != nil {
+		s.http.Close()
+		return fmt.Errorf("could not drain requests: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
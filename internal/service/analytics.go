@@ -1,15 +1,97 @@
 package service
 
-import "github.com/Komilov31/url-shortener/internal/dto"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
 
-func (s *Service) AggregateByUserAgent() ([]dto.UserAgentDTO, error) {
-	return s.storage.AggregateByUserAgent()
+	"github.com/Komilov31/url-shortener/internal/dto"
+)
+
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+func (s *Service) AggregateByUserAgent(ctx context.Context, filter dto.LinkFilter) ([]dto.UserAgentDTO, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.AggregateByUserAgent(ctx, normalizeFilter(filter))
+	return result, wrapError(ctx, err)
 }
 
-func (s *Service) AggregateByDate() ([]dto.DateDTO, error) {
-	return s.storage.AggregateByDate()
+func (s *Service) AggregateByDate(ctx context.Context, filter dto.LinkFilter) ([]dto.DateDTO, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.AggregateByDate(ctx, normalizeFilter(filter))
+	return result, wrapError(ctx, err)
 }
 
-func (s *Service) AggregateByMonth() ([]dto.MonthDTO, error) {
-	return s.storage.AggregateByMonth()
+func (s *Service) AggregateByMonth(ctx context.Context, filter dto.LinkFilter) ([]dto.MonthDTO, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.AggregateByMonth(ctx, normalizeFilter(filter))
+	return result, wrapError(ctx, err)
+}
+
+func (s *Service) AggregateByReferrer(ctx context.Context, filter dto.LinkFilter) ([]dto.ReferrerDTO, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.AggregateByReferrer(ctx, normalizeFilter(filter))
+	return result, wrapError(ctx, err)
+}
+
+func (s *Service) AggregateByTag(ctx context.Context, filter dto.LinkFilter) ([]dto.TagDTO, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.AggregateByTag(ctx, normalizeFilter(filter))
+	return result, wrapError(ctx, err)
+}
+
+func (s *Service) AggregateByCampaign(ctx context.Context, period string, filter dto.LinkFilter) ([]dto.CampaignDTO, error) {
+	period, err := normalizePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.AggregateByCampaign(ctx, period, normalizeFilter(filter))
+	return result, wrapError(ctx, err)
+}
+
+func normalizePeriod(period string) (string, error) {
+	period = strings.ToLower(strings.TrimSpace(period))
+	switch period {
+	case "":
+		return PeriodDay, nil
+	case PeriodDay, PeriodWeek, PeriodMonth:
+		return period, nil
+	default:
+		return "", fmt.Errorf("%w: unknown period %q, expected one of %v", ErrInvalidPeriod, period,
+			[]string{PeriodDay, PeriodWeek, PeriodMonth})
+	}
+}
+
+// PurgeAnalytics deletes clicks recorded before the given time, of one link
+// or of all links when short_url is empty, and returns how many were
+// deleted.
+func (s *Service) PurgeAnalytics(ctx context.Context, short_url string, before time.Time) (int, error) {
+	if before.IsZero() {
+		return 0, fmt.Errorf("%w: before is required", ErrInvalidPurge)
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.PurgeAnalytics(ctx, strings.TrimSpace(short_url), before)
+	return result, wrapError(ctx, err)
 }
@@ -1,41 +1,196 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Komilov31/url-shortener/internal/model"
 	"github.com/Komilov31/url-shortener/internal/repository"
 	"github.com/go-redis/redis/v8"
 )
 
-func (s *Service) CreateShortUrl(url model.Url) (*model.Url, error) {
-	url.Url = validateUrlScheme(url.Url)
+const maxShortUrlLength = 64
+
+func (s *Service) CreateShortUrl(ctx context.Context, url model.Url) (*model.Url, error) {
+	url, err := normalizeUrl(url)
+	if err != nil {
+		return nil, err
+	}
+
+	url.Disabled = false
+	if url.ExpiresAt != nil && !url.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiration
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
-	short_url, err := s.cache.Get(url.Url)
+	short_url, err := s.cache.Get(ctx, url.Url)
 	if err != nil && err != redis.Nil {
-		return nil, fmt.Errorf("could not get value from redis: %w", err)
-	}
-
-	var urlInfo *model.Url
-	if err == redis.Nil {
-		for {
-			url.ShortUrl = generateShortLink()
-			urlInfo, err = s.storage.CreateShortUrl(url)
-			short_url = urlInfo.ShortUrl
-			if err != nil && !errors.Is(err, repository.ErrUniqueConstraint) {
-				return nil, err
-			}
-
-			if errors.Is(err, repository.ErrUniqueConstraint) {
-				continue
-			}
-			break
+		return nil, wrapError(ctx, fmt.Errorf("%w: could not get value from redis: %w", ErrCacheUnavailable, err))
+	}
+
+	if err != redis.Nil {
+		// The cache does not know whether the link still redirects.
+		link, err := s.storage.GetLink(ctx, short_url)
+		if err == nil {
+			return reuseLink(url, &model.Url{Url: url.Url, ShortUrl: short_url}, link.Status)
+		}
+		if !errors.Is(err, repository.ErrAliasNotFound) {
+			return nil, wrapError(ctx, err)
 		}
 	}
 
-	urlInfo.Url = url.Url
-	urlInfo.ShortUrl = short_url
+	for {
+		url.ShortUrl = generateShortLink()
+		urlInfo, err := s.storage.CreateShortUrl(ctx, url)
+		if errors.Is(err, repository.ErrUniqueConstraint) {
+			s.metrics.ShortUrlCollision()
+			continue
+		}
+		if err != nil {
+			return nil, wrapError(ctx, err)
+		}
+
+		// The storage hands back the link of a destination shortened
+		// before, its metadata has been fetched already.
+		if urlInfo.ShortUrl != url.ShortUrl {
+			return reuseLink(url, urlInfo, linkStatus(*urlInfo))
+		}
+
+		s.metadata.Enqueue(*urlInfo)
+		return urlInfo, nil
+	}
+}
+
+// reuseLink answers the request for url with the existing link of its
+// destination. A destination can only be shortened once, so a link that no
+// longer redirects has to be enabled again or deleted first.
+func reuseLink(url model.Url, existing *model.Url, status string) (*model.Url, error) {
+	if status != model.LinkStatusActive {
+		return nil, fmt.Errorf("%w: %s is already shortened as %s, which is %s, enable or delete it to shorten the destination again", ErrUrlExists, url.Url, existing.ShortUrl, status)
+	}
+	if hasLinkOptions(url) {
+		return nil, errUrlExists(url.Url, existing.ShortUrl)
+	}
+	return existing, nil
+}
+
+func linkStatus(url model.Url) string {
+	switch {
+	case url.Disabled:
+		return model.LinkStatusDisabled
+	case url.ExpiresAt != nil && !url.ExpiresAt.After(time.Now()):
+		return model.LinkStatusExpired
+	default:
+		return model.LinkStatusActive
+	}
+}
+
+// hasLinkOptions reports whether url asks for more than a plain short link
+// of its destination. The existing link of the destination does not have
+// those settings, so it cannot answer such a request.
+func hasLinkOptions(url model.Url) bool {
+	return url.FallbackUrl != "" || len(url.Rules) > 0 || len(url.Variants) > 0 || url.StickyVariants ||
+		url.QueryPolicy != model.QueryPolicyNone || url.Utm != (model.Utm{}) ||
+		url.Folder != "" || len(url.Tags) > 0 || url.Owner != "" || url.ExpiresAt != nil
+}
+
+func errUrlExists(destination, short_url string) error {
+	return fmt.Errorf("%w: %s is already shortened as %s, change its settings there or shorten it without settings", ErrUrlExists, destination, short_url)
+}
+
+// ImportLink stores a link exported from another instance keeping its
+// short_url, expiration in the past and disabled state. Unlike
+// CreateShortUrl it never reuses a cached short_url and never generates a
+// new one: an alias that is taken fails with ErrShortUrlTaken
+// and a destination shortened under another alias with ErrUrlExists.
+func (s *Service) ImportLink(ctx context.Context, url model.Url) (*model.Url, error) {
+	if err := validateShortUrl(url.ShortUrl); err != nil {
+		return nil, err
+	}
 
+	disabled := url.Disabled
+	url, err := normalizeUrl(url)
+	if err != nil {
+		return nil, err
+	}
+	url.Disabled = false
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	urlInfo, err := s.storage.CreateShortUrl(ctx, url)
+	if err != nil {
+		return nil, wrapError(ctx, err)
+	}
+	if urlInfo.ShortUrl != url.ShortUrl {
+		return nil, errUrlExists(url.Url, urlInfo.ShortUrl)
+	}
+
+	if disabled {
+		if _, err := s.storage.SetDisabled(ctx, urlInfo.ShortUrl, true); err != nil {
+			return nil, wrapError(ctx, err)
+		}
+		urlInfo.Disabled = true
+	}
+
+	s.metadata.Enqueue(*urlInfo)
 	return urlInfo, nil
 }
+
+// normalizeUrl validates and normalizes everything the client may set on a
+// new link except its short_url, expiration and disabled state.
+func normalizeUrl(url model.Url) (model.Url, error) {
+	url.Url = validateUrlScheme(url.Url)
+	if url.FallbackUrl != "" {
+		url.FallbackUrl = validateUrlScheme(url.FallbackUrl)
+	}
+
+	rules, err := normalizeRules(url.Rules)
+	if err != nil {
+		return url, err
+	}
+	url.Rules = rules
+
+	variants, err := normalizeVariants(url.Variants)
+	if err != nil {
+		return url, err
+	}
+	url.Variants = variants
+
+	url.QueryPolicy, err = normalizeQueryPolicy(url.QueryPolicy)
+	if err != nil {
+		return url, err
+	}
+	url.Utm = normalizeUtm(url.Utm)
+
+	url.Tags, err = normalizeTags(url.Tags)
+	if err != nil {
+		return url, err
+	}
+	url.Folder, err = normalizeFolder(url.Folder)
+	if err != nil {
+		return url, err
+	}
+
+	url.Owner = strings.TrimSpace(url.Owner)
+	return url, nil
+}
+
+// validateShortUrl accepts aliases of letters, digits, '-' and '_', which
+// covers generated ones and keeps imported ones safe to use in a path.
+func validateShortUrl(short_url string) error {
+	if short_url == "" || len(short_url) > maxShortUrlLength {
+		return fmt.Errorf("%w: short_url must be 1 to %d characters long", ErrInvalidShortUrl, maxShortUrlLength)
+	}
+	for _, r := range short_url {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidShortUrl, short_url, r)
+		}
+	}
+	return nil
+}
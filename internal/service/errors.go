@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+// Kind tells what went wrong with a request independently of the transport,
+// the handler turns it into a status code.
+type Kind string
+
+const (
+	KindNotFound        Kind = "not_found"
+	KindConflict        Kind = "conflict"
+	KindUnprocessable   Kind = "unprocessable"
+	KindValidation      Kind = "validation"
+	KindUnauthenticated Kind = "unauthenticated"
+	KindExpired         Kind = "expired"
+	KindForbidden       Kind = "forbidden"
+	KindUnavailable     Kind = "unavailable"
+)
+
+// Error is a domain error. Code is stable and meant for clients to switch
+// on, Message is the human readable part. Details are added by wrapping,
+// e.g. fmt.Errorf("%w: %q is too long", ErrInvalidTag, tag).
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrInvalidRule              = &Error{Kind: KindValidation, Code: "invalid_rule", Message: "invalid targeting rule"}
+	ErrInvalidVariant           = &Error{Kind: KindValidation, Code: "invalid_variant", Message: "invalid variant"}
+	ErrInvalidQueryPolicy       = &Error{Kind: KindValidation, Code: "invalid_query_policy", Message: "invalid query policy"}
+	ErrInvalidPeriod            = &Error{Kind: KindValidation, Code: "invalid_period", Message: "invalid period"}
+	ErrInvalidTag               = &Error{Kind: KindValidation, Code: "invalid_tag", Message: "invalid tag"}
+	ErrInvalidFolder            = &Error{Kind: KindValidation, Code: "invalid_folder", Message: "invalid folder"}
+	ErrInvalidSearch            = &Error{Kind: KindValidation, Code: "invalid_search", Message: "invalid search"}
+	ErrInvalidExpiration        = &Error{Kind: KindValidation, Code: "invalid_expiration", Message: "expiration time must be in the future"}
+	ErrInvalidShortUrl          = &Error{Kind: KindValidation, Code: "invalid_short_url", Message: "invalid short_url"}
+	ErrInvalidPurge             = &Error{Kind: KindValidation, Code: "invalid_purge", Message: "invalid analytics purge"}
+	ErrInvalidApiKey            = &Error{Kind: KindValidation, Code: "invalid_api_key", Message: "invalid api key"}
+	ErrInvalidQrOptions         = &Error{Kind: KindValidation, Code: "invalid_qr_options", Message: "invalid qr code options"}
+	ErrInvalidBody              = &Error{Kind: KindValidation, Code: "invalid_body", Message: "invalid request body"}
+	ErrInvalidIdempotencyKey    = &Error{Kind: KindValidation, Code: "invalid_idempotency_key", Message: "invalid idempotency key"}
+	ErrLinkNotFound             = &Error{Kind: KindNotFound, Code: "link_not_found", Message: "short_url not found"}
+	ErrMetadataNotFound         = &Error{Kind: KindNotFound, Code: "metadata_not_found", Message: "metadata is not fetched yet"}
+	ErrApiKeyNotFound           = &Error{Kind: KindNotFound, Code: "api_key_not_found", Message: "api key not found"}
+	ErrUnauthenticated          = &Error{Kind: KindUnauthenticated, Code: "unauthenticated", Message: "a valid api key is required"}
+	ErrShortUrlTaken            = &Error{Kind: KindConflict, Code: "short_url_taken", Message: "short_url is already taken"}
+	ErrUrlExists                = &Error{Kind: KindConflict, Code: "url_exists", Message: "url is already shortened"}
+	ErrIdempotencyKeyInProgress = &Error{Kind: KindConflict, Code: "idempotency_key_in_progress", Message: "a request with this idempotency key is in progress"}
+	ErrIdempotencyKeyReused     = &Error{Kind: KindUnprocessable, Code: "idempotency_key_reused", Message: "idempotency key was used for another request"}
+	ErrLinkUnavailable          = &Error{Kind: KindExpired, Code: "link_unavailable", Message: "link is disabled or expired"}
+	ErrCacheUnavailable         = &Error{Kind: KindUnavailable, Code: "cache_unavailable", Message: "cache is unavailable"}
+)
+
+// storageErrors are the repository errors clients may cause, anything else
+// coming from storage is an internal error.
+var storageErrors = map[error]*Error{
+	repository.ErrAliasNotFound:    ErrLinkNotFound,
+	repository.ErrMetadataNotFound: ErrMetadataNotFound,
+	repository.ErrApiKeyNotFound:   ErrApiKeyNotFound,
+	repository.ErrUniqueConstraint: ErrShortUrlTaken,
+}
+
+// wrapError prepares an error of a backend for the caller. Repository
+// errors get their domain counterpart in front and an error caused by an
+// expired or cancelled context can be recognised with errors.Is, even when
+// the driver reports it with its own error value. The original error stays
+// in the chain.
+func wrapError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	for cause, domain := range storageErrors {
+		if errors.Is(err, cause) && !errors.Is(err, domain) {
+			err = fmt.Errorf("%w: %w", domain, err)
+			break
+		}
+	}
+
+	if ctx.Err() == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ctx.Err(), err)
+}
@@ -1,24 +1,30 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
 	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/referrer"
 	"github.com/go-redis/redis/v8"
-	"github.com/wb-go/wbf/zlog"
 )
 
-func (s *Service) GetAnalytics(short_url string) ([]dto.RedirectInfo, error) {
-	analytics, err := s.storage.GetAnalytics(short_url)
+func (s *Service) GetAnalytics(ctx context.Context, short_url string) ([]dto.RedirectInfo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	analytics, err := s.storage.GetAnalytics(ctx, short_url)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(ctx, err)
 	}
 
 	for _, a := range analytics {
 		if a.RedirectCount >= 5 {
-			if err := s.cache.Set(a.Url, a.ShortUrl); err != nil {
-				zlog.Logger.Error().Msg("could not save url to cache: " + err.Error())
+			if err := s.cache.Set(ctx, a.Url, a.ShortUrl); err != nil {
+				logging.FromContext(ctx).Error().Err(err).Msg("could not save url to cache")
 			}
 		}
 	}
@@ -26,28 +32,53 @@ func (s *Service) GetAnalytics(short_url string) ([]dto.RedirectInfo, error) {
 	return analytics, nil
 }
 
-func (s *Service) GetUrlByShort(short_url string, redirectInfo model.RedirectInfo) (*model.Url, error) {
-	url, err := s.cache.Get(short_url)
-	if err != nil && err != redis.Nil {
-		return nil, fmt.Errorf("could not get short_url from redis: %w", err)
+func (s *Service) GetUrlByShort(ctx context.Context, short_url string, redirectInfo model.RedirectInfo) (*model.Url, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
+	url, err := s.cache.Get(ctx, short_url)
+	if err != nil && err != redis.Nil {
+		return nil, wrapError(ctx, fmt.Errorf("%w: could not get short_url from redis: %w", ErrCacheUnavailable, err))
 	}
 
-	var urlInfo *model.Url
+	urlInfo := &model.Url{ShortUrl: short_url, Url: url}
+	target := "cache"
 	if err == redis.Nil {
-		urlInfo, err = s.storage.GetUrlByShort(short_url, redirectInfo)
-		url = urlInfo.Url
+		target = "destination"
+		urlInfo, err = s.storage.GetUrlByShort(ctx, short_url, redirectInfo)
 		if err != nil {
-			return nil, err
+			return nil, wrapError(ctx, err)
+		}
+		if urlInfo.Disabled || (urlInfo.ExpiresAt != nil && !urlInfo.ExpiresAt.After(time.Now())) {
+			return nil, ErrLinkUnavailable
+		}
+
+		previous := redirectInfo.Variant
+		redirectInfo.Variant = ""
+		if rule, ok := matchRule(urlInfo.Rules, redirectInfo); ok {
+			urlInfo.Url = rule.Destination
+			redirectInfo.MatchedRule = rule.Id
+			target = "rule"
+		} else if variant, ok := pickVariant(urlInfo.Variants, urlInfo.StickyVariants, previous); ok {
+			urlInfo.Url = variant.Url
+			urlInfo.Variant = variant.Name
+			redirectInfo.Variant = variant.Name
+			target = "variant"
+		} else if !urlInfo.Healthy && urlInfo.FallbackUrl != "" {
+			urlInfo.Url = urlInfo.FallbackUrl
+			target = "fallback"
 		}
-	}
 
-	if err := s.storage.CreateRedirectInfo(redirectInfo); err != nil {
-		return nil, err
+		urlInfo.Url = applyQuery(urlInfo.Url, urlInfo.QueryPolicy, urlInfo.Utm, redirectInfo.Query)
 	}
 
-	urlInfo.ShortUrl = short_url
-	urlInfo.Url = url
+	redirectInfo.Utm = clickUtm(redirectInfo.Query, urlInfo.Url)
+	redirectInfo.VisitorId = visitorId(redirectInfo.ClientIp, redirectInfo.UserAgent)
+	redirectInfo.ReferrerDomain = referrer.Normalize(redirectInfo.Referrer)
+	if err := s.storage.CreateRedirectInfo(ctx, redirectInfo); err != nil {
+		return nil, wrapError(ctx, err)
+	}
 
+	s.metrics.Redirect(redirectInfo.Source, target)
 	return urlInfo, nil
 }
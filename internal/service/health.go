@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+func (s *Service) GetUnhealthyUrls(ctx context.Context) ([]model.UrlHealth, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.GetUnhealthyUrls(ctx)
+	return result, wrapError(ctx, err)
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+// DefaultIdempotencyTTL is how long the result of a request with an
+// idempotency key is replayed unless SetIdempotencyTTL says otherwise.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// DefaultIdempotencyLease is how long a key stays claimed by a request that
+// has not completed unless SetIdempotencyLease says otherwise. It outlives
+// the timeout that bounds the whole request, so a key is only taken over
+// after its request died without releasing it.
+const DefaultIdempotencyLease = time.Minute
+
+const (
+	maxIdempotencyKeyLength = 255
+	idempotencyTokenBytes   = 16
+)
+
+// CreateShortUrlIdempotent is CreateShortUrl made safe to retry. The first
+// request with key claims it for the idempotency lease and creates the
+// link, its result is kept for the idempotency TTL. Retries of the same
+// request get that result back with replayed set, nothing is created and
+// no metadata is fetched again. A key reused for another link fails with
+// ErrIdempotencyKeyReused, a retry that arrives while the first request is
+// still running with ErrIdempotencyKeyInProgress. When creation fails the
+// key is released, so that the request can be retried, and a key left
+// behind by a request that crashed can be claimed again once its lease
+// runs out. Claiming, creating and completing share one timeout, which the
+// lease outlives. Keys are scoped to client, the API key of the caller,
+// anonymous requests fail with ErrUnauthenticated.
+func (s *Service) CreateShortUrlIdempotent(ctx context.Context, client *model.ApiKey, key string, url model.Url) (_ *model.Url, replayed bool, _ error) {
+	if err := validateIdempotencyKey(key); err != nil {
+		return nil, false, err
+	}
+	if client == nil {
+		return nil, false, fmt.Errorf("%w: an idempotency key can only be used with an api key", ErrUnauthenticated)
+	}
+
+	body, err := json.Marshal(url)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not encode request: %w", err)
+	}
+	hash := sha256.Sum256(body)
+	requestHash := hex.EncodeToString(hash[:])
+
+	token := make([]byte, idempotencyTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return nil, false, fmt.Errorf("could not generate idempotency token: %w", err)
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	claim := model.IdempotencyKey{Key: scopeIdempotencyKey(client, key), RequestHash: requestHash, Token: hex.EncodeToString(token)}
+	record, claimed, err := s.storage.ClaimIdempotencyKey(ctx, claim, s.idempotencyLease)
+	if err != nil {
+		return nil, false, wrapError(ctx, err)
+	}
+	if !claimed {
+		urlInfo, err := replay(record, requestHash)
+		return urlInfo, err == nil, err
+	}
+
+	urlInfo, err := s.CreateShortUrl(ctx, url)
+	if err != nil {
+		s.releaseIdempotencyKey(ctx, claim)
+		return nil, false, err
+	}
+
+	s.completeIdempotencyKey(ctx, claim, urlInfo)
+	return urlInfo, false, nil
+}
+
+// PurgeIdempotencyKeys deletes the keys whose results are no longer
+// replayed and returns how many were deleted.
+func (s *Service) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	deleted, err := s.storage.PurgeIdempotencyKeys(ctx)
+	return deleted, wrapError(ctx, err)
+}
+
+// completeIdempotencyKey stores the result even when the client has gone
+// away, the retry that follows is what it is for. If that fails the key
+// stays in progress until its lease runs out: answering retries with a
+// conflict is better than creating the link twice. A key taken over by
+// another request after the lease ran out is left alone.
+func (s *Service) completeIdempotencyKey(ctx context.Context, claim model.IdempotencyKey, urlInfo *model.Url) {
+	response, err := json.Marshal(urlInfo)
+	if err == nil {
+		ctx, cancel := detach(ctx)
+		defer cancel()
+		err = s.storage.CompleteIdempotencyKey(ctx, claim.Key, claim.Token, string(response), s.idempotencyTTL)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error().Err(err).Str("idempotency_key", claim.Key).Msg("could not save idempotent response")
+	}
+}
+
+// releaseIdempotencyKey lets a failed request be retried with the same key.
+func (s *Service) releaseIdempotencyKey(ctx context.Context, claim model.IdempotencyKey) {
+	ctx, cancel := detach(ctx)
+	defer cancel()
+
+	if err := s.storage.ReleaseIdempotencyKey(ctx, claim.Key, claim.Token); err != nil {
+		logging.FromContext(ctx).Error().Err(err).Str("idempotency_key", claim.Key).Msg("could not release idempotency key")
+	}
+}
+
+// detach returns ctx without its cancellation but with its deadline, so
+// that a request keeps its bookkeeping when the client goes away yet never
+// outlives its timeout.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return context.WithCancel(detached)
+}
+
+// replay returns the stored result of the request that claimed the key.
+func replay(record *model.IdempotencyKey, requestHash string) (*model.Url, error) {
+	if record.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if record.Response == "" {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	var urlInfo model.Url
+	if err := json.Unmarshal([]byte(record.Response), &urlInfo); err != nil {
+		return nil, fmt.Errorf("could not decode stored response: %w", err)
+	}
+	return &urlInfo, nil
+}
+
+// scopeIdempotencyKey keeps the keys of different clients apart. Keys
+// contain no spaces, so no key can reach into the scope of another client.
+func scopeIdempotencyKey(client *model.ApiKey, key string) string {
+	return key + " api_key:" + strconv.Itoa(client.Id)
+}
+
+// validateIdempotencyKey accepts printable ASCII keys of a sane length,
+// UUIDs being the usual choice.
+func validateIdempotencyKey(key string) error {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("%w: key must be 1 to %d characters long", ErrInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return fmt.Errorf("%w: key may contain printable ASCII characters only", ErrInvalidIdempotencyKey)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+)
+
+const (
+	// apiKeyPrefix marks the keys issued by this service, so that a leaked
+	// key is easy to recognise.
+	apiKeyPrefix        = "us_"
+	apiKeyBytes         = 32
+	apiKeyShownPrefix   = len(apiKeyPrefix) + 8
+	maxApiKeyNameLength = 128
+)
+
+// IssueApiKey creates a new key. Only its hash is stored, the key itself
+// is returned once and can not be recovered later.
+func (s *Service) IssueApiKey(ctx context.Context, name string) (*dto.IssuedApiKeyDTO, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxApiKeyNameLength {
+		return nil, fmt.Errorf("%w: name must be 1 to %d characters long", ErrInvalidApiKey, maxApiKeyNameLength)
+	}
+
+	secret := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("could not generate api key: %w", err)
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	created, err := s.storage.CreateApiKey(ctx, model.ApiKey{
+		Name:    name,
+		Prefix:  key[:apiKeyShownPrefix],
+		KeyHash: hashApiKey(key),
+	})
+	if err != nil {
+		return nil, wrapError(ctx, err)
+	}
+
+	return &dto.IssuedApiKeyDTO{ApiKey: *created, Key: key}, nil
+}
+
+func (s *Service) ListApiKeys(ctx context.Context) ([]model.ApiKey, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.ListApiKeys(ctx)
+	return result, wrapError(ctx, err)
+}
+
+func (s *Service) RevokeApiKey(ctx context.Context, id int) (*model.ApiKey, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.RevokeApiKey(ctx, id)
+	return result, wrapError(ctx, err)
+}
+
+// AuthenticateApiKey returns the key a client presented if it was issued
+// here and is not revoked, ErrUnauthenticated otherwise.
+func (s *Service) AuthenticateApiKey(ctx context.Context, key string) (*model.ApiKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrUnauthenticated
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	found, err := s.storage.GetApiKeyByHash(ctx, hashApiKey(key))
+	if errors.Is(err, repository.ErrApiKeyNotFound) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, wrapError(ctx, err)
+	}
+	if found.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: api key %s is revoked", ErrUnauthenticated, found.Prefix)
+	}
+
+	return found, nil
+}
+
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type apiKeyContextKey struct{}
+
+// WithApiKey returns ctx carrying the key that authenticated the request.
+func WithApiKey(ctx context.Context, key *model.ApiKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// ApiKeyFromContext returns the key that authenticated the request, nil
+// for anonymous requests.
+func ApiKeyFromContext(ctx context.Context) *model.ApiKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*model.ApiKey)
+	return key
+}
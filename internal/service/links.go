@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/logging"
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+const (
+	DefaultLinksLimit = 50
+	MaxLinksLimit     = 500
+)
+
+func (s *Service) SearchLinks(ctx context.Context, search dto.LinkQuery) ([]dto.LinkDTO, error) {
+	search, err := normalizeLinkQuery(search)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.SearchLinks(ctx, search)
+	return result, wrapError(ctx, err)
+}
+
+func (s *Service) GetLink(ctx context.Context, short_url string) (*dto.LinkDTO, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.GetLink(ctx, short_url)
+	return result, wrapError(ctx, err)
+}
+
+// SetDisabled disables or enables the link, a disabled link is dropped from
+// the cache so that it is not handed out for its destination again.
+func (s *Service) SetDisabled(ctx context.Context, short_url string, disabled bool) (*dto.LinkDTO, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.SetDisabled(ctx, short_url, disabled)
+	if err != nil {
+		return nil, wrapError(ctx, err)
+	}
+
+	if disabled {
+		if err := s.cache.Delete(ctx, result.Url, result.ShortUrl); err != nil {
+			logging.FromContext(ctx).Error().Err(err).Msg("could not delete url from cache")
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteLink deletes the link with all its analytics and drops it from the
+// cache, so that the alias stops redirecting right away.
+func (s *Service) DeleteLink(ctx context.Context, short_url string) (*model.Url, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	deleted, err := s.storage.DeleteLink(ctx, short_url)
+	if err != nil {
+		return nil, wrapError(ctx, err)
+	}
+
+	if err := s.cache.Delete(ctx, deleted.Url, deleted.ShortUrl); err != nil {
+		logging.FromContext(ctx).Error().Err(err).Msg("could not delete url from cache")
+	}
+
+	return deleted, nil
+}
+
+// ExportLinks returns every link matching the search with everything
+// needed to import it with ImportLink. Limit, Offset, Sort and Order of the
+// search are ignored: links are read page by page, oldest first.
+func (s *Service) ExportLinks(ctx context.Context, search dto.LinkQuery) ([]model.Url, error) {
+	search.Sort = dto.LinkSortCreated
+	search.Order = dto.LinkOrderAsc
+	search.Limit = MaxLinksLimit
+	search.Offset = 0
+
+	search, err := normalizeLinkQuery(search)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []model.Url
+	for {
+		page, err := s.exportPage(ctx, search)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, page...)
+
+		if len(page) < search.Limit {
+			return urls, nil
+		}
+		search.Offset += search.Limit
+	}
+}
+
+func (s *Service) exportPage(ctx context.Context, search dto.LinkQuery) ([]model.Url, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	links, err := s.storage.SearchLinks(ctx, search)
+	if err != nil {
+		return nil, wrapError(ctx, err)
+	}
+
+	urls := make([]model.Url, 0, len(links))
+	for _, link := range links {
+		urlInfo, err := s.storage.GetUrlByShort(ctx, link.ShortUrl, model.RedirectInfo{ShortUrl: link.ShortUrl})
+		if err != nil {
+			return nil, wrapError(ctx, err)
+		}
+
+		urlInfo.Folder = link.Folder
+		urlInfo.Tags = link.Tags
+		urlInfo.Owner = link.Owner
+		urls = append(urls, *urlInfo)
+	}
+
+	return urls, nil
+}
+
+func normalizeLinkQuery(search dto.LinkQuery) (dto.LinkQuery, error) {
+	search.LinkFilter = normalizeFilter(search.LinkFilter)
+	search.Search = strings.TrimSpace(search.Search)
+	search.Owner = strings.TrimSpace(search.Owner)
+	search.Status = strings.ToLower(strings.TrimSpace(search.Status))
+	search.Sort = strings.ToLower(strings.TrimSpace(search.Sort))
+	search.Order = strings.ToLower(strings.TrimSpace(search.Order))
+
+	switch search.Status {
+	case "", model.LinkStatusActive, model.LinkStatusExpired, model.LinkStatusDisabled:
+	default:
+		return search, fmt.Errorf("%w: unknown status %q, expected one of %v", ErrInvalidSearch, search.Status,
+			[]string{model.LinkStatusActive, model.LinkStatusExpired, model.LinkStatusDisabled})
+	}
+
+	switch search.Sort {
+	case "":
+		search.Sort = dto.LinkSortCreated
+	case dto.LinkSortCreated, dto.LinkSortLastClick, dto.LinkSortClicks:
+	default:
+		return search, fmt.Errorf("%w: unknown sort %q, expected one of %v", ErrInvalidSearch, search.Sort,
+			[]string{dto.LinkSortCreated, dto.LinkSortLastClick, dto.LinkSortClicks})
+	}
+
+	switch search.Order {
+	case "":
+		search.Order = dto.LinkOrderDesc
+	case dto.LinkOrderAsc, dto.LinkOrderDesc:
+	default:
+		return search, fmt.Errorf("%w: order must be asc or desc", ErrInvalidSearch)
+	}
+
+	switch {
+	case search.Limit == 0:
+		search.Limit = DefaultLinksLimit
+	case search.Limit < 0 || search.Limit > MaxLinksLimit:
+		return search, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSearch, MaxLinksLimit)
+	}
+	if search.Offset < 0 {
+		return search, fmt.Errorf("%w: offset must not be negative", ErrInvalidSearch)
+	}
+
+	if search.MinClicks != nil && search.MaxClicks != nil && *search.MinClicks > *search.MaxClicks {
+		return search, fmt.Errorf("%w: min_clicks is greater than max_clicks", ErrInvalidSearch)
+	}
+	if search.CreatedFrom != nil && search.CreatedTo != nil && search.CreatedFrom.After(*search.CreatedTo) {
+		return search, fmt.Errorf("%w: created_from is after created_to", ErrInvalidSearch)
+	}
+
+	return search, nil
+}
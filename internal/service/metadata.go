@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+func (s *Service) GetUrlMetadata(ctx context.Context, short_url string) (*model.UrlMetadata, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.GetUrlMetadata(ctx, short_url)
+	return result, wrapError(ctx, err)
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/qr"
+)
+
+func (s *Service) GetQrCode(ctx context.Context, short_url, content string, opts qr.Options) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidQrOptions, err)
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.storage.GetUrlByShort(ctx, short_url, model.RedirectInfo{ShortUrl: short_url}); err != nil {
+		return nil, wrapError(ctx, err)
+	}
+
+	return qr.Encode(content, opts)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+const (
+	utmSource   = "utm_source"
+	utmMedium   = "utm_medium"
+	utmCampaign = "utm_campaign"
+	utmTerm     = "utm_term"
+	utmContent  = "utm_content"
+)
+
+func normalizeQueryPolicy(policy string) (string, error) {
+	policy = strings.ToLower(strings.TrimSpace(policy))
+	switch policy {
+	case "":
+		return model.QueryPolicyNone, nil
+	case model.QueryPolicyNone, model.QueryPolicyKeep, model.QueryPolicyOverride:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: unknown query policy %q, expected one of %v", ErrInvalidQueryPolicy, policy,
+			[]string{model.QueryPolicyNone, model.QueryPolicyKeep, model.QueryPolicyOverride})
+	}
+}
+
+func normalizeUtm(utm model.Utm) model.Utm {
+	return model.Utm{
+		Source:   strings.TrimSpace(utm.Source),
+		Medium:   strings.TrimSpace(utm.Medium),
+		Campaign: strings.TrimSpace(utm.Campaign),
+		Term:     strings.TrimSpace(utm.Term),
+		Content:  strings.TrimSpace(utm.Content),
+	}
+}
+
+// clickUtm attributes a click to a campaign: utm values of the short link
+// request win, missing ones are taken from the final destination.
+func clickUtm(incoming url.Values, destination string) model.Utm {
+	utm := utmFromQuery(incoming)
+
+	target, err := url.Parse(destination)
+	if err != nil {
+		return utm
+	}
+	fromDestination := utmFromQuery(target.Query())
+
+	for _, field := range []struct{ value, fallback *string }{
+		{&utm.Source, &fromDestination.Source},
+		{&utm.Medium, &fromDestination.Medium},
+		{&utm.Campaign, &fromDestination.Campaign},
+		{&utm.Term, &fromDestination.Term},
+		{&utm.Content, &fromDestination.Content},
+	} {
+		if *field.value == "" {
+			*field.value = *field.fallback
+		}
+	}
+
+	return utm
+}
+
+func utmFromQuery(query url.Values) model.Utm {
+	return model.Utm{
+		Source:   query.Get(utmSource),
+		Medium:   query.Get(utmMedium),
+		Campaign: query.Get(utmCampaign),
+		Term:     query.Get(utmTerm),
+		Content:  query.Get(utmContent),
+	}
+}
+
+// applyQuery builds the final destination: fixed UTM parameters of the link
+// replace the ones in the destination, then the query of the short link
+// request is merged according to policy. The destination fragment is kept.
+func applyQuery(destination, policy string, utm model.Utm, incoming url.Values) string {
+	if utm == (model.Utm{}) && (policy == model.QueryPolicyNone || policy == "" || len(incoming) == 0) {
+		return destination
+	}
+
+	target, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+	params := target.Query()
+
+	for key, value := range map[string]string{
+		utmSource:   utm.Source,
+		utmMedium:   utm.Medium,
+		utmCampaign: utm.Campaign,
+		utmTerm:     utm.Term,
+		utmContent:  utm.Content,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+
+	if policy == model.QueryPolicyKeep || policy == model.QueryPolicyOverride {
+		for key, values := range incoming {
+			if _, ok := params[key]; ok && policy == model.QueryPolicyKeep {
+				continue
+			}
+			params[key] = values
+		}
+	}
+
+	target.RawQuery = params.Encode()
+	return target.String()
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/useragent"
+)
+
+func (s *Service) SetTargetingRules(ctx context.Context, short_url string, rules []model.TargetingRule) ([]model.TargetingRule, error) {
+	rules, err := normalizeRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.SetTargetingRules(ctx, short_url, rules)
+	return result, wrapError(ctx, err)
+}
+
+func (s *Service) GetTargetingRules(ctx context.Context, short_url string) ([]model.TargetingRule, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.GetTargetingRules(ctx, short_url)
+	return result, wrapError(ctx, err)
+}
+
+// normalizeRules validates rules and assigns positions in the order the
+// rules were given, the first matching rule wins on redirect.
+func normalizeRules(rules []model.TargetingRule) ([]model.TargetingRule, error) {
+	normalized := make([]model.TargetingRule, 0, len(rules))
+	for i, rule := range rules {
+		rule.Id = 0
+		rule.Position = i + 1
+		rule.Os = strings.ToLower(strings.TrimSpace(rule.Os))
+		rule.Device = strings.ToLower(strings.TrimSpace(rule.Device))
+		rule.Browser = strings.ToLower(strings.TrimSpace(rule.Browser))
+		rule.Language = strings.ToLower(strings.TrimSpace(rule.Language))
+		rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+		rule.Destination = strings.TrimSpace(rule.Destination)
+
+		switch {
+		case rule.Destination == "":
+			return nil, fmt.Errorf("%w: rule %d has no destination", ErrInvalidRule, rule.Position)
+		case rule.Os != "" && !slices.Contains(useragent.OSes, rule.Os):
+			return nil, fmt.Errorf("%w: unknown os %q, expected one of %v", ErrInvalidRule, rule.Os, useragent.OSes)
+		case rule.Device != "" && !slices.Contains(useragent.Devices, rule.Device):
+			return nil, fmt.Errorf("%w: unknown device %q, expected one of %v", ErrInvalidRule, rule.Device, useragent.Devices)
+		case rule.Browser != "" && !slices.Contains(useragent.Browsers, rule.Browser):
+			return nil, fmt.Errorf("%w: unknown browser %q, expected one of %v", ErrInvalidRule, rule.Browser, useragent.Browsers)
+		case rule.Country != "" && len(rule.Country) != 2:
+			return nil, fmt.Errorf("%w: country must be a two letter code", ErrInvalidRule)
+		}
+
+		rule.Destination = validateUrlScheme(rule.Destination)
+		normalized = append(normalized, rule)
+	}
+
+	return normalized, nil
+}
+
+// matchRule returns the first rule whose conditions all hold for the
+// request. Empty conditions match any value.
+func matchRule(rules []model.TargetingRule, redirectInfo model.RedirectInfo) (model.TargetingRule, bool) {
+	if len(rules) == 0 {
+		return model.TargetingRule{}, false
+	}
+
+	info := useragent.Parse(redirectInfo.UserAgent)
+	for _, rule := range rules {
+		if rule.Os != "" && rule.Os != info.OS {
+			continue
+		}
+		if rule.Device != "" && rule.Device != info.Device {
+			continue
+		}
+		if rule.Browser != "" && rule.Browser != info.Browser {
+			continue
+		}
+		if rule.Language != "" && !matchLanguage(rule.Language, redirectInfo.Languages) {
+			continue
+		}
+		if rule.Country != "" && !strings.EqualFold(rule.Country, redirectInfo.Country) {
+			continue
+		}
+		return rule, true
+	}
+
+	return model.TargetingRule{}, false
+}
+
+// matchLanguage reports whether language is accepted by the visitor. A rule
+// for "en" matches "en-US" while a rule for "en-us" only matches that region.
+func matchLanguage(language string, accepted []string) bool {
+	for _, tag := range accepted {
+		tag = strings.ToLower(tag)
+		if tag == language || strings.HasPrefix(tag, language+"-") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,33 +1,118 @@
 package service
 
 import (
+	"context"
+	"time"
+
 	"github.com/Komilov31/url-shortener/internal/dto"
 	"github.com/Komilov31/url-shortener/internal/model"
 )
 
 type Storage interface {
-	CreateShortUrl(model.Url) (*model.Url, error)
-	CreateRedirectInfo(model.RedirectInfo) error
-	GetUrlByShort(string, model.RedirectInfo) (*model.Url, error)
-	GetAnalytics(string) ([]dto.RedirectInfo, error)
-	AggregateByUserAgent() ([]dto.UserAgentDTO, error)
-	AggregateByDate() ([]dto.DateDTO, error)
-	AggregateByMonth() ([]dto.MonthDTO, error)
+	CreateShortUrl(context.Context, model.Url) (*model.Url, error)
+	CreateRedirectInfo(context.Context, model.RedirectInfo) error
+	GetUrlByShort(context.Context, string, model.RedirectInfo) (*model.Url, error)
+	GetAnalytics(context.Context, string) ([]dto.RedirectInfo, error)
+	AggregateByUserAgent(context.Context, dto.LinkFilter) ([]dto.UserAgentDTO, error)
+	AggregateByDate(context.Context, dto.LinkFilter) ([]dto.DateDTO, error)
+	AggregateByMonth(context.Context, dto.LinkFilter) ([]dto.MonthDTO, error)
+	AggregateByCampaign(context.Context, string, dto.LinkFilter) ([]dto.CampaignDTO, error)
+	AggregateByReferrer(context.Context, dto.LinkFilter) ([]dto.ReferrerDTO, error)
+	AggregateByTag(context.Context, dto.LinkFilter) ([]dto.TagDTO, error)
+	GetUrlMetadata(context.Context, string) (*model.UrlMetadata, error)
+	GetUnhealthyUrls(context.Context) ([]model.UrlHealth, error)
+	SetTargetingRules(context.Context, string, []model.TargetingRule) ([]model.TargetingRule, error)
+	GetTargetingRules(context.Context, string) ([]model.TargetingRule, error)
+	SetVariants(context.Context, dto.VariantsDTO) (*dto.VariantsDTO, error)
+	GetVariants(context.Context, string) (*dto.VariantsDTO, error)
+	SearchLinks(context.Context, dto.LinkQuery) ([]dto.LinkDTO, error)
+	GetLink(context.Context, string) (*dto.LinkDTO, error)
+	SetDisabled(context.Context, string, bool) (*dto.LinkDTO, error)
+	SetTags(context.Context, string, []string) (*dto.LinkDTO, error)
+	SetFolder(context.Context, string, string) (*dto.LinkDTO, error)
+	DeleteLink(context.Context, string) (*model.Url, error)
+	PurgeAnalytics(context.Context, string, time.Time) (int, error)
+	CreateApiKey(context.Context, model.ApiKey) (*model.ApiKey, error)
+	ListApiKeys(context.Context) ([]model.ApiKey, error)
+	GetApiKeyByHash(context.Context, string) (*model.ApiKey, error)
+	RevokeApiKey(context.Context, int) (*model.ApiKey, error)
+	ClaimIdempotencyKey(context.Context, model.IdempotencyKey, time.Duration) (*model.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(context.Context, string, string, string, time.Duration) error
+	ReleaseIdempotencyKey(context.Context, string, string) error
+	PurgeIdempotencyKeys(context.Context) (int, error)
 }
 
 type Cache interface {
-	Get(string) (string, error)
-	Set(string, interface{}) error
+	Get(context.Context, string) (string, error)
+	Set(context.Context, string, interface{}) error
+	Delete(context.Context, ...string) error
+}
+
+type MetadataQueue interface {
+	Enqueue(model.Url)
+}
+
+// Metrics receives the events that are only visible inside the service.
+// Redirect is called for every successful redirect with the source of the
+// click (direct or qr) and what decided the destination: cache, rule,
+// variant, fallback or destination.
+type Metrics interface {
+	Redirect(source, target string)
+	ShortUrlCollision()
 }
 
+type nopMetrics struct{}
+
+func (nopMetrics) Redirect(string, string) {}
+func (nopMetrics) ShortUrlCollision()      {}
+
 type Service struct {
-	storage Storage
-	cache   Cache
+	storage  Storage
+	cache    Cache
+	metadata MetadataQueue
+	metrics  Metrics
+	timeout  time.Duration
+
+	idempotencyTTL   time.Duration
+	idempotencyLease time.Duration
 }
 
-func New(storage Storage, cache Cache) *Service {
+// New creates a service. timeout bounds every storage and cache operation
+// started by a single service call; zero means no deadline besides the
+// caller's own context.
+func New(storage Storage, cache Cache, metadata MetadataQueue, timeout time.Duration) *Service {
 	return &Service{
-		storage: storage,
-		cache:   cache,
+		storage:  storage,
+		cache:    cache,
+		metadata: metadata,
+		metrics:  nopMetrics{},
+		timeout:  timeout,
+
+		idempotencyTTL:   DefaultIdempotencyTTL,
+		idempotencyLease: DefaultIdempotencyLease,
+	}
+}
+
+// SetMetrics makes the service report to metrics instead of nowhere.
+func (s *Service) SetMetrics(metrics Metrics) {
+	s.metrics = metrics
+}
+
+// SetIdempotencyTTL sets how long the result of a request with an
+// idempotency key is replayed, DefaultIdempotencyTTL unless set.
+func (s *Service) SetIdempotencyTTL(ttl time.Duration) {
+	s.idempotencyTTL = ttl
+}
+
+// SetIdempotencyLease sets how long a key stays claimed by a request that
+// has not completed, DefaultIdempotencyLease unless set.
+func (s *Service) SetIdempotencyLease(lease time.Duration) {
+	s.idempotencyLease = lease
+}
+
+func (s *Service) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, s.timeout)
 }
@@ -1,10 +1,20 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	memorycache "github.com/Komilov31/url-shortener/internal/cache/memory"
 	"github.com/Komilov31/url-shortener/internal/dto"
 	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/qr"
+	"github.com/Komilov31/url-shortener/internal/referrer"
+	"github.com/Komilov31/url-shortener/internal/repository"
+	memoryrepo "github.com/Komilov31/url-shortener/internal/repository/memory"
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -15,108 +25,414 @@ type MockStorage struct {
 	mock.Mock
 }
 
-func (m *MockStorage) CreateShortUrl(url model.Url) (*model.Url, error) {
-	args := m.Called(url)
+func (m *MockStorage) CreateShortUrl(ctx context.Context, url model.Url) (*model.Url, error) {
+	args := m.Called(ctx, url)
 	return args.Get(0).(*model.Url), args.Error(1)
 }
 
-func (m *MockStorage) CreateRedirectInfo(redirectInfo model.RedirectInfo) error {
-	args := m.Called(redirectInfo)
+func (m *MockStorage) CreateRedirectInfo(ctx context.Context, redirectInfo model.RedirectInfo) error {
+	args := m.Called(ctx, redirectInfo)
 	return args.Error(0)
 }
 
-func (m *MockStorage) GetUrlByShort(short string, redirectInfo model.RedirectInfo) (*model.Url, error) {
-	args := m.Called(short, redirectInfo)
+func (m *MockStorage) GetUrlByShort(ctx context.Context, short string, redirectInfo model.RedirectInfo) (*model.Url, error) {
+	args := m.Called(ctx, short, redirectInfo)
 	return args.Get(0).(*model.Url), args.Error(1)
 }
 
-func (m *MockStorage) GetAnalytics(short_url string) ([]dto.RedirectInfo, error) {
-	args := m.Called(short_url)
+func (m *MockStorage) GetAnalytics(ctx context.Context, short_url string) ([]dto.RedirectInfo, error) {
+	args := m.Called(ctx, short_url)
 	return args.Get(0).([]dto.RedirectInfo), args.Error(1)
 }
 
-func (m *MockStorage) AggregateByUserAgent() ([]dto.UserAgentDTO, error) {
-	args := m.Called()
+func (m *MockStorage) AggregateByUserAgent(ctx context.Context, filter dto.LinkFilter) ([]dto.UserAgentDTO, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]dto.UserAgentDTO), args.Error(1)
 }
 
-func (m *MockStorage) AggregateByDate() ([]dto.DateDTO, error) {
-	args := m.Called()
+func (m *MockStorage) AggregateByDate(ctx context.Context, filter dto.LinkFilter) ([]dto.DateDTO, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]dto.DateDTO), args.Error(1)
 }
 
-func (m *MockStorage) AggregateByMonth() ([]dto.MonthDTO, error) {
-	args := m.Called()
+func (m *MockStorage) AggregateByMonth(ctx context.Context, filter dto.LinkFilter) ([]dto.MonthDTO, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).([]dto.MonthDTO), args.Error(1)
 }
 
+func (m *MockStorage) AggregateByCampaign(ctx context.Context, period string, filter dto.LinkFilter) ([]dto.CampaignDTO, error) {
+	args := m.Called(ctx, period, filter)
+	return args.Get(0).([]dto.CampaignDTO), args.Error(1)
+}
+
+func (m *MockStorage) AggregateByReferrer(ctx context.Context, filter dto.LinkFilter) ([]dto.ReferrerDTO, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]dto.ReferrerDTO), args.Error(1)
+}
+
+func (m *MockStorage) AggregateByTag(ctx context.Context, filter dto.LinkFilter) ([]dto.TagDTO, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]dto.TagDTO), args.Error(1)
+}
+
+func (m *MockStorage) SearchLinks(ctx context.Context, search dto.LinkQuery) ([]dto.LinkDTO, error) {
+	args := m.Called(ctx, search)
+	return args.Get(0).([]dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockStorage) SetDisabled(ctx context.Context, short_url string, disabled bool) (*dto.LinkDTO, error) {
+	args := m.Called(ctx, short_url, disabled)
+	return args.Get(0).(*dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockStorage) SetTags(ctx context.Context, short_url string, tags []string) (*dto.LinkDTO, error) {
+	args := m.Called(ctx, short_url, tags)
+	return args.Get(0).(*dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockStorage) SetFolder(ctx context.Context, short_url string, folder string) (*dto.LinkDTO, error) {
+	args := m.Called(ctx, short_url, folder)
+	return args.Get(0).(*dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockStorage) GetUrlMetadata(ctx context.Context, short_url string) (*model.UrlMetadata, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).(*model.UrlMetadata), args.Error(1)
+}
+
+func (m *MockStorage) GetUnhealthyUrls(ctx context.Context) ([]model.UrlHealth, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.UrlHealth), args.Error(1)
+}
+
+func (m *MockStorage) SetTargetingRules(ctx context.Context, short_url string, rules []model.TargetingRule) ([]model.TargetingRule, error) {
+	args := m.Called(ctx, short_url, rules)
+	return args.Get(0).([]model.TargetingRule), args.Error(1)
+}
+
+func (m *MockStorage) GetTargetingRules(ctx context.Context, short_url string) ([]model.TargetingRule, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).([]model.TargetingRule), args.Error(1)
+}
+
+func (m *MockStorage) SetVariants(ctx context.Context, variants dto.VariantsDTO) (*dto.VariantsDTO, error) {
+	args := m.Called(ctx, variants)
+	return args.Get(0).(*dto.VariantsDTO), args.Error(1)
+}
+
+func (m *MockStorage) GetVariants(ctx context.Context, short_url string) (*dto.VariantsDTO, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).(*dto.VariantsDTO), args.Error(1)
+}
+
+func (m *MockStorage) GetLink(ctx context.Context, short_url string) (*dto.LinkDTO, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).(*dto.LinkDTO), args.Error(1)
+}
+
+func (m *MockStorage) DeleteLink(ctx context.Context, short_url string) (*model.Url, error) {
+	args := m.Called(ctx, short_url)
+	return args.Get(0).(*model.Url), args.Error(1)
+}
+
+func (m *MockStorage) PurgeAnalytics(ctx context.Context, short_url string, before time.Time) (int, error) {
+	args := m.Called(ctx, short_url, before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorage) CreateApiKey(ctx context.Context, key model.ApiKey) (*model.ApiKey, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(*model.ApiKey), args.Error(1)
+}
+
+func (m *MockStorage) ListApiKeys(ctx context.Context) ([]model.ApiKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.ApiKey), args.Error(1)
+}
+
+func (m *MockStorage) GetApiKeyByHash(ctx context.Context, keyHash string) (*model.ApiKey, error) {
+	args := m.Called(ctx, keyHash)
+	return args.Get(0).(*model.ApiKey), args.Error(1)
+}
+
+func (m *MockStorage) RevokeApiKey(ctx context.Context, id int) (*model.ApiKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.ApiKey), args.Error(1)
+}
+
+func (m *MockStorage) ClaimIdempotencyKey(ctx context.Context, key model.IdempotencyKey, ttl time.Duration) (*model.IdempotencyKey, bool, error) {
+	args := m.Called(ctx, key, ttl)
+	return args.Get(0).(*model.IdempotencyKey), args.Bool(1), args.Error(2)
+}
+
+func (m *MockStorage) CompleteIdempotencyKey(ctx context.Context, key, token, response string, ttl time.Duration) error {
+	args := m.Called(ctx, key, token, response, ttl)
+	return args.Error(0)
+}
+
+func (m *MockStorage) ReleaseIdempotencyKey(ctx context.Context, key, token string) error {
+	args := m.Called(ctx, key, token)
+	return args.Error(0)
+}
+
+func (m *MockStorage) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
 // MockCache is a mock implementation of the Cache interface
 type MockCache struct {
 	mock.Mock
 }
 
-func (m *MockCache) Get(key string) (string, error) {
-	args := m.Called(key)
+func (m *MockCache) Get(ctx context.Context, key string) (string, error) {
+	args := m.Called(ctx, key)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockCache) Set(key string, value interface{}) error {
-	args := m.Called(key, value)
+func (m *MockCache) Set(ctx context.Context, key string, value interface{}) error {
+	args := m.Called(ctx, key, value)
+	return args.Error(0)
+}
+
+func (m *MockCache) Delete(ctx context.Context, keys ...string) error {
+	args := m.Called(ctx, keys)
 	return args.Error(0)
 }
 
+// MockMetadataQueue is a mock implementation of the MetadataQueue interface
+type MockMetadataQueue struct {
+	mock.Mock
+}
+
+func (m *MockMetadataQueue) Enqueue(url model.Url) {
+	m.Called(url)
+}
+
+// MockMetrics is a mock implementation of the Metrics interface
+type MockMetrics struct {
+	mock.Mock
+}
+
+func (m *MockMetrics) Redirect(source, target string) {
+	m.Called(source, target)
+}
+
+func (m *MockMetrics) ShortUrlCollision() {
+	m.Called()
+}
+
 func TestService_CreateShortUrl_CacheHit(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockCache := new(MockCache)
-	service := New(mockStorage, mockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
 
 	url := model.Url{Url: "https://example.com"}
 	shortUrl := "abc123"
 
-	mockCache.On("Get", url.Url).Return(shortUrl, nil)
+	mockCache.On("Get", mock.Anything, url.Url).Return(shortUrl, nil)
+	mockStorage.On("GetLink", mock.Anything, shortUrl).Return(&dto.LinkDTO{ShortUrl: shortUrl, Url: url.Url, Status: model.LinkStatusActive}, nil)
 
-	result, err := service.CreateShortUrl(url)
+	result, err := service.CreateShortUrl(context.Background(), url)
 
 	assert.NoError(t, err)
 	assert.Equal(t, url.Url, result.Url)
 	assert.Equal(t, shortUrl, result.ShortUrl)
 	mockCache.AssertExpectations(t)
-	mockStorage.AssertNotCalled(t, "CreateShortUrl", mock.Anything)
+	mockStorage.AssertNotCalled(t, "CreateShortUrl", mock.Anything, mock.Anything)
+	mockQueue.AssertNotCalled(t, "Enqueue", mock.Anything)
 }
 
 func TestService_CreateShortUrl_CacheMiss(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockCache := new(MockCache)
-	service := New(mockStorage, mockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
 
 	url := model.Url{Url: "https://example.com"}
-	shortUrl := "def456"
-	createdUrl := &model.Url{Url: url.Url, ShortUrl: shortUrl}
+	createdUrl := &model.Url{Url: url.Url}
 
-	mockCache.On("Get", url.Url).Return("", redis.Nil)
-	mockStorage.On("CreateShortUrl", mock.AnythingOfType("model.Url")).Return(createdUrl, nil).Once()
+	mockCache.On("Get", mock.Anything, url.Url).Return("", redis.Nil)
+	mockStorage.On("CreateShortUrl", mock.Anything, mock.AnythingOfType("model.Url")).
+		Run(func(args mock.Arguments) { createdUrl.ShortUrl = args.Get(1).(model.Url).ShortUrl }).
+		Return(createdUrl, nil).Once()
+	mockQueue.On("Enqueue", mock.AnythingOfType("model.Url")).Once()
 
-	result, err := service.CreateShortUrl(url)
+	result, err := service.CreateShortUrl(context.Background(), url)
 
 	assert.NoError(t, err)
 	assert.Equal(t, createdUrl, result)
+	assert.NotEmpty(t, result.ShortUrl)
 	mockCache.AssertExpectations(t)
 	mockStorage.AssertExpectations(t)
+	mockQueue.AssertExpectations(t)
+}
+
+func TestService_CreateShortUrl_ExistingUrlIsNotEnqueued(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	url := model.Url{Url: "https://example.com"}
+	existing := &model.Url{Url: url.Url, ShortUrl: "def456"}
+
+	mockCache.On("Get", mock.Anything, url.Url).Return("", redis.Nil)
+	mockStorage.On("CreateShortUrl", mock.Anything, mock.AnythingOfType("model.Url")).Return(existing, nil).Once()
+
+	result, err := service.CreateShortUrl(context.Background(), url)
+
+	assert.NoError(t, err)
+	assert.Equal(t, existing, result)
+	mockQueue.AssertNotCalled(t, "Enqueue", mock.Anything)
+}
+
+func TestService_CreateShortUrl_DisabledLink(t *testing.T) {
+	mockQueue := new(MockMetadataQueue)
+	cache := memorycache.New()
+	service := New(memoryrepo.New(), cache, mockQueue, time.Second)
+	ctx := context.Background()
+
+	mockQueue.On("Enqueue", mock.Anything).Once()
+	created, err := service.CreateShortUrl(ctx, model.Url{Url: "https://example.com"})
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Set(ctx, "https://example.com", created.ShortUrl))
+
+	_, err = service.SetDisabled(ctx, created.ShortUrl, true)
+	assert.NoError(t, err)
+	_, err = cache.Get(ctx, "https://example.com")
+	assert.ErrorIs(t, err, redis.Nil)
+
+	_, err = service.CreateShortUrl(ctx, model.Url{Url: "https://example.com"})
+	assert.ErrorIs(t, err, ErrUrlExists)
+	assert.ErrorContains(t, err, "which is disabled")
+
+	// A cache entry that outlived the change is checked as well.
+	assert.NoError(t, cache.Set(ctx, "https://example.com", created.ShortUrl))
+	_, err = service.CreateShortUrl(ctx, model.Url{Url: "https://example.com"})
+	assert.ErrorIs(t, err, ErrUrlExists)
+	assert.ErrorContains(t, err, "which is disabled")
+	mockQueue.AssertExpectations(t)
+}
+
+func TestService_CreateShortUrl_ExpiredLink(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	service := New(mockStorage, mockCache, new(MockMetadataQueue), time.Second)
+
+	expired := time.Now().Add(-time.Hour)
+	mockCache.On("Get", mock.Anything, "https://example.com").Return("", redis.Nil)
+	mockStorage.On("CreateShortUrl", mock.Anything, mock.AnythingOfType("model.Url")).
+		Return(&model.Url{Url: "https://example.com", ShortUrl: "def456", ExpiresAt: &expired}, nil).Once()
+
+	_, err := service.CreateShortUrl(context.Background(), model.Url{Url: "https://example.com"})
+
+	assert.ErrorIs(t, err, ErrUrlExists)
+	assert.ErrorContains(t, err, "def456, which is expired")
+}
+
+func TestService_CreateShortUrl_CollisionMetrics(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	mockMetrics := new(MockMetrics)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+	service.SetMetrics(mockMetrics)
+
+	url := model.Url{Url: "https://example.com"}
+	createdUrl := &model.Url{Url: url.Url, ShortUrl: "def456"}
+
+	mockCache.On("Get", mock.Anything, url.Url).Return("", redis.Nil)
+	mockStorage.On("CreateShortUrl", mock.Anything, mock.AnythingOfType("model.Url")).Return((*model.Url)(nil), repository.ErrUniqueConstraint).Twice()
+	mockStorage.On("CreateShortUrl", mock.Anything, mock.AnythingOfType("model.Url")).Return(createdUrl, nil).Once()
+	mockQueue.On("Enqueue", *createdUrl).Once()
+	mockMetrics.On("ShortUrlCollision").Twice()
+
+	result, err := service.CreateShortUrl(context.Background(), url)
+
+	assert.NoError(t, err)
+	assert.Equal(t, createdUrl, result)
+	mockStorage.AssertExpectations(t)
+	mockMetrics.AssertExpectations(t)
+}
+
+func TestService_CreateShortUrl_ExistingUrlWithOptions(t *testing.T) {
+	mockQueue := new(MockMetadataQueue)
+	cache := memorycache.New()
+	service := New(memoryrepo.New(), cache, mockQueue, time.Second)
+	ctx := context.Background()
+
+	mockQueue.On("Enqueue", mock.Anything).Once()
+	created, err := service.CreateShortUrl(ctx, model.Url{Url: "https://example.com"})
+	assert.NoError(t, err)
+
+	again, err := service.CreateShortUrl(ctx, model.Url{Url: "https://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, created.ShortUrl, again.ShortUrl)
+
+	for name, url := range map[string]model.Url{
+		"Tags":     {Url: "https://example.com", Tags: []string{"promo"}},
+		"Rules":    {Url: "https://example.com", Rules: []model.TargetingRule{{Os: "ios", Destination: "https://apps.apple.com"}}},
+		"Fallback": {Url: "https://example.com", FallbackUrl: "https://example.org"},
+		"Query":    {Url: "https://example.com", QueryPolicy: model.QueryPolicyKeep},
+	} {
+		_, err := service.CreateShortUrl(ctx, url)
+		assert.ErrorIs(t, err, ErrUrlExists, name)
+		assert.Contains(t, err.Error(), created.ShortUrl, name)
+	}
+
+	// A cached destination is answered without the storage.
+	assert.NoError(t, cache.Set(ctx, "https://example.com", created.ShortUrl))
+	_, err = service.CreateShortUrl(ctx, model.Url{Url: "https://example.com", Owner: "alice"})
+	assert.ErrorIs(t, err, ErrUrlExists)
+	mockQueue.AssertExpectations(t)
+}
+
+func TestService_GetUrlByShort_RedirectMetrics(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	mockMetrics := new(MockMetrics)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+	service.SetMetrics(mockMetrics)
+
+	mockCache.On("Get", mock.Anything, "abc123").Return("https://example.com", nil)
+	mockCache.On("Get", mock.Anything, "def456").Return("", redis.Nil)
+	mockStorage.On("GetUrlByShort", mock.Anything, "def456", mock.Anything).Return(&model.Url{
+		ShortUrl:    "def456",
+		Url:         "https://example.com",
+		FallbackUrl: "https://example.org",
+	}, nil)
+	mockStorage.On("CreateRedirectInfo", mock.Anything, mock.Anything).Return(nil)
+	mockMetrics.On("Redirect", model.SourceQr, "cache").Once()
+	mockMetrics.On("Redirect", model.SourceDirect, "fallback").Once()
+
+	_, err := service.GetUrlByShort(context.Background(), "abc123", model.RedirectInfo{ShortUrl: "abc123", Source: model.SourceQr})
+	assert.NoError(t, err)
+	url, err := service.GetUrlByShort(context.Background(), "def456", model.RedirectInfo{ShortUrl: "def456", Source: model.SourceDirect})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org", url.Url)
+
+	mockMetrics.AssertExpectations(t)
 }
 
 func TestService_GetUrlByShort_CacheHit(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockCache := new(MockCache)
-	service := New(mockStorage, mockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
 
 	shortUrl := "abc123"
 	originalUrl := "https://example.com"
 	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl}
 
-	mockCache.On("Get", shortUrl).Return(originalUrl, nil)
-	mockStorage.On("CreateRedirectInfo", redirectInfo).Return(nil)
+	mockCache.On("Get", mock.Anything, shortUrl).Return(originalUrl, nil)
+	recorded := redirectInfo
+	recorded.ReferrerDomain = referrer.Direct
+	mockStorage.On("CreateRedirectInfo", mock.Anything, recorded).Return(nil)
 
-	result, err := service.GetUrlByShort(shortUrl, redirectInfo)
+	result, err := service.GetUrlByShort(context.Background(), shortUrl, redirectInfo)
 
 	assert.NoError(t, err)
 	assert.Equal(t, shortUrl, result.ShortUrl)
@@ -128,17 +444,21 @@ func TestService_GetUrlByShort_CacheHit(t *testing.T) {
 func TestService_GetUrlByShort_CacheMiss(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockCache := new(MockCache)
-	service := New(mockStorage, mockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
 
 	shortUrl := "abc123"
 	originalUrl := "https://example.com"
 	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl}
 	urlInfo := &model.Url{ShortUrl: shortUrl, Url: originalUrl}
 
-	mockCache.On("Get", shortUrl).Return("", redis.Nil)
-	mockStorage.On("GetUrlByShort", shortUrl, redirectInfo).Return(urlInfo, nil)
+	mockCache.On("Get", mock.Anything, shortUrl).Return("", redis.Nil)
+	mockStorage.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+	recorded := redirectInfo
+	recorded.ReferrerDomain = referrer.Direct
+	mockStorage.On("CreateRedirectInfo", mock.Anything, recorded).Return(nil)
 
-	result, err := service.GetUrlByShort(shortUrl, redirectInfo)
+	result, err := service.GetUrlByShort(context.Background(), shortUrl, redirectInfo)
 
 	assert.NoError(t, err)
 	assert.Equal(t, urlInfo, result)
@@ -149,17 +469,18 @@ func TestService_GetUrlByShort_CacheMiss(t *testing.T) {
 func TestService_GetAnalytics(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockCache := new(MockCache)
-	service := New(mockStorage, mockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
 
 	shortUrl := "abc123"
 	analytics := []dto.RedirectInfo{
 		{Url: "https://example.com", ShortUrl: shortUrl, RedirectCount: 10},
 	}
 
-	mockStorage.On("GetAnalytics", shortUrl).Return(analytics, nil)
-	mockCache.On("Set", "https://example.com", shortUrl).Return(nil)
+	mockStorage.On("GetAnalytics", mock.Anything, shortUrl).Return(analytics, nil)
+	mockCache.On("Set", mock.Anything, "https://example.com", shortUrl).Return(nil)
 
-	result, err := service.GetAnalytics(shortUrl)
+	result, err := service.GetAnalytics(context.Background(), shortUrl)
 
 	assert.NoError(t, err)
 	assert.Equal(t, analytics, result)
@@ -170,15 +491,16 @@ func TestService_GetAnalytics(t *testing.T) {
 func TestService_AggregateByUserAgent(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockCache := new(MockCache)
-	service := New(mockStorage, mockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
 
 	expected := []dto.UserAgentDTO{
 		{ShortUrl: "abc123", UserAgent: []string{"Mozilla/5.0"}, RedirectCount: 5},
 	}
 
-	mockStorage.On("AggregateByUserAgent").Return(expected, nil)
+	mockStorage.On("AggregateByUserAgent", mock.Anything, dto.LinkFilter{}).Return(expected, nil)
 
-	result, err := service.AggregateByUserAgent()
+	result, err := service.AggregateByUserAgent(context.Background(), dto.LinkFilter{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
@@ -188,15 +510,16 @@ func TestService_AggregateByUserAgent(t *testing.T) {
 func TestService_AggregateByDate(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockCache := new(MockCache)
-	service := New(mockStorage, mockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
 
 	expected := []dto.DateDTO{
 		{Day: 1, Month: 1, Year: 2023, UrlInfo: []dto.UrlInfo{{ShortUrl: "abc123", Time: "10:00"}}, RedirectCount: 10},
 	}
 
-	mockStorage.On("AggregateByDate").Return(expected, nil)
+	mockStorage.On("AggregateByDate", mock.Anything, dto.LinkFilter{}).Return(expected, nil)
 
-	result, err := service.AggregateByDate()
+	result, err := service.AggregateByDate(context.Background(), dto.LinkFilter{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
@@ -206,7 +529,8 @@ func TestService_AggregateByDate(t *testing.T) {
 func TestService_AggregateByMonth(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockCache := new(MockCache)
-	service := New(mockStorage, mockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
 
 	expected := []dto.MonthDTO{
 		{Month: 1, Year: 2023, UrlInfo: []struct {
@@ -215,11 +539,1001 @@ func TestService_AggregateByMonth(t *testing.T) {
 		}{{ShortUrl: "abc123", Time: "10:00"}}, RedirectCount: 100},
 	}
 
-	mockStorage.On("AggregateByMonth").Return(expected, nil)
+	mockStorage.On("AggregateByMonth", mock.Anything, dto.LinkFilter{}).Return(expected, nil)
+
+	result, err := service.AggregateByMonth(context.Background(), dto.LinkFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_GetUrlMetadata(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	expected := &model.UrlMetadata{ShortUrl: "abc123", Title: "Example", Status: model.MetadataStatusOk}
+
+	mockStorage.On("GetUrlMetadata", mock.Anything, "abc123").Return(expected, nil)
+
+	result, err := service.GetUrlMetadata(context.Background(), "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_GetUrlByShort_UnhealthyFallback(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl}
+	urlInfo := &model.Url{
+		ShortUrl:    shortUrl,
+		Url:         "https://example.com",
+		FallbackUrl: "https://fallback.example.com",
+		Healthy:     false,
+	}
+
+	mockCache.On("Get", mock.Anything, shortUrl).Return("", redis.Nil)
+	mockStorage.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+	recorded := redirectInfo
+	recorded.ReferrerDomain = referrer.Direct
+	mockStorage.On("CreateRedirectInfo", mock.Anything, recorded).Return(nil)
+
+	result, err := service.GetUrlByShort(context.Background(), shortUrl, redirectInfo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://fallback.example.com", result.Url)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_GetUnhealthyUrls(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	expected := []model.UrlHealth{
+		{ShortUrl: "abc123", Url: "https://example.com", StatusCode: 404},
+	}
+
+	mockStorage.On("GetUnhealthyUrls", mock.Anything).Return(expected, nil)
+
+	result, err := service.GetUnhealthyUrls(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_GetQrCode(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl}
+	urlInfo := &model.Url{ShortUrl: shortUrl, Url: "https://example.com"}
+
+	mockStorage.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+
+	image, err := service.GetQrCode(context.Background(), shortUrl, "http://localhost/s/abc123?source=qr", qr.DefaultOptions())
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, image)
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "CreateRedirectInfo", mock.Anything, mock.Anything)
+}
+
+func TestService_GetUrlByShort_TargetingRules(t *testing.T) {
+	const (
+		iphone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+		android = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+		desktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	)
+
+	rules := []model.TargetingRule{
+		{Id: 1, Position: 1, Os: "ios", Destination: "https://apps.apple.com/app"},
+		{Id: 2, Position: 2, Os: "android", Destination: "https://play.google.com/app"},
+		{Id: 3, Position: 3, Language: "de", Country: "DE", Destination: "https://example.de"},
+	}
+
+	tests := []struct {
+		name         string
+		redirectInfo model.RedirectInfo
+		expectedUrl  string
+		expectedRule int
+	}{
+		{name: "ios", redirectInfo: model.RedirectInfo{UserAgent: iphone}, expectedUrl: "https://apps.apple.com/app", expectedRule: 1},
+		{name: "android", redirectInfo: model.RedirectInfo{UserAgent: android}, expectedUrl: "https://play.google.com/app", expectedRule: 2},
+		{name: "language and country", redirectInfo: model.RedirectInfo{UserAgent: desktop, Languages: []string{"de-DE"}, Country: "DE"}, expectedUrl: "https://example.de", expectedRule: 3},
+		{name: "no match", redirectInfo: model.RedirectInfo{UserAgent: desktop, Languages: []string{"de-DE"}, Country: "AT"}, expectedUrl: "https://example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			mockCache := new(MockCache)
+			mockQueue := new(MockMetadataQueue)
+			service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+			shortUrl := "abc123"
+			tt.redirectInfo.ShortUrl = shortUrl
+			urlInfo := &model.Url{ShortUrl: shortUrl, Url: "https://example.com", Rules: rules, Healthy: true}
+
+			recorded := tt.redirectInfo
+			recorded.MatchedRule = tt.expectedRule
+			recorded.ReferrerDomain = referrer.Direct
+
+			mockCache.On("Get", mock.Anything, shortUrl).Return("", redis.Nil)
+			mockStorage.On("GetUrlByShort", mock.Anything, shortUrl, tt.redirectInfo).Return(urlInfo, nil)
+			mockStorage.On("CreateRedirectInfo", mock.Anything, recorded).Return(nil)
+
+			result, err := service.GetUrlByShort(context.Background(), shortUrl, tt.redirectInfo)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedUrl, result.Url)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_SetTargetingRules_Invalid(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	_, err := service.SetTargetingRules(context.Background(), "abc123", []model.TargetingRule{{Os: "symbian", Destination: "https://example.com"}})
+
+	assert.ErrorIs(t, err, ErrInvalidRule)
+	mockStorage.AssertNotCalled(t, "SetTargetingRules", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_SetTargetingRules(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	rules := []model.TargetingRule{
+		{Os: " iOS ", Destination: "apps.apple.com/app"},
+		{Country: "de", Destination: "https://example.de"},
+	}
+	normalized := []model.TargetingRule{
+		{Position: 1, Os: "ios", Destination: "https://apps.apple.com/app"},
+		{Position: 2, Country: "DE", Destination: "https://example.de"},
+	}
+
+	mockStorage.On("SetTargetingRules", mock.Anything, "abc123", normalized).Return(normalized, nil)
+
+	result, err := service.SetTargetingRules(context.Background(), "abc123", rules)
+
+	assert.NoError(t, err)
+	assert.Equal(t, normalized, result)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_GetUrlByShort_StickyVariant(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl, UserAgent: "test-agent", Variant: "b"}
+	urlInfo := &model.Url{
+		ShortUrl: shortUrl,
+		Url:      "https://example.com",
+		Variants: []model.Variant{
+			{Id: 1, Name: "a", Url: "https://a.example.com", Weight: 1000},
+			{Id: 2, Name: "b", Url: "https://b.example.com", Weight: 1},
+		},
+		StickyVariants: true,
+		Healthy:        true,
+	}
+
+	mockCache.On("Get", mock.Anything, shortUrl).Return("", redis.Nil)
+	mockStorage.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+	recorded := redirectInfo
+	recorded.ReferrerDomain = referrer.Direct
+	mockStorage.On("CreateRedirectInfo", mock.Anything, recorded).Return(nil)
+
+	result, err := service.GetUrlByShort(context.Background(), shortUrl, redirectInfo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://b.example.com", result.Url)
+	assert.Equal(t, "b", result.Variant)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestPickVariant_Weights(t *testing.T) {
+	variants := []model.Variant{
+		{Name: "a", Url: "https://a.example.com", Weight: 70},
+		{Name: "b", Url: "https://b.example.com", Weight: 30},
+	}
+
+	const total = 10000
+	counts := make(map[string]int)
+	for i := 0; i < total; i++ {
+		variant, ok := pickVariant(variants, false, "b")
+		assert.True(t, ok)
+		counts[variant.Name]++
+	}
+
+	assert.InDelta(t, 0.7, float64(counts["a"])/total, 0.05)
+	assert.InDelta(t, 0.3, float64(counts["b"])/total, 0.05)
+
+	_, ok := pickVariant(nil, true, "a")
+	assert.False(t, ok)
+}
+
+func TestService_SetVariants_Invalid(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	tests := [][]model.Variant{
+		{{Name: "a", Url: "https://a.example.com", Weight: 0}},
+		{{Name: "a", Weight: 1}},
+		{{Name: "a", Url: "https://a.example.com", Weight: 1}, {Name: "a", Url: "https://b.example.com", Weight: 1}},
+	}
+
+	for _, variants := range tests {
+		_, err := service.SetVariants(context.Background(), dto.VariantsDTO{ShortUrl: "abc123", Variants: variants})
+		assert.ErrorIs(t, err, ErrInvalidVariant)
+	}
+	mockStorage.AssertNotCalled(t, "SetVariants", mock.Anything, mock.Anything)
+}
+
+func TestService_SetVariants(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	variants := dto.VariantsDTO{
+		ShortUrl: "abc123",
+		Sticky:   true,
+		Variants: []model.Variant{
+			{Url: "a.example.com", Weight: 70},
+			{Name: " control ", Url: "https://b.example.com", Weight: 30},
+		},
+	}
+	normalized := dto.VariantsDTO{
+		ShortUrl: "abc123",
+		Sticky:   true,
+		Variants: []model.Variant{
+			{Name: "1", Url: "https://a.example.com", Weight: 70},
+			{Name: "control", Url: "https://b.example.com", Weight: 30},
+		},
+	}
+
+	mockStorage.On("SetVariants", mock.Anything, normalized).Return(&normalized, nil)
+
+	result, err := service.SetVariants(context.Background(), variants)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &normalized, result)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestApplyQuery(t *testing.T) {
+	incoming := url.Values{"utm_source": {"newsletter"}, "ref": {"mail"}}
+
+	tests := []struct {
+		name        string
+		destination string
+		policy      string
+		utm         model.Utm
+		incoming    url.Values
+		expected    string
+	}{
+		{
+			name:        "none keeps destination verbatim",
+			destination: "https://example.com/page?b=2&a=1#top",
+			policy:      model.QueryPolicyNone,
+			incoming:    incoming,
+			expected:    "https://example.com/page?b=2&a=1#top",
+		},
+		{
+			name:        "keep preserves destination values",
+			destination: "https://example.com/page?utm_source=site#top",
+			policy:      model.QueryPolicyKeep,
+			incoming:    incoming,
+			expected:    "https://example.com/page?ref=mail&utm_source=site#top",
+		},
+		{
+			name:        "override replaces destination values",
+			destination: "https://example.com/page?utm_source=site#top",
+			policy:      model.QueryPolicyOverride,
+			incoming:    incoming,
+			expected:    "https://example.com/page?ref=mail&utm_source=newsletter#top",
+		},
+		{
+			name:        "fixed utm is appended",
+			destination: "https://example.com/page#top",
+			policy:      model.QueryPolicyNone,
+			utm:         model.Utm{Source: "shortener", Campaign: "spring"},
+			incoming:    incoming,
+			expected:    "https://example.com/page?utm_campaign=spring&utm_source=shortener#top",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, applyQuery(tt.destination, tt.policy, tt.utm, tt.incoming))
+		})
+	}
+}
+
+func TestService_GetUrlByShort_QueryPassthrough(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	shortUrl := "abc123"
+	query := url.Values{"utm_source": {"newsletter"}, "utm_medium": {"email"}}
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl, Query: query}
+	recorded := redirectInfo
+	recorded.Utm = model.Utm{Source: "newsletter", Medium: "email"}
+	recorded.ReferrerDomain = referrer.Direct
+	urlInfo := &model.Url{
+		ShortUrl:    shortUrl,
+		Url:         "https://example.com/landing#pricing",
+		QueryPolicy: model.QueryPolicyOverride,
+		Healthy:     true,
+	}
+
+	mockCache.On("Get", mock.Anything, shortUrl).Return("", redis.Nil)
+	mockStorage.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+	mockStorage.On("CreateRedirectInfo", mock.Anything, recorded).Return(nil)
+
+	result, err := service.GetUrlByShort(context.Background(), shortUrl, redirectInfo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/landing?utm_medium=email&utm_source=newsletter#pricing", result.Url)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_CreateShortUrl_InvalidQueryPolicy(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	_, err := service.CreateShortUrl(context.Background(), model.Url{Url: "https://example.com", QueryPolicy: "merge"})
+
+	assert.ErrorIs(t, err, ErrInvalidQueryPolicy)
+	mockStorage.AssertNotCalled(t, "CreateShortUrl", mock.Anything, mock.Anything)
+}
+
+func TestService_GetUrlByShort_CampaignAttribution(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{
+		ShortUrl:  shortUrl,
+		UserAgent: "test-agent",
+		ClientIp:  "192.0.2.1",
+		Query:     url.Values{"utm_source": {"newsletter"}},
+	}
+	urlInfo := &model.Url{
+		ShortUrl:    shortUrl,
+		Url:         "https://example.com/?utm_source=site&utm_campaign=spring",
+		QueryPolicy: model.QueryPolicyNone,
+		Healthy:     true,
+	}
+
+	recorded := redirectInfo
+	recorded.Utm = model.Utm{Source: "newsletter", Campaign: "spring"}
+	recorded.VisitorId = visitorId("192.0.2.1", "test-agent")
+	recorded.ReferrerDomain = referrer.Direct
+
+	mockCache.On("Get", mock.Anything, shortUrl).Return("", redis.Nil)
+	mockStorage.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+	mockStorage.On("CreateRedirectInfo", mock.Anything, recorded).Return(nil)
+
+	_, err := service.GetUrlByShort(context.Background(), shortUrl, redirectInfo)
+
+	assert.NoError(t, err)
+	assert.Len(t, recorded.VisitorId, 32)
+	assert.NotEqual(t, recorded.VisitorId, visitorId("192.0.2.2", "test-agent"))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_AggregateByCampaign(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	expected := []dto.CampaignDTO{{Source: "newsletter", Medium: "email", Campaign: "spring", Clicks: 10, Uniques: 7}}
+	mockStorage.On("AggregateByCampaign", mock.Anything, PeriodDay, dto.LinkFilter{}).Return(expected, nil)
+	mockStorage.On("AggregateByCampaign", mock.Anything, PeriodMonth, dto.LinkFilter{}).Return(expected, nil)
+
+	result, err := service.AggregateByCampaign(context.Background(), "", dto.LinkFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	_, err = service.AggregateByCampaign(context.Background(), "Month", dto.LinkFilter{})
+	assert.NoError(t, err)
+
+	_, err = service.AggregateByCampaign(context.Background(), "hour", dto.LinkFilter{})
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_GetUrlByShort_Referrer(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	shortUrl := "abc123"
+	redirectInfo := model.RedirectInfo{ShortUrl: shortUrl, Referrer: "https://t.co/xyz"}
+	urlInfo := &model.Url{ShortUrl: shortUrl, Url: "https://example.com", Healthy: true}
+
+	recorded := redirectInfo
+	recorded.ReferrerDomain = "twitter"
+
+	mockCache.On("Get", mock.Anything, shortUrl).Return("", redis.Nil)
+	mockStorage.On("GetUrlByShort", mock.Anything, shortUrl, redirectInfo).Return(urlInfo, nil)
+	mockStorage.On("CreateRedirectInfo", mock.Anything, recorded).Return(nil)
 
-	result, err := service.AggregateByMonth()
+	_, err := service.GetUrlByShort(context.Background(), shortUrl, redirectInfo)
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_AggregateByReferrer(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	expected := []dto.ReferrerDTO{
+		{
+			ShortUrl:      "abc123",
+			Referrers:     []dto.ReferrerCount{{Referrer: "twitter", RedirectCount: 3}, {Referrer: referrer.Direct, RedirectCount: 1}},
+			RedirectCount: 4,
+		},
+	}
+	mockStorage.On("AggregateByReferrer", mock.Anything, dto.LinkFilter{}).Return(expected, nil)
+
+	result, err := service.AggregateByReferrer(context.Background(), dto.LinkFilter{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
 	mockStorage.AssertExpectations(t)
 }
+
+func TestService_SetTags(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	normalized := []string{"black-friday", "email"}
+	link := &dto.LinkDTO{ShortUrl: "abc123", Url: "https://example.com", Tags: normalized}
+	mockStorage.On("SetTags", mock.Anything, "abc123", normalized).Return(link, nil)
+
+	result, err := service.SetTags(context.Background(), "abc123", []string{" Email", "black-friday", "email"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, link, result)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_SetTags_Invalid(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	for _, tags := range [][]string{{""}, {"black friday"}, {"a,b"}} {
+		_, err := service.SetTags(context.Background(), "abc123", tags)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+	}
+	mockStorage.AssertNotCalled(t, "SetTags", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_SetFolder(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	link := &dto.LinkDTO{ShortUrl: "abc123", Url: "https://example.com", Folder: "Marketing"}
+	mockStorage.On("SetFolder", mock.Anything, "abc123", "Marketing").Return(link, nil)
+
+	result, err := service.SetFolder(context.Background(), "abc123", "  Marketing ")
+	assert.NoError(t, err)
+	assert.Equal(t, link, result)
+
+	_, err = service.SetFolder(context.Background(), "abc123", strings.Repeat("a", 129))
+	assert.ErrorIs(t, err, ErrInvalidFolder)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_AggregateByTag_Filter(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	expected := []dto.TagDTO{{Tag: "black-friday", Links: 3, RedirectCount: 120, Uniques: 80}}
+	mockStorage.On("AggregateByTag", mock.Anything, dto.LinkFilter{Tag: "black-friday", Folder: "Marketing"}).Return(expected, nil)
+
+	result, err := service.AggregateByTag(context.Background(), dto.LinkFilter{Tag: " Black-Friday", Folder: "Marketing "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_GetUrlByShort_Unavailable(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		urlInfo *model.Url
+	}{
+		{name: "disabled", urlInfo: &model.Url{ShortUrl: "abc123", Url: "https://example.com", Disabled: true}},
+		{name: "expired", urlInfo: &model.Url{ShortUrl: "abc123", Url: "https://example.com", ExpiresAt: &expired}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			mockCache := new(MockCache)
+			mockQueue := new(MockMetadataQueue)
+			service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+			redirectInfo := model.RedirectInfo{ShortUrl: "abc123"}
+			mockCache.On("Get", mock.Anything, "abc123").Return("", redis.Nil)
+			mockStorage.On("GetUrlByShort", mock.Anything, "abc123", redirectInfo).Return(tt.urlInfo, nil)
+
+			_, err := service.GetUrlByShort(context.Background(), "abc123", redirectInfo)
+
+			assert.ErrorIs(t, err, ErrLinkUnavailable)
+			mockStorage.AssertNotCalled(t, "CreateRedirectInfo", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_SearchLinks(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	normalized := dto.LinkQuery{
+		LinkFilter: dto.LinkFilter{Tag: "black-friday"},
+		Search:     "example",
+		Status:     model.LinkStatusExpired,
+		Sort:       dto.LinkSortCreated,
+		Order:      dto.LinkOrderDesc,
+		Limit:      DefaultLinksLimit,
+	}
+	mockStorage.On("SearchLinks", mock.Anything, normalized).Return([]dto.LinkDTO{}, nil)
+
+	_, err := service.SearchLinks(context.Background(), dto.LinkQuery{
+		LinkFilter: dto.LinkFilter{Tag: "Black-Friday"},
+		Search:     " example ",
+		Status:     "Expired",
+	})
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_SearchLinks_Invalid(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	minClicks, maxClicks := 10, 5
+	tests := []dto.LinkQuery{
+		{Status: "deleted"},
+		{Sort: "title"},
+		{Order: "up"},
+		{Limit: MaxLinksLimit + 1},
+		{Offset: -1},
+		{MinClicks: &minClicks, MaxClicks: &maxClicks},
+	}
+
+	for _, search := range tests {
+		_, err := service.SearchLinks(context.Background(), search)
+		assert.ErrorIs(t, err, ErrInvalidSearch)
+	}
+	mockStorage.AssertNotCalled(t, "SearchLinks", mock.Anything, mock.Anything)
+}
+
+func TestService_CreateShortUrl_PastExpiration(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	expired := time.Now().Add(-time.Minute)
+	_, err := service.CreateShortUrl(context.Background(), model.Url{Url: "https://example.com", ExpiresAt: &expired})
+
+	assert.ErrorIs(t, err, ErrInvalidExpiration)
+	mockStorage.AssertNotCalled(t, "CreateShortUrl", mock.Anything, mock.Anything)
+}
+
+func TestService_GetAnalytics_Timeout(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, 10*time.Millisecond)
+
+	// The driver reports a cancelled query with its own error, the service
+	// has to make it recognisable as a timeout.
+	mockStorage.On("GetAnalytics", mock.Anything, "abc123").
+		Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return([]dto.RedirectInfo(nil), errors.New("pq: canceling statement due to user request"))
+
+	_, err := service.GetAnalytics(context.Background(), "abc123")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_AggregateByDate_AppliesDeadline(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Minute)
+
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		deadline, ok := ctx.Deadline()
+		return ok && time.Until(deadline) <= time.Minute
+	})
+	mockStorage.On("AggregateByDate", hasDeadline, dto.LinkFilter{}).Return([]dto.DateDTO{}, nil)
+
+	_, err := service.AggregateByDate(context.Background(), dto.LinkFilter{})
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_GetUrlByShort_Cancelled(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockCache.On("Get", mock.Anything, "abc123").Return("", errors.New("redis: connection closed"))
+
+	_, err := service.GetUrlByShort(ctx, "abc123", model.RedirectInfo{ShortUrl: "abc123"})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	mockStorage.AssertNotCalled(t, "GetUrlByShort", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_GetUrlByShort_NotFound(t *testing.T) {
+	service := New(memoryrepo.New(), memorycache.New(), new(MockMetadataQueue), time.Second)
+
+	_, err := service.GetUrlByShort(context.Background(), "missing", model.RedirectInfo{ShortUrl: "missing"})
+
+	var domain *Error
+	assert.ErrorAs(t, err, &domain)
+	assert.Equal(t, KindNotFound, domain.Kind)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+	assert.ErrorIs(t, err, repository.ErrAliasNotFound)
+}
+
+func TestService_GetUrlByShort_CacheUnavailable(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	service := New(mockStorage, mockCache, new(MockMetadataQueue), time.Second)
+
+	mockCache.On("Get", mock.Anything, "abc123").Return("", errors.New("dial tcp 127.0.0.1:6379: connection refused"))
+
+	_, err := service.GetUrlByShort(context.Background(), "abc123", model.RedirectInfo{ShortUrl: "abc123"})
+
+	assert.ErrorIs(t, err, ErrCacheUnavailable)
+	mockStorage.AssertNotCalled(t, "GetUrlByShort", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_InMemoryBackends(t *testing.T) {
+	mockQueue := new(MockMetadataQueue)
+	service := New(memoryrepo.New(), memorycache.New(), mockQueue, time.Second)
+	ctx := context.Background()
+
+	mockQueue.On("Enqueue", mock.Anything).Once()
+
+	created, err := service.CreateShortUrl(ctx, model.Url{Url: "example.com", Tags: []string{"Promo"}})
+	assert.NoError(t, err)
+
+	urlInfo, err := service.GetUrlByShort(ctx, created.ShortUrl, model.RedirectInfo{
+		ShortUrl:  created.ShortUrl,
+		UserAgent: "test-agent",
+		Source:    model.SourceDirect,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", urlInfo.Url)
+
+	analytics, err := service.GetAnalytics(ctx, created.ShortUrl)
+	assert.NoError(t, err)
+	assert.Len(t, analytics, 1)
+	assert.Equal(t, 1, analytics[0].RedirectCount)
+
+	tags, err := service.AggregateByTag(ctx, dto.LinkFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.TagDTO{{Tag: "promo", Links: 1, RedirectCount: 1}}, tags)
+	mockQueue.AssertExpectations(t)
+}
+
+func TestService_DeleteLink(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockCache := new(MockCache)
+	mockQueue := new(MockMetadataQueue)
+	service := New(mockStorage, mockCache, mockQueue, time.Second)
+
+	deleted := &model.Url{Id: 1, Url: "https://example.com", ShortUrl: "abc123"}
+	mockStorage.On("DeleteLink", mock.Anything, "abc123").Return(deleted, nil)
+	mockCache.On("Delete", mock.Anything, []string{"https://example.com", "abc123"}).Return(errors.New("connection refused"))
+
+	result, err := service.DeleteLink(context.Background(), "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, deleted, result)
+	mockStorage.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestService_ImportAndExportLinks(t *testing.T) {
+	mockQueue := new(MockMetadataQueue)
+	service := New(memoryrepo.New(), memorycache.New(), mockQueue, time.Second)
+	ctx := context.Background()
+	expired := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	mockQueue.On("Enqueue", mock.Anything).Twice()
+
+	imported, err := service.ImportLink(ctx, model.Url{
+		Url:       "example.com",
+		ShortUrl:  "old-alias",
+		Tags:      []string{"Promo"},
+		Folder:    "marketing",
+		Owner:     "alice",
+		ExpiresAt: &expired,
+		Disabled:  true,
+		Variants:  []model.Variant{{Name: "a", Url: "https://example.com/a", Weight: 1}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "old-alias", imported.ShortUrl)
+	assert.True(t, imported.Disabled)
+
+	_, err = service.ImportLink(ctx, model.Url{Url: "https://example.org", ShortUrl: "second"})
+	assert.NoError(t, err)
+
+	_, err = service.ImportLink(ctx, model.Url{Url: "https://example.com", ShortUrl: "copy"})
+	assert.ErrorIs(t, err, ErrUrlExists)
+	_, err = service.ImportLink(ctx, model.Url{Url: "https://example.net", ShortUrl: "bad alias"})
+	assert.ErrorIs(t, err, ErrInvalidShortUrl)
+	_, err = service.ImportLink(ctx, model.Url{Url: "https://example.net"})
+	assert.ErrorIs(t, err, ErrInvalidShortUrl)
+
+	exported, err := service.ExportLinks(ctx, dto.LinkQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, exported, 2) {
+		first := exported[0]
+		assert.Equal(t, "old-alias", first.ShortUrl)
+		assert.Equal(t, "https://example.com", first.Url)
+		assert.Equal(t, []string{"promo"}, first.Tags)
+		assert.Equal(t, "marketing", first.Folder)
+		assert.Equal(t, "alice", first.Owner)
+		assert.True(t, first.Disabled)
+		assert.True(t, expired.Equal(*first.ExpiresAt))
+		assert.Len(t, first.Variants, 1)
+		assert.Equal(t, "second", exported[1].ShortUrl)
+	}
+
+	exported, err = service.ExportLinks(ctx, dto.LinkQuery{LinkFilter: dto.LinkFilter{Tag: "promo"}})
+	assert.NoError(t, err)
+	assert.Len(t, exported, 1)
+	mockQueue.AssertExpectations(t)
+}
+
+func TestService_PurgeAnalytics_Invalid(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, new(MockCache), new(MockMetadataQueue), time.Second)
+
+	_, err := service.PurgeAnalytics(context.Background(), "abc123", time.Time{})
+
+	assert.ErrorIs(t, err, ErrInvalidPurge)
+	mockStorage.AssertNotCalled(t, "PurgeAnalytics", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_AuthenticateApiKey(t *testing.T) {
+	service := New(memoryrepo.New(), memorycache.New(), new(MockMetadataQueue), time.Second)
+	ctx := context.Background()
+
+	issued, err := service.IssueApiKey(ctx, "ci")
+	assert.NoError(t, err)
+
+	key, err := service.AuthenticateApiKey(ctx, issued.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, issued.Id, key.Id)
+
+	for _, presented := range []string{"", "secret", "us_unknown", issued.Key + "x"} {
+		_, err = service.AuthenticateApiKey(ctx, presented)
+		assert.ErrorIs(t, err, ErrUnauthenticated, presented)
+	}
+
+	_, err = service.RevokeApiKey(ctx, issued.Id)
+	assert.NoError(t, err)
+	_, err = service.AuthenticateApiKey(ctx, issued.Key)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestService_IssueApiKey(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, new(MockCache), new(MockMetadataQueue), time.Second)
+
+	var stored model.ApiKey
+	mockStorage.On("CreateApiKey", mock.Anything, mock.AnythingOfType("model.ApiKey")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(model.ApiKey) }).
+		Return(&model.ApiKey{Id: 1, Name: "ci"}, nil)
+
+	issued, err := service.IssueApiKey(context.Background(), "  ci ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, issued.Id)
+	assert.True(t, strings.HasPrefix(issued.Key, "us_"))
+	assert.Equal(t, "ci", stored.Name)
+	assert.Equal(t, issued.Key[:len(stored.Prefix)], stored.Prefix)
+	assert.Equal(t, hashApiKey(issued.Key), stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, issued.Key)
+
+	_, err = service.IssueApiKey(context.Background(), " ")
+	assert.ErrorIs(t, err, ErrInvalidApiKey)
+	mockStorage.AssertNumberOfCalls(t, "CreateApiKey", 1)
+}
+
+func TestService_CreateShortUrlIdempotent(t *testing.T) {
+	mockQueue := new(MockMetadataQueue)
+	service := New(memoryrepo.New(), memorycache.New(), mockQueue, time.Second)
+	ctx := context.Background()
+	client := &model.ApiKey{Id: 1}
+
+	mockQueue.On("Enqueue", mock.Anything).Once()
+
+	created, replayed, err := service.CreateShortUrlIdempotent(ctx, client, "key-1", model.Url{Url: "https://example.com"})
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+
+	again, replayed, err := service.CreateShortUrlIdempotent(ctx, client, "key-1", model.Url{Url: "https://example.com"})
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, created.ShortUrl, again.ShortUrl)
+
+	_, _, err = service.CreateShortUrlIdempotent(ctx, client, "key-1", model.Url{Url: "https://example.org"})
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	for _, key := range []string{"", "with space", strings.Repeat("k", 256)} {
+		_, _, err = service.CreateShortUrlIdempotent(ctx, client, key, model.Url{Url: "https://example.com"})
+		assert.ErrorIs(t, err, ErrInvalidIdempotencyKey, key)
+	}
+
+	// Anonymous requests have no scope to keep their keys apart.
+	_, _, err = service.CreateShortUrlIdempotent(ctx, nil, "key-2", model.Url{Url: "https://example.com"})
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	mockQueue.AssertExpectations(t)
+}
+
+func TestService_CreateShortUrlIdempotent_ScopedPerClient(t *testing.T) {
+	mockQueue := new(MockMetadataQueue)
+	service := New(memoryrepo.New(), memorycache.New(), mockQueue, time.Second)
+	ctx := context.Background()
+
+	mockQueue.On("Enqueue", mock.Anything)
+
+	// The same key sent by different clients for different links creates
+	// both links instead of failing as reused.
+	for _, request := range []struct {
+		client *model.ApiKey
+		url    model.Url
+	}{
+		{&model.ApiKey{Id: 1}, model.Url{Url: "https://example.com/1"}},
+		{&model.ApiKey{Id: 2}, model.Url{Url: "https://example.com/2"}},
+	} {
+		created, replayed, err := service.CreateShortUrlIdempotent(ctx, request.client, "key-1", request.url)
+		assert.NoError(t, err, request.url.Url)
+		assert.False(t, replayed, request.url.Url)
+		assert.Equal(t, request.url.Url, created.Url)
+	}
+
+	again, replayed, err := service.CreateShortUrlIdempotent(ctx, &model.ApiKey{Id: 2}, "key-1", model.Url{Url: "https://example.com/2"})
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, "https://example.com/2", again.Url)
+}
+
+func TestService_CreateShortUrlIdempotent_ReleasesFailedKey(t *testing.T) {
+	mockQueue := new(MockMetadataQueue)
+	service := New(memoryrepo.New(), memorycache.New(), mockQueue, time.Second)
+	ctx := context.Background()
+	client := &model.ApiKey{Id: 1}
+
+	mockQueue.On("Enqueue", mock.Anything).Once()
+
+	_, _, err := service.CreateShortUrlIdempotent(ctx, client, "key-1", model.Url{Url: "https://example.com", QueryPolicy: "merge"})
+	assert.ErrorIs(t, err, ErrInvalidQueryPolicy)
+
+	created, replayed, err := service.CreateShortUrlIdempotent(ctx, client, "key-1", model.Url{Url: "https://example.com"})
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.NotEmpty(t, created.ShortUrl)
+	mockQueue.AssertExpectations(t)
+}
+
+func TestService_CreateShortUrlIdempotent_SharesDeadline(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, new(MockCache), new(MockMetadataQueue), time.Second)
+
+	var claim model.IdempotencyKey
+	var claimDeadline time.Time
+	mockStorage.On("ClaimIdempotencyKey", mock.Anything, mock.AnythingOfType("model.IdempotencyKey"), DefaultIdempotencyLease).
+		Run(func(args mock.Arguments) {
+			claim = args.Get(1).(model.IdempotencyKey)
+			claimDeadline, _ = args.Get(0).(context.Context).Deadline()
+		}).
+		Return(&model.IdempotencyKey{}, true, nil)
+	var releaseDeadline time.Time
+	mockStorage.On("ReleaseIdempotencyKey", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { releaseDeadline, _ = args.Get(0).(context.Context).Deadline() }).
+		Return(nil)
+
+	// The request is cancelled once creation fails, the key is released
+	// anyway, with the token of the claim and within the same deadline.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := service.CreateShortUrlIdempotent(ctx, &model.ApiKey{Id: 1}, "key-1", model.Url{Url: "https://example.com", QueryPolicy: "merge"})
+
+	assert.ErrorIs(t, err, ErrInvalidQueryPolicy)
+	assert.NotEmpty(t, claim.Token)
+	mockStorage.AssertCalled(t, "ReleaseIdempotencyKey", mock.Anything, claim.Key, claim.Token)
+	assert.False(t, claimDeadline.IsZero())
+	assert.Equal(t, claimDeadline, releaseDeadline)
+}
+
+func TestService_CreateShortUrlIdempotent_InProgress(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, new(MockCache), new(MockMetadataQueue), time.Second)
+
+	// Another request claimed the same key for the same link and has not
+	// completed yet.
+	record := &model.IdempotencyKey{}
+	mockStorage.On("ClaimIdempotencyKey", mock.Anything, mock.AnythingOfType("model.IdempotencyKey"), DefaultIdempotencyLease).
+		Run(func(args mock.Arguments) { *record = args.Get(1).(model.IdempotencyKey) }).
+		Return(record, false, nil)
+
+	_, replayed, err := service.CreateShortUrlIdempotent(context.Background(), &model.ApiKey{Id: 1}, "key-1", model.Url{Url: "https://example.com"})
+
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+	assert.False(t, replayed)
+	mockStorage.AssertNotCalled(t, "CreateShortUrl", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+)
+
+const (
+	maxTagLength    = 64
+	maxFolderLength = 128
+)
+
+func (s *Service) SetTags(ctx context.Context, short_url string, tags []string) (*dto.LinkDTO, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.SetTags(ctx, short_url, tags)
+	return result, wrapError(ctx, err)
+}
+
+func (s *Service) SetFolder(ctx context.Context, short_url string, folder string) (*dto.LinkDTO, error) {
+	folder, err := normalizeFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.SetFolder(ctx, short_url, folder)
+	return result, wrapError(ctx, err)
+}
+
+// normalizeTags lowercases tags and removes duplicates. Tags may contain
+// letters, digits, '-', '_' and '.', so that they are safe to pass in
+// query strings like ?tag=black-friday.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: tag must be between 1 and %d characters", ErrInvalidTag, maxTagLength)
+		}
+		for _, r := range tag {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' {
+				return nil, fmt.Errorf("%w: tag %q contains %q", ErrInvalidTag, tag, r)
+			}
+		}
+		normalized = append(normalized, tag)
+	}
+
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
+
+func normalizeFolder(folder string) (string, error) {
+	folder = strings.TrimSpace(folder)
+	if len(folder) > maxFolderLength {
+		return "", fmt.Errorf("%w: folder must be at most %d characters", ErrInvalidFolder, maxFolderLength)
+	}
+
+	return folder, nil
+}
+
+func normalizeFilter(filter dto.LinkFilter) dto.LinkFilter {
+	return dto.LinkFilter{
+		Tag:    strings.ToLower(strings.TrimSpace(filter.Tag)),
+		Folder: strings.TrimSpace(filter.Folder),
+	}
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"math/rand"
 	"strings"
 	"time"
@@ -26,3 +28,14 @@ func validateUrlScheme(url string) string {
 
 	return url
 }
+
+// visitorId identifies a visitor for unique counts without storing the
+// client address itself. Visitors without a known address are not counted.
+func visitorId(clientIp, userAgent string) string {
+	if clientIp == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(clientIp + "\x00" + userAgent))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/model"
+)
+
+func (s *Service) SetVariants(ctx context.Context, variants dto.VariantsDTO) (*dto.VariantsDTO, error) {
+	normalized, err := normalizeVariants(variants.Variants)
+	if err != nil {
+		return nil, err
+	}
+	variants.Variants = normalized
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.SetVariants(ctx, variants)
+	return result, wrapError(ctx, err)
+}
+
+func (s *Service) GetVariants(ctx context.Context, short_url string) (*dto.VariantsDTO, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.storage.GetVariants(ctx, short_url)
+	return result, wrapError(ctx, err)
+}
+
+// normalizeVariants validates weighted destinations. Variants without a
+// name are named after their position so that clicks can be attributed.
+func normalizeVariants(variants []model.Variant) ([]model.Variant, error) {
+	names := make(map[string]struct{}, len(variants))
+	normalized := make([]model.Variant, 0, len(variants))
+	for i, variant := range variants {
+		variant.Id = 0
+		variant.Name = strings.TrimSpace(variant.Name)
+		variant.Url = strings.TrimSpace(variant.Url)
+		if variant.Name == "" {
+			variant.Name = strconv.Itoa(i + 1)
+		}
+
+		if variant.Url == "" {
+			return nil, fmt.Errorf("%w: variant %q has no url", ErrInvalidVariant, variant.Name)
+		}
+		if variant.Weight <= 0 {
+			return nil, fmt.Errorf("%w: variant %q must have a positive weight", ErrInvalidVariant, variant.Name)
+		}
+		if _, ok := names[variant.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate variant name %q", ErrInvalidVariant, variant.Name)
+		}
+		names[variant.Name] = struct{}{}
+
+		variant.Url = validateUrlScheme(variant.Url)
+		normalized = append(normalized, variant)
+	}
+
+	return normalized, nil
+}
+
+// pickVariant chooses a destination proportionally to the variant weights.
+// For sticky links the variant remembered by the visitor is kept as long
+// as it still exists.
+func pickVariant(variants []model.Variant, sticky bool, previous string) (model.Variant, bool) {
+	if len(variants) == 0 {
+		return model.Variant{}, false
+	}
+
+	if sticky && previous != "" {
+		for _, variant := range variants {
+			if variant.Name == previous {
+				return variant, true
+			}
+		}
+	}
+
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+
+	n := rand.IntN(total)
+	for _, variant := range variants {
+		if n < variant.Weight {
+			return variant, true
+		}
+		n -= variant.Weight
+	}
+
+	return variants[len(variants)-1], true
+}
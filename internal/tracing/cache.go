@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type cache struct {
+	cache service.Cache
+}
+
+// Cache traces every operation of c. A miss is not an error, the span of
+// a Get is marked with cache.hit instead.
+func Cache(c service.Cache) service.Cache {
+	return &cache{cache: c}
+}
+
+func (c *cache) Get(ctx context.Context, key string) (string, error) {
+	ctx, span := start(ctx, "cache.Get")
+	value, err := c.cache.Get(ctx, key)
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	if err == redis.Nil {
+		span.End()
+		return value, err
+	}
+	end(span, err)
+	return value, err
+}
+
+func (c *cache) Set(ctx context.Context, key string, value interface{}) error {
+	ctx, span := start(ctx, "cache.Set")
+	err := c.cache.Set(ctx, key, value)
+	end(span, err)
+	return err
+}
+
+func (c *cache) Delete(ctx context.Context, keys ...string) error {
+	ctx, span := start(ctx, "cache.Delete")
+	err := c.cache.Delete(ctx, keys...)
+	end(span, err)
+	return err
+}
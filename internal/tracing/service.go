@@ -0,0 +1,187 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/Komilov31/url-shortener/internal/dto"
+	"github.com/Komilov31/url-shortener/internal/handler"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/qr"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type shortener struct {
+	service handler.ShortnerServcie
+}
+
+// Service traces every call the handlers make to s, so that a slow request
+// shows whether the time went to the service itself or to the cache and
+// the queries under it.
+func Service(s handler.ShortnerServcie) handler.ShortnerServcie {
+	return &shortener{service: s}
+}
+
+func (s *shortener) GetAnalytics(ctx context.Context, shortUrl string) (_ []dto.RedirectInfo, err error) {
+	ctx, span := start(ctx, "Service.GetAnalytics")
+	defer func() { end(span, err) }()
+	return s.service.GetAnalytics(ctx, shortUrl)
+}
+
+func (s *shortener) GetUrlByShort(ctx context.Context, shortUrl string, info model.RedirectInfo) (_ *model.Url, err error) {
+	ctx, span := start(ctx, "Service.GetUrlByShort")
+	defer func() { end(span, err) }()
+	return s.service.GetUrlByShort(ctx, shortUrl, info)
+}
+
+func (s *shortener) CreateShortUrl(ctx context.Context, url model.Url) (_ *model.Url, err error) {
+	ctx, span := start(ctx, "Service.CreateShortUrl")
+	defer func() { end(span, err) }()
+	return s.service.CreateShortUrl(ctx, url)
+}
+
+func (s *shortener) CreateShortUrlIdempotent(ctx context.Context, client *model.ApiKey, key string, url model.Url) (_ *model.Url, replayed bool, err error) {
+	ctx, span := start(ctx, "Service.CreateShortUrlIdempotent")
+	defer func() {
+		span.SetAttributes(attribute.Bool("idempotency.replayed", replayed))
+		end(span, err)
+	}()
+	return s.service.CreateShortUrlIdempotent(ctx, client, key, url)
+}
+
+func (s *shortener) AggregateByUserAgent(ctx context.Context, filter dto.LinkFilter) (_ []dto.UserAgentDTO, err error) {
+	ctx, span := start(ctx, "Service.AggregateByUserAgent")
+	defer func() { end(span, err) }()
+	return s.service.AggregateByUserAgent(ctx, filter)
+}
+
+func (s *shortener) AggregateByDate(ctx context.Context, filter dto.LinkFilter) (_ []dto.DateDTO, err error) {
+	ctx, span := start(ctx, "Service.AggregateByDate")
+	defer func() { end(span, err) }()
+	return s.service.AggregateByDate(ctx, filter)
+}
+
+func (s *shortener) AggregateByMonth(ctx context.Context, filter dto.LinkFilter) (_ []dto.MonthDTO, err error) {
+	ctx, span := start(ctx, "Service.AggregateByMonth")
+	defer func() { end(span, err) }()
+	return s.service.AggregateByMonth(ctx, filter)
+}
+
+func (s *shortener) AggregateByCampaign(ctx context.Context, period string, filter dto.LinkFilter) (_ []dto.CampaignDTO, err error) {
+	ctx, span := start(ctx, "Service.AggregateByCampaign")
+	defer func() { end(span, err) }()
+	return s.service.AggregateByCampaign(ctx, period, filter)
+}
+
+func (s *shortener) AggregateByReferrer(ctx context.Context, filter dto.LinkFilter) (_ []dto.ReferrerDTO, err error) {
+	ctx, span := start(ctx, "Service.AggregateByReferrer")
+	defer func() { end(span, err) }()
+	return s.service.AggregateByReferrer(ctx, filter)
+}
+
+func (s *shortener) AggregateByTag(ctx context.Context, filter dto.LinkFilter) (_ []dto.TagDTO, err error) {
+	ctx, span := start(ctx, "Service.AggregateByTag")
+	defer func() { end(span, err) }()
+	return s.service.AggregateByTag(ctx, filter)
+}
+
+func (s *shortener) GetUrlMetadata(ctx context.Context, shortUrl string) (_ *model.UrlMetadata, err error) {
+	ctx, span := start(ctx, "Service.GetUrlMetadata")
+	defer func() { end(span, err) }()
+	return s.service.GetUrlMetadata(ctx, shortUrl)
+}
+
+func (s *shortener) GetUnhealthyUrls(ctx context.Context) (_ []model.UrlHealth, err error) {
+	ctx, span := start(ctx, "Service.GetUnhealthyUrls")
+	defer func() { end(span, err) }()
+	return s.service.GetUnhealthyUrls(ctx)
+}
+
+func (s *shortener) GetQrCode(ctx context.Context, shortUrl string, content string, opts qr.Options) (_ []byte, err error) {
+	ctx, span := start(ctx, "Service.GetQrCode")
+	defer func() { end(span, err) }()
+	return s.service.GetQrCode(ctx, shortUrl, content, opts)
+}
+
+func (s *shortener) SetTargetingRules(ctx context.Context, shortUrl string, rules []model.TargetingRule) (_ []model.TargetingRule, err error) {
+	ctx, span := start(ctx, "Service.SetTargetingRules")
+	defer func() { end(span, err) }()
+	return s.service.SetTargetingRules(ctx, shortUrl, rules)
+}
+
+func (s *shortener) GetTargetingRules(ctx context.Context, shortUrl string) (_ []model.TargetingRule, err error) {
+	ctx, span := start(ctx, "Service.GetTargetingRules")
+	defer func() { end(span, err) }()
+	return s.service.GetTargetingRules(ctx, shortUrl)
+}
+
+func (s *shortener) SetVariants(ctx context.Context, variants dto.VariantsDTO) (_ *dto.VariantsDTO, err error) {
+	ctx, span := start(ctx, "Service.SetVariants")
+	defer func() { end(span, err) }()
+	return s.service.SetVariants(ctx, variants)
+}
+
+func (s *shortener) GetVariants(ctx context.Context, shortUrl string) (_ *dto.VariantsDTO, err error) {
+	ctx, span := start(ctx, "Service.GetVariants")
+	defer func() { end(span, err) }()
+	return s.service.GetVariants(ctx, shortUrl)
+}
+
+func (s *shortener) SearchLinks(ctx context.Context, query dto.LinkQuery) (_ []dto.LinkDTO, err error) {
+	ctx, span := start(ctx, "Service.SearchLinks")
+	defer func() { end(span, err) }()
+	return s.service.SearchLinks(ctx, query)
+}
+
+func (s *shortener) SetDisabled(ctx context.Context, shortUrl string, disabled bool) (_ *dto.LinkDTO, err error) {
+	ctx, span := start(ctx, "Service.SetDisabled")
+	defer func() { end(span, err) }()
+	return s.service.SetDisabled(ctx, shortUrl, disabled)
+}
+
+func (s *shortener) SetTags(ctx context.Context, shortUrl string, tags []string) (_ *dto.LinkDTO, err error) {
+	ctx, span := start(ctx, "Service.SetTags")
+	defer func() { end(span, err) }()
+	return s.service.SetTags(ctx, shortUrl, tags)
+}
+
+func (s *shortener) SetFolder(ctx context.Context, shortUrl string, folder string) (_ *dto.LinkDTO, err error) {
+	ctx, span := start(ctx, "Service.SetFolder")
+	defer func() { end(span, err) }()
+	return s.service.SetFolder(ctx, shortUrl, folder)
+}
+
+func (s *shortener) GetLink(ctx context.Context, shortUrl string) (_ *dto.LinkDTO, err error) {
+	ctx, span := start(ctx, "Service.GetLink")
+	defer func() { end(span, err) }()
+	return s.service.GetLink(ctx, shortUrl)
+}
+
+func (s *shortener) DeleteLink(ctx context.Context, shortUrl string) (_ *model.Url, err error) {
+	ctx, span := start(ctx, "Service.DeleteLink")
+	defer func() { end(span, err) }()
+	return s.service.DeleteLink(ctx, shortUrl)
+}
+
+func (s *shortener) IssueApiKey(ctx context.Context, name string) (_ *dto.IssuedApiKeyDTO, err error) {
+	ctx, span := start(ctx, "Service.IssueApiKey")
+	defer func() { end(span, err) }()
+	return s.service.IssueApiKey(ctx, name)
+}
+
+func (s *shortener) ListApiKeys(ctx context.Context) (_ []model.ApiKey, err error) {
+	ctx, span := start(ctx, "Service.ListApiKeys")
+	defer func() { end(span, err) }()
+	return s.service.ListApiKeys(ctx)
+}
+
+func (s *shortener) RevokeApiKey(ctx context.Context, id int) (_ *model.ApiKey, err error) {
+	ctx, span := start(ctx, "Service.RevokeApiKey")
+	defer func() { end(span, err) }()
+	return s.service.RevokeApiKey(ctx, id)
+}
+
+func (s *shortener) AuthenticateApiKey(ctx context.Context, key string) (_ *model.ApiKey, err error) {
+	ctx, span := start(ctx, "Service.AuthenticateApiKey")
+	defer func() { end(span, err) }()
+	return s.service.AuthenticateApiKey(ctx, key)
+}
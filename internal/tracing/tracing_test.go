@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	memorycache "github.com/Komilov31/url-shortener/internal/cache/memory"
+	"github.com/Komilov31/url-shortener/internal/model"
+	"github.com/Komilov31/url-shortener/internal/repository"
+	memoryrepo "github.com/Komilov31/url-shortener/internal/repository/memory"
+	"github.com/Komilov31/url-shortener/internal/service"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	_ "modernc.org/sqlite"
+)
+
+// record installs a tracer provider that keeps finished spans in memory.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	return names
+}
+
+func TestService_SpansNestUnderRequest(t *testing.T) {
+	recorder := record(t)
+	cache := Cache(memorycache.New())
+	svc := Service(service.New(memoryrepo.New(), cache, nopQueue{}, 0))
+
+	ctx, request := start(context.Background(), "GET /s/:short_url")
+	created, err := svc.CreateShortUrl(ctx, model.Url{Url: "https://example.com"})
+	require.NoError(t, err)
+	_, err = svc.GetUrlByShort(ctx, "missing", model.RedirectInfo{ShortUrl: "missing"})
+	require.ErrorIs(t, err, repository.ErrAliasNotFound)
+	request.End()
+
+	spans := recorder.Ended()
+	assert.Equal(t, []string{"cache.Get", "Service.CreateShortUrl", "cache.Get", "Service.GetUrlByShort", "GET /s/:short_url"}, spanNames(spans))
+	for _, span := range spans[:4] {
+		assert.Equal(t, request.SpanContext().TraceID(), span.SpanContext().TraceID())
+	}
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, codes.Error, spans[3].Status().Code)
+	assert.NotEmpty(t, created.ShortUrl)
+}
+
+func TestCache_MissIsNotAnError(t *testing.T) {
+	recorder := record(t)
+	cache := Cache(memorycache.New())
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "abc123", "https://example.com"))
+	_, err := cache.Get(ctx, "abc123")
+	require.NoError(t, err)
+	_, err = cache.Get(ctx, "missing")
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, []string{"cache.Set", "cache.Get", "cache.Get"}, spanNames(spans))
+	assert.Contains(t, spans[1].Attributes(), attribute.Bool("cache.hit", true))
+	assert.Contains(t, spans[2].Attributes(), attribute.Bool("cache.hit", false))
+	assert.Equal(t, codes.Unset, spans[2].Status().Code)
+}
+
+func TestOpenDB_TracesQueries(t *testing.T) {
+	recorder := record(t)
+	db, err := OpenDB("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, request := start(context.Background(), "request")
+	_, err = db.ExecContext(ctx, "CREATE TABLE urls (short_url TEXT)")
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT count(*) FROM urls").Scan(&count))
+	request.End()
+
+	var queries []string
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() != request.SpanContext().SpanID() {
+			continue
+		}
+		for _, attr := range span.Attributes() {
+			if attr.Key == "db.statement" {
+				queries = append(queries, attr.Value.AsString())
+			}
+		}
+	}
+	assert.Equal(t, []string{"CREATE TABLE urls (short_url TEXT)", "SELECT count(*) FROM urls"}, queries)
+}
+
+func TestLogHook(t *testing.T) {
+	record(t)
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(LogHook{})
+
+	ctx, span := start(context.Background(), "request")
+	logger.Info().Ctx(ctx).Msg("traced")
+	span.End()
+	logger.Info().Msg("untraced")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var traced, untraced map[string]string
+	require.NoError(t, json.Unmarshal(lines[0], &traced))
+	require.NoError(t, json.Unmarshal(lines[1], &untraced))
+	assert.Equal(t, span.SpanContext().TraceID().String(), traced["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), traced["span_id"])
+	assert.NotContains(t, untraced, "trace_id")
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	_, err := Setup(context.Background(), Options{Exporter: "jaeger"})
+	assert.ErrorContains(t, err, `unknown trace exporter "jaeger"`)
+
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{Exporter: "stdout", ServiceName: "url-shortener", Stdout: &out})
+	require.NoError(t, err)
+	_, span := start(context.Background(), "request")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Contains(t, out.String(), `"Name":"request"`)
+	assert.Contains(t, out.String(), "url-shortener")
+}
+
+type nopQueue struct{}
+
+func (nopQueue) Enqueue(model.Url) {}
@@ -0,0 +1,111 @@
+// Package useragent extracts operating system, device type and browser
+// family from User-Agent headers. It only recognises the families needed
+// for link targeting, everything else is reported as "other".
+package useragent
+
+import "strings"
+
+const (
+	OSiOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+	Other      = "other"
+
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+
+	BrowserChrome  = "chrome"
+	BrowserFirefox = "firefox"
+	BrowserSafari  = "safari"
+	BrowserEdge    = "edge"
+	BrowserOpera   = "opera"
+	BrowserSamsung = "samsung"
+)
+
+var (
+	OSes     = []string{OSiOS, OSAndroid, OSWindows, OSMacOS, OSLinux, OSChromeOS, Other}
+	Devices  = []string{DeviceMobile, DeviceTablet, DeviceDesktop, DeviceBot}
+	Browsers = []string{BrowserChrome, BrowserFirefox, BrowserSafari, BrowserEdge, BrowserOpera, BrowserSamsung, Other}
+)
+
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "curl/", "wget/", "python-requests", "go-http-client"}
+
+type Info struct {
+	OS      string
+	Device  string
+	Browser string
+}
+
+func Parse(userAgent string) Info {
+	ua := strings.ToLower(userAgent)
+
+	return Info{
+		OS:      parseOS(ua),
+		Device:  parseDevice(ua),
+		Browser: parseBrowser(ua),
+	}
+}
+
+func parseOS(ua string) string {
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad") || strings.Contains(ua, "ipod"):
+		return OSiOS
+	case strings.Contains(ua, "android"):
+		return OSAndroid
+	case strings.Contains(ua, "windows"):
+		return OSWindows
+	case strings.Contains(ua, "cros"):
+		return OSChromeOS
+	case strings.Contains(ua, "mac os x") || strings.Contains(ua, "macintosh"):
+		return OSMacOS
+	case strings.Contains(ua, "linux"):
+		return OSLinux
+	default:
+		return Other
+	}
+}
+
+func parseDevice(ua string) string {
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return DeviceBot
+		}
+	}
+
+	switch {
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet"):
+		return DeviceTablet
+	case strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return DeviceTablet
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone") || strings.Contains(ua, "ipod"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+// parseBrowser checks the most specific tokens first: Edge, Opera and
+// Samsung Internet also advertise Chrome and Safari.
+func parseBrowser(ua string) string {
+	switch {
+	case strings.Contains(ua, "edg/") || strings.Contains(ua, "edga/") || strings.Contains(ua, "edgios/") || strings.Contains(ua, "edge/"):
+		return BrowserEdge
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		return BrowserOpera
+	case strings.Contains(ua, "samsungbrowser/"):
+		return BrowserSamsung
+	case strings.Contains(ua, "firefox/") || strings.Contains(ua, "fxios/"):
+		return BrowserFirefox
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/") || strings.Contains(ua, "chromium/"):
+		return BrowserChrome
+	case strings.Contains(ua, "safari/"):
+		return BrowserSafari
+	default:
+		return Other
+	}
+}
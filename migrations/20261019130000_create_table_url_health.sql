@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS fallback_url TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS url_health(
+    short_url TEXT PRIMARY KEY REFERENCES urls(short_url) ON DELETE CASCADE,
+    status_code INT NOT NULL DEFAULT 0,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    healthy BOOLEAN NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS url_health_unhealthy_idx ON url_health(checked_at) WHERE NOT healthy;
+
+-- +goose Down
+DROP TABLE IF EXISTS url_health;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS fallback_url;
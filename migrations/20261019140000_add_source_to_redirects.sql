@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'direct';
+
+-- +goose Down
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS source;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS targeting_rules(
+    id SERIAL PRIMARY KEY,
+    short_url TEXT NOT NULL REFERENCES urls(short_url) ON DELETE CASCADE,
+    position INT NOT NULL,
+    os TEXT NOT NULL DEFAULT '',
+    device TEXT NOT NULL DEFAULT '',
+    browser TEXT NOT NULL DEFAULT '',
+    language TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    destination TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS targeting_rules_short_url_idx ON targeting_rules(short_url, position);
+
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS matched_rule INT;
+
+-- +goose Down
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS matched_rule;
+
+DROP TABLE IF EXISTS targeting_rules;
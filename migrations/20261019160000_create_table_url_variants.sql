@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS url_variants(
+    id SERIAL PRIMARY KEY,
+    short_url TEXT NOT NULL REFERENCES urls(short_url) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    weight INT NOT NULL CHECK (weight > 0),
+    UNIQUE (short_url, name)
+);
+
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS sticky_variants BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS variant TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS variant;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS sticky_variants;
+
+DROP TABLE IF EXISTS url_variants;
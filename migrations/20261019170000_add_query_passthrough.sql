@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS query_policy TEXT NOT NULL DEFAULT 'none';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_source TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_medium TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_campaign TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_term TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_content TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS utm_source TEXT NOT NULL DEFAULT '';
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS utm_medium TEXT NOT NULL DEFAULT '';
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS utm_campaign TEXT NOT NULL DEFAULT '';
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS utm_term TEXT NOT NULL DEFAULT '';
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS utm_content TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS utm_content;
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS utm_term;
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS utm_campaign;
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS utm_medium;
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS utm_source;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS utm_content;
+ALTER TABLE urls DROP COLUMN IF EXISTS utm_term;
+ALTER TABLE urls DROP COLUMN IF EXISTS utm_campaign;
+ALTER TABLE urls DROP COLUMN IF EXISTS utm_medium;
+ALTER TABLE urls DROP COLUMN IF EXISTS utm_source;
+ALTER TABLE urls DROP COLUMN IF EXISTS query_policy;
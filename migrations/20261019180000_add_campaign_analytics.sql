@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS visitor_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_redirect_analytics_campaign
+    ON redirect_analytics (utm_source, utm_medium, utm_campaign, request_time);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_redirect_analytics_campaign;
+
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS visitor_id;
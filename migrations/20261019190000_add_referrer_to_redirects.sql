@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS referrer TEXT NOT NULL DEFAULT '';
+ALTER TABLE redirect_analytics ADD COLUMN IF NOT EXISTS referrer_domain TEXT NOT NULL DEFAULT 'direct';
+
+CREATE INDEX IF NOT EXISTS idx_redirect_analytics_referrer_domain
+    ON redirect_analytics (short_url, referrer_domain);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_redirect_analytics_referrer_domain;
+
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS referrer_domain;
+ALTER TABLE redirect_analytics DROP COLUMN IF EXISTS referrer;
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS folder TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_urls_folder ON urls (folder);
+
+CREATE TABLE IF NOT EXISTS url_tags(
+    short_url TEXT NOT NULL REFERENCES urls(short_url) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (short_url, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag ON url_tags (tag);
+
+-- +goose Down
+DROP TABLE IF EXISTS url_tags;
+
+DROP INDEX IF EXISTS idx_urls_folder;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS folder;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_urls_url_trgm ON urls USING GIN (url gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_urls_short_url_trgm ON urls USING GIN (short_url gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_url_metadata_title_trgm ON url_metadata USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag_trgm ON url_tags USING GIN (tag gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_urls_created_at ON urls (created_at);
+CREATE INDEX IF NOT EXISTS idx_urls_owner ON urls (owner);
+CREATE INDEX IF NOT EXISTS idx_redirect_analytics_short_url_time ON redirect_analytics (short_url, request_time);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_redirect_analytics_short_url_time;
+DROP INDEX IF EXISTS idx_urls_owner;
+DROP INDEX IF EXISTS idx_urls_created_at;
+DROP INDEX IF EXISTS idx_url_tags_tag_trgm;
+DROP INDEX IF EXISTS idx_url_metadata_title_trgm;
+DROP INDEX IF EXISTS idx_urls_short_url_trgm;
+DROP INDEX IF EXISTS idx_urls_url_trgm;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS disabled;
+ALTER TABLE urls DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS owner;
+ALTER TABLE urls DROP COLUMN IF EXISTS created_at;
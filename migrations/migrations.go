@@ -0,0 +1,30 @@
+// Package migrations embeds the database schema migrations so that the
+// binary can apply them without the sql files next to it. The files are
+// in goose format, the Postgres ones are in this directory and the SQLite
+// ones in sqlite/.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var postgresFS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// Postgres returns the Postgres migrations.
+func Postgres() fs.FS {
+	return postgresFS
+}
+
+// Sqlite returns the SQLite migrations with the files at the root.
+func Sqlite() fs.FS {
+	sub, err := fs.Sub(sqliteFS, "sqlite")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
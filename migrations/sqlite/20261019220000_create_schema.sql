@@ -0,0 +1,112 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS urls(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL UNIQUE,
+    fallback_url TEXT NOT NULL DEFAULT '',
+    sticky_variants BOOLEAN NOT NULL DEFAULT FALSE,
+    query_policy TEXT NOT NULL DEFAULT 'none',
+    utm_source TEXT NOT NULL DEFAULT '',
+    utm_medium TEXT NOT NULL DEFAULT '',
+    utm_campaign TEXT NOT NULL DEFAULT '',
+    utm_term TEXT NOT NULL DEFAULT '',
+    utm_content TEXT NOT NULL DEFAULT '',
+    folder TEXT NOT NULL DEFAULT '',
+    owner TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_urls_folder ON urls (folder);
+CREATE INDEX IF NOT EXISTS idx_urls_created_at ON urls (created_at);
+CREATE INDEX IF NOT EXISTS idx_urls_owner ON urls (owner);
+
+CREATE TABLE IF NOT EXISTS redirect_analytics(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url TEXT NOT NULL REFERENCES urls(short_url),
+    request_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_agent TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT 'direct',
+    matched_rule INTEGER,
+    variant TEXT NOT NULL DEFAULT '',
+    utm_source TEXT NOT NULL DEFAULT '',
+    utm_medium TEXT NOT NULL DEFAULT '',
+    utm_campaign TEXT NOT NULL DEFAULT '',
+    utm_term TEXT NOT NULL DEFAULT '',
+    utm_content TEXT NOT NULL DEFAULT '',
+    visitor_id TEXT NOT NULL DEFAULT '',
+    referrer TEXT NOT NULL DEFAULT '',
+    referrer_domain TEXT NOT NULL DEFAULT 'direct'
+);
+
+CREATE INDEX IF NOT EXISTS idx_redirect_analytics_short_url_time
+    ON redirect_analytics (short_url, request_time);
+CREATE INDEX IF NOT EXISTS idx_redirect_analytics_campaign
+    ON redirect_analytics (utm_source, utm_medium, utm_campaign, request_time);
+CREATE INDEX IF NOT EXISTS idx_redirect_analytics_referrer_domain
+    ON redirect_analytics (short_url, referrer_domain);
+
+CREATE TABLE IF NOT EXISTS url_metadata(
+    short_url TEXT PRIMARY KEY REFERENCES urls(short_url) ON DELETE CASCADE,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image TEXT NOT NULL DEFAULT '',
+    canonical_url TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS url_health(
+    short_url TEXT PRIMARY KEY REFERENCES urls(short_url) ON DELETE CASCADE,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    healthy BOOLEAN NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS url_health_unhealthy_idx ON url_health(checked_at) WHERE NOT healthy;
+
+CREATE TABLE IF NOT EXISTS targeting_rules(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url TEXT NOT NULL REFERENCES urls(short_url) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    os TEXT NOT NULL DEFAULT '',
+    device TEXT NOT NULL DEFAULT '',
+    browser TEXT NOT NULL DEFAULT '',
+    language TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    destination TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS targeting_rules_short_url_idx ON targeting_rules(short_url, position);
+
+CREATE TABLE IF NOT EXISTS url_variants(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_url TEXT NOT NULL REFERENCES urls(short_url) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    weight INTEGER NOT NULL CHECK (weight > 0),
+    UNIQUE (short_url, name)
+);
+
+CREATE TABLE IF NOT EXISTS url_tags(
+    short_url TEXT NOT NULL REFERENCES urls(short_url) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (short_url, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag ON url_tags (tag);
+
+-- +goose Down
+DROP TABLE IF EXISTS url_tags;
+DROP TABLE IF EXISTS url_variants;
+DROP TABLE IF EXISTS targeting_rules;
+DROP TABLE IF EXISTS url_health;
+DROP TABLE IF EXISTS url_metadata;
+DROP TABLE IF EXISTS redirect_analytics;
+DROP TABLE IF EXISTS urls;
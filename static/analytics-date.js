@@ -9,7 +9,7 @@ document.addEventListener('DOMContentLoaded', function() {
     fetchAggregate();
 
     function fetchAggregate() {
-        fetch(`http://localhost:8080/analytics/date/`)
+        fetch(`http://localhost:8080/api/v1/analytics/date`)
             .then(response => response.json())
             .then(data => {
                 displayAnalytics(data);
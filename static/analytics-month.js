@@ -9,7 +9,7 @@ document.addEventListener('DOMContentLoaded', function() {
     fetchAggregate();
 
     function fetchAggregate() {
-        fetch(`http://localhost:8080/analytics/month/`)
+        fetch(`http://localhost:8080/api/v1/analytics/month`)
             .then(response => response.json())
             .then(data => {
                 displayAnalytics(data);
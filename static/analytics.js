@@ -16,7 +16,7 @@ document.addEventListener('DOMContentLoaded', function() {
     }
 
     function fetchAnalytics(shortUrl) {
-        fetch(`http://localhost:8080/analytics/${shortUrl}`)
+        fetch(`http://localhost:8080/api/v1/links/${shortUrl}/analytics`)
             .then(response => response.json())
             .then(data => {
                 displayAnalytics(data);
@@ -23,7 +23,7 @@ document.addEventListener('DOMContentLoaded', function() {
             alert('Please enter a URL');
             return;
         }
-        fetch('http://localhost:8080/shorten', {
+        fetch('http://localhost:8080/api/v1/links', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json'
@@ -67,7 +67,7 @@ document.addEventListener('DOMContentLoaded', function() {
     });
 
     function fetchAnalytics(shortUrl) {
-        fetch(`http://localhost:8080/analytics/${shortUrl}`)
+        fetch(`http://localhost:8080/api/v1/links/${shortUrl}/analytics`)
             .then(response => response.json())
             .then(data => {
                 displayResults('Analytics for ' + shortUrl, data);